@@ -0,0 +1,93 @@
+/*
+Copyright 2019 Marek Cermak <macermak@redhat.com>.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+	http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+package v1alpha1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+// +kubebuilder:object:root=true
+// +kubebuilder:resource:shortName=apv,categories=argo
+// +kubebuilder:printcolumn:name="Await",type="string",JSONPath=".spec.awaitName"
+// +kubebuilder:printcolumn:name="Decision",type="string",JSONPath=".spec.decision"
+// +kubebuilder:printcolumn:name="Approver",type="string",JSONPath=".spec.approver.username"
+// +kubebuilder:printcolumn:name="Age",type="date",JSONPath=".metadata.creationTimestamp"
+
+// Approval is the Schema for the approvals API, an Approval is a decision
+// of a single user on an Await awaiting the manual approval
+type Approval struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec ApprovalSpec `json:"spec,omitempty"`
+}
+
+// ApprovalDecision is the decision of the approver
+type ApprovalDecision string
+
+const (
+	// ApprovalApprove approves the Await
+	ApprovalApprove ApprovalDecision = "Approve"
+	// ApprovalReject rejects the Await, which fails
+	ApprovalReject ApprovalDecision = "Reject"
+)
+
+// ApprovalSpec defines the decision on the Await
+// +k8s:openapi-gen=true
+type ApprovalSpec struct {
+	// AwaitName is the name of the Await in the namespace of the Approval
+	AwaitName string `json:"awaitName"`
+
+	// AwaitUID is the UID of the approved Await, it is set by the admission webhook
+	// so that the Approval is never counted for another Await of the same name
+	// +optional
+	AwaitUID types.UID `json:"awaitUID,omitempty"`
+
+	// Decision is the decision on the Await, Approve if not set
+	// +kubebuilder:validation:Enum=Approve;Reject
+	// +optional
+	Decision ApprovalDecision `json:"decision,omitempty"`
+
+	// Comment is the comment of the approver
+	// +optional
+	Comment string `json:"comment,omitempty"`
+
+	// Approver is the user who has created the Approval, it is set
+	// by the admission webhook and can not be changed
+	// +optional
+	Approver *Approver `json:"approver,omitempty"`
+}
+
+// Approver is the identity of the user who has created an Approval
+// +k8s:openapi-gen=true
+type Approver struct {
+	Username string   `json:"username"`
+	Groups   []string `json:"groups,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+
+// ApprovalList contains a list of Approval
+type ApprovalList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []Approval `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&Approval{}, &ApprovalList{})
+}
@@ -0,0 +1,139 @@
+/*
+Copyright 2019 Marek Cermak <macermak@redhat.com>.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"reflect"
+
+	admissionv1beta1 "k8s.io/api/admission/v1beta1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
+
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/webhook"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+)
+
+var approvallog = logf.Log.WithName("approval-resource")
+
+// approvalWebhookPath is the path the Approval webhook is served at
+const approvalWebhookPath = "/mutate-await-argoproj-io-v1alpha1-approval"
+
+// SetupApprovalWebhookWithManager registers the Approval webhook with the manager.
+// Unlike the Await webhooks, the identity of the requesting user is needed,
+// hence the Approval is admitted by a plain admission handler.
+func SetupApprovalWebhookWithManager(mgr ctrl.Manager) error {
+	decoder, err := admission.NewDecoder(mgr.GetScheme())
+	if err != nil {
+		return err
+	}
+
+	mgr.GetWebhookServer().Register(approvalWebhookPath, &webhook.Admission{
+		Handler: &approvalAdmission{decoder: decoder, reader: mgr.GetAPIReader()},
+	})
+
+	return nil
+}
+
+// +kubebuilder:webhook:path=/mutate-await-argoproj-io-v1alpha1-approval,mutating=true,failurePolicy=fail,groups=await.argoproj.io,resources=approvals,verbs=create;update,versions=v1alpha1,name=mapproval.kb.io
+
+// approvalAdmission records the approver and the approved Await of the created
+// Approvals and makes sure the Approvals are not changed afterwards
+type approvalAdmission struct {
+	decoder *admission.Decoder
+	// reader reads the approved Awaits, bypassing the cache
+	reader client.Reader
+}
+
+var _ admission.Handler = &approvalAdmission{}
+
+// Handle implements admission.Handler
+func (a *approvalAdmission) Handle(ctx context.Context, req admission.Request) admission.Response {
+	approval := &Approval{}
+	if err := a.decoder.Decode(req, approval); err != nil {
+		return admission.Errored(http.StatusBadRequest, err)
+	}
+	approvallog.Info("admit", "name", approval.Name, "operation", req.Operation)
+
+	if req.Operation == admissionv1beta1.Update {
+		old := &Approval{}
+		if err := a.decoder.DecodeRaw(req.OldObject, old); err != nil {
+			return admission.Errored(http.StatusBadRequest, err)
+		}
+		if !reflect.DeepEqual(old.Spec, approval.Spec) {
+			return admission.Denied("the spec of the Approval can not be changed")
+		}
+		return admission.Allowed("")
+	}
+
+	if approval.Spec.AwaitName == "" {
+		return admission.Denied("spec.awaitName: the name of the approved Await must be specified")
+	}
+	if approval.Spec.Decision == "" {
+		approval.Spec.Decision = ApprovalApprove
+	}
+	if resp := a.bindAwait(ctx, req, approval); resp != nil {
+		return *resp
+	}
+	if req.UserInfo.Username == "" {
+		return admission.Denied("the approver could not be identified")
+	}
+	// the approver is whoever has made the request, regardless of what the user has specified
+	approval.Spec.Approver = &Approver{
+		Username: req.UserInfo.Username,
+		Groups:   req.UserInfo.Groups,
+	}
+
+	current, err := json.Marshal(approval)
+	if err != nil {
+		return admission.Errored(http.StatusInternalServerError, fmt.Errorf("marshal approval: %v", err))
+	}
+
+	return admission.PatchResponseFromRaw(req.Object.Raw, current)
+}
+
+// bindAwait binds the Approval to the UID of the approved Await, a response
+// is returned if the Approval can not be admitted
+func (a *approvalAdmission) bindAwait(ctx context.Context, req admission.Request, approval *Approval) *admission.Response {
+	namespace := req.Namespace
+	if namespace == "" {
+		namespace = approval.Namespace
+	}
+
+	await := &Await{}
+	err := a.reader.Get(ctx, types.NamespacedName{Namespace: namespace, Name: approval.Spec.AwaitName}, await)
+	switch {
+	case apierrors.IsNotFound(err):
+		resp := admission.Denied(fmt.Sprintf("spec.awaitName: the Await %s does not exist", approval.Spec.AwaitName))
+		return &resp
+	case err != nil:
+		resp := admission.Errored(http.StatusInternalServerError, fmt.Errorf("get await: %v", err))
+		return &resp
+	case approval.Spec.AwaitUID != "" && approval.Spec.AwaitUID != await.UID:
+		resp := admission.Denied(fmt.Sprintf("spec.awaitUID: the Await %s has a different UID", approval.Spec.AwaitName))
+		return &resp
+	}
+
+	approval.Spec.AwaitUID = await.UID
+	return nil
+}
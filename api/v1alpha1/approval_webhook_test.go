@@ -0,0 +1,150 @@
+/*
+Copyright 2019 Marek Cermak <macermak@redhat.com>.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	"context"
+	"encoding/json"
+	"reflect"
+	"sort"
+	"testing"
+
+	admissionv1beta1 "k8s.io/api/admission/v1beta1"
+	authenticationv1 "k8s.io/api/authentication/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+)
+
+func newApprovalRequest(t *testing.T, op admissionv1beta1.Operation, spec ApprovalSpec, old *ApprovalSpec) admission.Request {
+	raw := func(spec ApprovalSpec) runtime.RawExtension {
+		approval := &Approval{Spec: spec}
+		approval.APIVersion = GroupVersion.String()
+		approval.Kind = "Approval"
+		approval.Name = "approval"
+		approval.Namespace = "default"
+
+		data, err := json.Marshal(approval)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return runtime.RawExtension{Raw: data}
+	}
+
+	req := admission.Request{AdmissionRequest: admissionv1beta1.AdmissionRequest{
+		Operation: op,
+		Object:    raw(spec),
+		UserInfo:  authenticationv1.UserInfo{Username: "jane", Groups: []string{"release"}},
+	}}
+	if old != nil {
+		req.OldObject = raw(*old)
+	}
+
+	return req
+}
+
+func TestApprovalAdmission_Handle(t *testing.T) {
+	scheme := runtime.NewScheme()
+	if err := AddToScheme(scheme); err != nil {
+		t.Fatal(err)
+	}
+	decoder, err := admission.NewDecoder(scheme)
+	if err != nil {
+		t.Fatal(err)
+	}
+	await := &Await{ObjectMeta: metav1.ObjectMeta{Name: "await", Namespace: "default", UID: "7c1f0a2e-3b4d-4e5f-8a9b-0c1d2e3f4a5b"}}
+	a := &approvalAdmission{decoder: decoder, reader: fake.NewFakeClientWithScheme(scheme, await)}
+
+	approved := ApprovalSpec{
+		AwaitName: "await",
+		Decision:  ApprovalApprove,
+		Approver:  &Approver{Username: "jane", Groups: []string{"release"}},
+	}
+
+	tests := []struct {
+		name        string
+		req         admission.Request
+		wantAllowed bool
+		wantPatches []string
+	}{
+		{
+			name:        "approver and decision are set",
+			req:         newApprovalRequest(t, admissionv1beta1.Create, ApprovalSpec{AwaitName: "await"}, nil),
+			wantAllowed: true,
+			wantPatches: []string{"/spec/approver", "/spec/awaitUID", "/spec/decision"},
+		},
+		{
+			name:        "missing await",
+			req:         newApprovalRequest(t, admissionv1beta1.Create, ApprovalSpec{AwaitName: "missing"}, nil),
+			wantAllowed: false,
+		},
+		{
+			name: "await of another UID",
+			req: newApprovalRequest(t, admissionv1beta1.Create, ApprovalSpec{
+				AwaitName: "await",
+				AwaitUID:  "5b0c1e7d-9f1a-4d2e-8c3b-6a4f2e1d0c9b",
+			}, nil),
+			wantAllowed: false,
+		},
+		{
+			name: "approver can not be impersonated",
+			req: newApprovalRequest(t, admissionv1beta1.Create, ApprovalSpec{
+				AwaitName: "await",
+				Decision:  ApprovalReject,
+				Approver:  &Approver{Username: "admin"},
+			}, nil),
+			wantAllowed: true,
+			wantPatches: []string{"/spec/approver/groups", "/spec/approver/username", "/spec/awaitUID"},
+		},
+		{
+			name:        "missing await name",
+			req:         newApprovalRequest(t, admissionv1beta1.Create, ApprovalSpec{}, nil),
+			wantAllowed: false,
+		},
+		{
+			name:        "unchanged spec",
+			req:         newApprovalRequest(t, admissionv1beta1.Update, approved, &approved),
+			wantAllowed: true,
+		},
+		{
+			name: "changed decision",
+			req: newApprovalRequest(t, admissionv1beta1.Update, ApprovalSpec{
+				AwaitName: "await",
+				Decision:  ApprovalReject,
+				Approver:  approved.Approver,
+			}, &approved),
+			wantAllowed: false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp := a.Handle(context.TODO(), tt.req)
+			if resp.Allowed != tt.wantAllowed {
+				t.Fatalf("approvalAdmission.Handle() allowed = %v, want %v (%v)", resp.Allowed, tt.wantAllowed, resp.Result)
+			}
+			var paths []string
+			for _, patch := range resp.Patches {
+				paths = append(paths, patch.Path)
+			}
+			sort.Strings(paths)
+			if !reflect.DeepEqual(paths, tt.wantPatches) {
+				t.Errorf("approvalAdmission.Handle() patches = %v, want %v", resp.Patches, tt.wantPatches)
+			}
+		})
+	}
+}
@@ -17,10 +17,22 @@ limitations under the License.
 package v1alpha1
 
 import (
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
 // +kubebuilder:object:root=true
+// +kubebuilder:storageversion
+// +kubebuilder:resource:shortName=aw,categories=argo
+// +kubebuilder:subresource:status
+// +kubebuilder:printcolumn:name="Workflow",type="string",JSONPath=".spec.workflow.name"
+// +kubebuilder:printcolumn:name="Kind",type="string",JSONPath=".status.observedKind"
+// +kubebuilder:printcolumn:name="Phase",type="string",JSONPath=".status.phase"
+// +kubebuilder:printcolumn:name="Waiting",type="date",JSONPath=".status.startedAt"
+// +kubebuilder:printcolumn:name="Matched",type="string",JSONPath=".status.matchedObject.name"
+// +kubebuilder:printcolumn:name="Age",type="date",JSONPath=".metadata.creationTimestamp"
 
 // Await is the Schema for the awaits API
 type Await struct {
@@ -34,9 +46,183 @@ type Await struct {
 // AwaitSpec defines the desired state of Await
 // +k8s:openapi-gen=true
 type AwaitSpec struct {
-	Workflow NamespacedWorkflow `json:"workflow"`
-	Resource Resource           `json:"resource"`
-	Filters  []string           `json:"filters,omitempty"`
+	// Workflow is the suspended Workflow to be resumed, ignored if the Target is set
+	// +optional
+	Workflow NamespacedWorkflow `json:"workflow,omitempty"`
+
+	// Target is the object to be resumed, for the kinds other than Workflow
+	// +optional
+	Target *Target `json:"target,omitempty"`
+
+	// Callback is the HTTP request sent once the Resource has been awaited,
+	// the Workflow or Target are optional if it is set
+	// +optional
+	Callback *HTTPCallback `json:"callback,omitempty"`
+
+	// Resource is the Kubernetes resource to be awaited
+	// +optional
+	Resource Resource `json:"resource,omitempty"`
+
+	// HTTP is the external endpoint to be awaited instead of the Resource
+	// +optional
+	HTTP *HTTPEndpoint `json:"http,omitempty"`
+
+	// Schedule is the time to be awaited instead of the Resource
+	// +optional
+	Schedule *Schedule `json:"schedule,omitempty"`
+
+	// Completion is the completion of other Argo Workflows to be awaited instead of the Resource
+	// +optional
+	Completion *WorkflowCompletion `json:"completion,omitempty"`
+
+	// Pod is the exit code or log line of a Pod container to be awaited instead of the Resource
+	// +optional
+	Pod *PodMatch `json:"pod,omitempty"`
+
+	// Event is the CloudEvent to be awaited instead of the Resource
+	// +optional
+	Event *CloudEventMatch `json:"event,omitempty"`
+
+	// Approval is the manual approval to be awaited instead of the Resource
+	// +optional
+	Approval *ApprovalPolicy `json:"approval,omitempty"`
+
+	// Observer selects the type of the observer of the Await, the type
+	// is given by the awaited Resource, HTTP, Completion, Pod or Event if not set
+	// +optional
+	Observer *ObserverSpec `json:"observer,omitempty"`
+
+	// Filters have to be all passed by the awaited resource or the HTTP response body
+	Filters []string `json:"filters,omitempty"`
+
+	// FilterMode is either Level, the filters are passed whenever they match,
+	// or Changed, the filters are passed only when they start to match. The filters
+	// may reference the previous state of the object as _old, e.g. _old.status.phase.
+	// Level if not set.
+	// +kubebuilder:validation:Enum=Level;Changed
+	// +optional
+	FilterMode FilterMode `json:"filterMode,omitempty"`
+
+	// StableFor is the duration the filters have to be passed continuously for
+	// before the Await is fulfilled, the window is reset whenever they are not passed.
+	// The Await is fulfilled right away if not set.
+	// +optional
+	StableFor *metav1.Duration `json:"stableFor,omitempty"`
+
+	// Count is the aggregate condition over the awaited resources, the Await
+	// is fulfilled by the first resource passing the filters if not set
+	// +optional
+	Count *CountCondition `json:"count,omitempty"`
+}
+
+// StableDuration returns the duration the filters have to be passed for, zero if not set
+func (s *AwaitSpec) StableDuration() time.Duration {
+	if s.StableFor == nil {
+		return 0
+	}
+	return s.StableFor.Duration
+}
+
+// TargetRef returns the object to be resumed, which is the Workflow unless the Target is set.
+// It is nil if there is nothing to be resumed, i.e. only the Callback is set.
+func (s *AwaitSpec) TargetRef() *Target {
+	if s.Target != nil {
+		return s.Target
+	}
+	if s.Workflow.Name == "" && s.Callback != nil {
+		return nil
+	}
+
+	return &Target{
+		Kind:      TargetWorkflow,
+		Name:      s.Workflow.Name,
+		Namespace: s.Workflow.Namespace,
+	}
+}
+
+// The kinds the awaits other than of the Resources are reported as
+const (
+	// ObservedKindHTTP is an awaited HTTP endpoint
+	ObservedKindHTTP = "HTTP"
+	// ObservedKindSchedule is an awaited Schedule
+	ObservedKindSchedule = "Schedule"
+	// ObservedKindWorkflow is an awaited Workflow completion
+	ObservedKindWorkflow = "Workflow"
+	// ObservedKindPod is an awaited Pod container exit code or log line
+	ObservedKindPod = "Pod"
+	// ObservedKindCloudEvent is an awaited CloudEvent
+	ObservedKindCloudEvent = "CloudEvent"
+	// ObservedKindApproval is an awaited manual approval
+	ObservedKindApproval = "Approval"
+)
+
+// ObservedKind returns the kind of the awaited Resource,
+// or the ObservedKind* if something else than a Resource is awaited
+func (s *AwaitSpec) ObservedKind() string {
+	switch {
+	case s.HTTP != nil:
+		return ObservedKindHTTP
+	case s.Schedule != nil:
+		return ObservedKindSchedule
+	case s.Completion != nil:
+		return ObservedKindWorkflow
+	case s.Pod != nil:
+		return ObservedKindPod
+	case s.Event != nil:
+		return ObservedKindCloudEvent
+	case s.Approval != nil:
+		return ObservedKindApproval
+	case s.Observer != nil && !IsBuiltinObserver(s.Observer.Type):
+		return s.Observer.Type
+	}
+
+	return s.Resource.Kind
+}
+
+// The types of the built-in observers
+const (
+	ObserverTypeResource   = "resource"
+	ObserverTypeHTTP       = "http"
+	ObserverTypeWorkflow   = "workflow"
+	ObserverTypePod        = "pod"
+	ObserverTypeCloudEvent = "cloudevent"
+)
+
+// IsBuiltinObserver returns whether the observer type is one of the ObserverType*
+func IsBuiltinObserver(observerType string) bool {
+	switch observerType {
+	case ObserverTypeResource, ObserverTypeHTTP, ObserverTypeWorkflow, ObserverTypePod, ObserverTypeCloudEvent:
+		return true
+	}
+	return false
+}
+
+// ObserverType returns the type of the observer of the Await,
+// empty if the Await is fulfilled by the controller itself
+func (s *AwaitSpec) ObserverType() string {
+	if s.Observer != nil && s.Observer.Type != "" {
+		return s.Observer.Type
+	}
+
+	return s.builtinObserverType()
+}
+
+// builtinObserverType returns the type of the built-in observer given by the spec
+func (s *AwaitSpec) builtinObserverType() string {
+	switch {
+	case s.HTTP != nil:
+		return ObserverTypeHTTP
+	case s.Completion != nil:
+		return ObserverTypeWorkflow
+	case s.Pod != nil:
+		return ObserverTypePod
+	case s.Event != nil:
+		return ObserverTypeCloudEvent
+	case s.Schedule != nil, s.Approval != nil:
+		return ""
+	}
+
+	return ObserverTypeResource
 }
 
 // Resource defines the Resource to be awaited
@@ -56,11 +242,301 @@ type NamespacedWorkflow struct {
 	Namespace string `json:"namespace"`
 }
 
+// The kinds of the objects which can be resumed
+const (
+	// TargetWorkflow is an Argo Workflow suspended as a whole or at a suspend node
+	TargetWorkflow = "Workflow"
+	// TargetCronWorkflow is an Argo CronWorkflow with the suspend flag set
+	TargetCronWorkflow = "CronWorkflow"
+	// TargetPipelineRun is a Tekton PipelineRun which is pending
+	TargetPipelineRun = "PipelineRun"
+	// TargetDeployment is a paused Deployment
+	TargetDeployment = "Deployment"
+	// TargetJob is a suspended Job
+	TargetJob = "Job"
+)
+
+// Target defines the object to be resumed
+// +k8s:openapi-gen=true
+type Target struct {
+	// Kind is the kind of the object
+	// +kubebuilder:validation:Enum=Workflow;CronWorkflow;PipelineRun;Deployment;Job
+	Kind      string `json:"kind"`
+	Name      string `json:"name"`
+	Namespace string `json:"namespace"`
+}
+
+// HTTPEndpoint defines the external HTTP endpoint to be awaited. The endpoint is polled
+// until it responds with the status code and a JSON body passing the filters.
+// +k8s:openapi-gen=true
+type HTTPEndpoint struct {
+	// URL is the http or https URL to be polled
+	URL string `json:"url"`
+
+	// Interval is the interval between the requests, defaults to 10s
+	// +optional
+	Interval *metav1.Duration `json:"interval,omitempty"`
+
+	// StatusCode is the expected status code of the response, any 2xx code if not set
+	// +optional
+	StatusCode int32 `json:"statusCode,omitempty"`
+}
+
+// Schedule defines the time the Await is fulfilled at, either At or Cron has to be set
+// +k8s:openapi-gen=true
+type Schedule struct {
+	// At is the absolute time the Await is fulfilled at
+	// +optional
+	At *metav1.Time `json:"at,omitempty"`
+
+	// Cron is the standard cron expression, the Await is fulfilled at the next time
+	// matching it after the Await has started waiting
+	// +optional
+	Cron string `json:"cron,omitempty"`
+
+	// TimeZone is the IANA time zone of the Cron, UTC if not set
+	// +optional
+	TimeZone string `json:"timeZone,omitempty"`
+}
+
+// CountOperator is the aggregate condition over the awaited resources
+type CountOperator string
+
+const (
+	// CountAtLeast is fulfilled once at least Value resources pass the filters
+	CountAtLeast CountOperator = "AtLeast"
+	// CountAll is fulfilled once all the selected resources pass the filters, there has to be at least one
+	CountAll CountOperator = "All"
+	// CountNone is fulfilled once none of the selected resources pass the filters,
+	// i.e. once no resource remains if there are no filters
+	CountNone CountOperator = "None"
+)
+
+// CountCondition defines the aggregate condition over the awaited resources
+// +k8s:openapi-gen=true
+type CountCondition struct {
+	// Operator is either AtLeast, All or None
+	// +kubebuilder:validation:Enum=AtLeast;All;None
+	Operator CountOperator `json:"operator"`
+
+	// Value is the minimum number of the resources passing the filters for AtLeast, 1 if not set
+	// +optional
+	Value int32 `json:"value,omitempty"`
+
+	// Selector selects the resources the condition is evaluated over,
+	// all the resources of the kind in the namespace if not set
+	// +optional
+	Selector *metav1.LabelSelector `json:"selector,omitempty"`
+}
+
+// WorkflowOutcome is the awaited outcome of a Workflow
+type WorkflowOutcome string
+
+const (
+	// OutcomeSucceeded is awaited by default, the Workflow has to succeed
+	OutcomeSucceeded WorkflowOutcome = "Succeeded"
+	// OutcomeFailed means the Workflow has to fail or error
+	OutcomeFailed WorkflowOutcome = "Failed"
+	// OutcomeAny means the Workflow has to complete with any outcome
+	OutcomeAny WorkflowOutcome = "Any"
+)
+
+// WorkflowCompletion defines the Argo Workflows whose completion is awaited,
+// either Name or Selector has to be set
+// +k8s:openapi-gen=true
+type WorkflowCompletion struct {
+	// Name is the name of the awaited Workflow
+	// +optional
+	Name string `json:"name,omitempty"`
+
+	// Namespace is the namespace of the awaited Workflows, the Await namespace if not set
+	// +optional
+	Namespace string `json:"namespace,omitempty"`
+
+	// Selector selects the awaited Workflows by their labels,
+	// the completion of any of them fulfills the Await
+	// +optional
+	Selector *metav1.LabelSelector `json:"selector,omitempty"`
+
+	// Node is the name of the node whose outcome is awaited instead of the whole Workflow
+	// +optional
+	Node string `json:"node,omitempty"`
+
+	// Outcome is the awaited outcome, Succeeded if not set
+	// +kubebuilder:validation:Enum=Succeeded;Failed;Any
+	// +optional
+	Outcome WorkflowOutcome `json:"outcome,omitempty"`
+}
+
+// PodMatch defines the Pods whose container exit code or log line is awaited,
+// either Name or Selector and either ExitCode or LogPattern have to be set
+// +k8s:openapi-gen=true
+type PodMatch struct {
+	// Name is the name of the awaited Pod
+	// +optional
+	Name string `json:"name,omitempty"`
+
+	// Namespace is the namespace of the awaited Pods, the Await namespace if not set
+	// +optional
+	Namespace string `json:"namespace,omitempty"`
+
+	// Selector selects the awaited Pods by their labels
+	// +optional
+	Selector *metav1.LabelSelector `json:"selector,omitempty"`
+
+	// Container is the name of the awaited container, any container is matched
+	// by the ExitCode and the first one is used for the LogPattern if not set
+	// +optional
+	Container string `json:"container,omitempty"`
+
+	// ExitCode is the awaited exit code of the terminated container
+	// +optional
+	ExitCode *int32 `json:"exitCode,omitempty"`
+
+	// LogPattern is the regular expression matched against the log lines of the container
+	// +optional
+	LogPattern string `json:"logPattern,omitempty"`
+}
+
+// CloudEventMatch defines the CloudEvent to be awaited, the event is received
+// by the operator for the namespace of the Await and has to pass the filters as well
+// +k8s:openapi-gen=true
+type CloudEventMatch struct {
+	// Type is the awaited type of the event, any type if not set
+	// +optional
+	Type string `json:"type,omitempty"`
+
+	// Source is the awaited source of the event, any source if not set
+	// +optional
+	Source string `json:"source,omitempty"`
+}
+
+// FilterMode is the mode the filters are evaluated in
+type FilterMode string
+
+const (
+	// FilterModeLevel passes the filters whenever they match
+	FilterModeLevel FilterMode = "Level"
+	// FilterModeChanged passes the filters only when they start to match
+	FilterModeChanged FilterMode = "Changed"
+)
+
+// ObserverSpec selects the observer of the Await
+// +k8s:openapi-gen=true
+type ObserverSpec struct {
+	// Type is the type of the observer the observers are registered with
+	Type string `json:"type"`
+
+	// Parameters configure the observers of the types other than the built-in ones
+	// +optional
+	Parameters map[string]string `json:"parameters,omitempty"`
+}
+
+// ApprovalPolicy defines the manual approval to be awaited, the Await is approved
+// by creating the Approvals referencing it
+// +k8s:openapi-gen=true
+type ApprovalPolicy struct {
+	// Approvals is the number of the distinct approvers required, 1 if not set
+	// +kubebuilder:validation:Minimum=1
+	// +optional
+	Approvals int32 `json:"approvals,omitempty"`
+
+	// Groups restricts the approvers to the members of any of the groups
+	// +optional
+	Groups []string `json:"groups,omitempty"`
+}
+
+// ApprovalRecord is the audit record of an Approval counted by the controller
+// +k8s:openapi-gen=true
+type ApprovalRecord struct {
+	// Name is the name of the Approval
+	Name     string           `json:"name"`
+	Approver string           `json:"approver"`
+	Decision ApprovalDecision `json:"decision"`
+	Comment  string           `json:"comment,omitempty"`
+	Time     metav1.Time      `json:"time"`
+}
+
+// HTTPCallback defines the HTTP request sent once the Resource has been awaited.
+// The Await metadata and the matched object are POSTed as JSON to the URL.
+// +k8s:openapi-gen=true
+type HTTPCallback struct {
+	// URL is the http or https URL the payload is sent to
+	URL string `json:"url"`
+
+	// SecretRef selects the key of a Secret in the Await namespace, the payload
+	// is signed with HMAC-SHA256 using its value if set
+	// +optional
+	SecretRef *corev1.SecretKeySelector `json:"secretRef,omitempty"`
+}
+
+// AwaitPhase is the current phase of the Await
+type AwaitPhase string
+
+const (
+	// AwaitPending means that the Workflow has not been suspended yet
+	AwaitPending AwaitPhase = "Pending"
+	// AwaitWaiting means that the Resource is being awaited
+	AwaitWaiting AwaitPhase = "Waiting"
+	// AwaitFulfilled means that the Resource has been awaited, but the Workflow not resumed yet
+	AwaitFulfilled AwaitPhase = "Fulfilled"
+	// AwaitResumed means that the Resource has been awaited and the Workflow resumed
+	AwaitResumed AwaitPhase = "Resumed"
+	// AwaitCompleted means that the Resource has been awaited and the Callback sent,
+	// with no Workflow or Target to be resumed
+	AwaitCompleted AwaitPhase = "Completed"
+	// AwaitSkipped means that the Resource has been awaited, but the Workflow
+	// was no longer suspended at the node the Await was created for
+	AwaitSkipped AwaitPhase = "Skipped"
+	// AwaitFailed means that the Resource could not be awaited or the Workflow resumed
+	AwaitFailed AwaitPhase = "Failed"
+)
+
 // AwaitStatus defines the observed state of Await
 // +k8s:openapi-gen=true
 type AwaitStatus struct {
+	Phase      AwaitPhase  `json:"phase,omitempty"`
 	StartedAt  metav1.Time `json:"startedAt,omitempty"`
 	FinishedAt metav1.Time `json:"finishedAt,omitempty"`
+
+	// ObservedKind is the kind of the awaited Resource, or the kind of whatever
+	// else is awaited, e.g. HTTP or Approval, set once the Await starts waiting
+	// +optional
+	ObservedKind string `json:"observedKind,omitempty"`
+
+	// MatchedObject references the resource which fulfilled the Await
+	MatchedObject *corev1.ObjectReference `json:"matchedObject,omitempty"`
+
+	// MatchedCount is the number of the resources passing the filters
+	// when the Count condition was fulfilled
+	// +optional
+	MatchedCount int32 `json:"matchedCount,omitempty"`
+
+	// SuspendedNode is the ID of the Workflow suspend node the Await was created for,
+	// empty if the whole Workflow has been suspended
+	// +optional
+	SuspendedNode string `json:"suspendedNode,omitempty"`
+
+	// Outputs are the output parameters of the awaited Workflow, they are set
+	// as the outputs of the suspend node when the Workflow is resumed
+	// +optional
+	Outputs map[string]string `json:"outputs,omitempty"`
+
+	// Approvals are the decisions of the approvers of the Await
+	// +optional
+	Approvals []ApprovalRecord `json:"approvals,omitempty"`
+
+	// Message is a human readable description of the current phase
+	// +optional
+	Message string `json:"message,omitempty"`
+
+	// ResumeAttempts is the number of failed attempts to resume the Workflow
+	// +optional
+	ResumeAttempts int32 `json:"resumeAttempts,omitempty"`
+
+	// LastResumeError is the error of the last failed attempt to resume the Workflow
+	// +optional
+	LastResumeError string `json:"lastResumeError,omitempty"`
 }
 
 // +kubebuilder:object:root=true
@@ -0,0 +1,536 @@
+/*
+Copyright 2019 Marek Cermak <macermak@redhat.com>.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	"fmt"
+	"net/url"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/cermakm/argo-await-operator/observers/filter"
+	"github.com/cermakm/argo-await-operator/observers/schedule"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	metav1validation "k8s.io/apimachinery/pkg/apis/meta/v1/validation"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+
+	ctrl "sigs.k8s.io/controller-runtime"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/webhook"
+)
+
+var awaitlog = logf.Log.WithName("await-resource")
+
+// DefaultHTTPInterval is the default interval of polling the HTTP endpoints
+const DefaultHTTPInterval = 10 * time.Second
+
+// restMapper is used to look up the awaited resources,
+// it is set up together with the webhooks
+var restMapper meta.RESTMapper
+
+// SetupWebhookWithManager registers the Await webhooks with the manager
+func (r *Await) SetupWebhookWithManager(mgr ctrl.Manager) error {
+	restMapper = mgr.GetRESTMapper()
+
+	return ctrl.NewWebhookManagedBy(mgr).
+		For(r).
+		Complete()
+}
+
+// +kubebuilder:webhook:path=/mutate-await-argoproj-io-v1alpha1-await,mutating=true,failurePolicy=fail,groups=await.argoproj.io,resources=awaits,verbs=create;update,versions=v1alpha1,name=mawait.kb.io
+
+var _ webhook.Defaulter = &Await{}
+
+// Default implements webhook.Defaulter
+func (r *Await) Default() {
+	awaitlog.Info("default", "name", r.Name)
+
+	if r.Spec.Target != nil {
+		if r.Spec.Target.Namespace == "" {
+			r.Spec.Target.Namespace = r.Namespace
+		}
+	} else if r.Spec.Workflow.Namespace == "" && r.Spec.TargetRef() != nil {
+		r.Spec.Workflow.Namespace = r.Namespace
+	}
+
+	if r.Spec.Count != nil && r.Spec.Count.Operator == CountAtLeast && r.Spec.Count.Value == 0 {
+		r.Spec.Count.Value = 1
+	}
+
+	if r.Spec.Pod != nil {
+		if r.Spec.Pod.Namespace == "" {
+			r.Spec.Pod.Namespace = r.Namespace
+		}
+	} else if r.Spec.Completion != nil {
+		if r.Spec.Completion.Namespace == "" {
+			r.Spec.Completion.Namespace = r.Namespace
+		}
+		if r.Spec.Completion.Outcome == "" {
+			r.Spec.Completion.Outcome = OutcomeSucceeded
+		}
+	} else if r.Spec.HTTP != nil {
+		if r.Spec.HTTP.Interval == nil {
+			r.Spec.HTTP.Interval = &metav1.Duration{Duration: DefaultHTTPInterval}
+		}
+	} else if r.Spec.Approval != nil {
+		if r.Spec.Approval.Approvals == 0 {
+			r.Spec.Approval.Approvals = 1
+		}
+	} else if r.Spec.ObserverType() == ObserverTypeResource && restMapper != nil {
+		r.defaultResource()
+	}
+}
+
+// defaultResource fills in the group, version, kind and plural name
+// of the awaited resource from whatever the user has specified
+func (r *Await) defaultResource() {
+	res := &r.Spec.Resource
+	log := awaitlog.WithValues("name", r.Name, "resource", *res)
+
+	gk := schema.GroupKind{Group: res.Group, Kind: res.Kind}
+	if res.Name != "" && (res.Kind == "" || res.Group == "") {
+		// the plural name is matched across all the groups
+		gvk, err := restMapper.KindFor(schema.GroupVersionResource{
+			Group:    res.Group,
+			Version:  res.Version,
+			Resource: res.Name,
+		})
+		if err != nil {
+			log.Info("unable to resolve the resource kind", "error", err.Error())
+			return
+		}
+		gk = gvk.GroupKind()
+	}
+	if gk.Kind == "" {
+		return
+	}
+
+	var versions []string
+	if res.Version != "" {
+		versions = append(versions, res.Version)
+	}
+
+	// with no version given, the mapping contains the preferred version
+	mapping, err := restMapper.RESTMapping(gk, versions...)
+	if err != nil {
+		log.Info("unable to resolve the resource mapping", "error", err.Error())
+		return
+	}
+
+	res.Group = mapping.GroupVersionKind.Group
+	res.Kind = mapping.GroupVersionKind.Kind
+	if res.Version == "" {
+		res.Version = mapping.GroupVersionKind.Version
+	}
+	if res.Name == "" {
+		res.Name = mapping.Resource.Resource
+	}
+}
+
+// +kubebuilder:webhook:verbs=create;update,path=/validate-await-argoproj-io-v1alpha1-await,mutating=false,failurePolicy=fail,groups=await.argoproj.io,resources=awaits,versions=v1alpha1,name=vawait.kb.io
+
+var _ webhook.Validator = &Await{}
+
+// ValidateCreate implements webhook.Validator
+func (r *Await) ValidateCreate() error {
+	awaitlog.Info("validate create", "name", r.Name)
+
+	return r.validateAwait()
+}
+
+// ValidateUpdate implements webhook.Validator
+func (r *Await) ValidateUpdate(old runtime.Object) error {
+	awaitlog.Info("validate update", "name", r.Name)
+
+	return r.validateAwait()
+}
+
+// ValidateDelete implements webhook.Validator
+func (r *Await) ValidateDelete() error {
+	return nil
+}
+
+func (r *Await) validateAwait() error {
+	var allErrs field.ErrorList
+
+	specPath := field.NewPath("spec")
+	if r.Spec.Target != nil {
+		allErrs = append(allErrs, validateTarget(r.Spec.Target, specPath.Child("target"))...)
+	} else if r.Spec.TargetRef() != nil {
+		allErrs = append(allErrs, validateWorkflow(&r.Spec.Workflow, specPath.Child("workflow"))...)
+	}
+	if r.Spec.Callback != nil {
+		allErrs = append(allErrs, validateCallback(r.Spec.Callback, specPath.Child("callback"))...)
+	}
+	allErrs = append(allErrs, validateObserved(&r.Spec, specPath)...)
+	if r.Spec.Schedule != nil && len(r.Spec.Filters) > 0 {
+		allErrs = append(allErrs, field.Forbidden(specPath.Child("filters"), "filters must not be set together with schedule"))
+	} else if r.Spec.Approval != nil && len(r.Spec.Filters) > 0 {
+		allErrs = append(allErrs, field.Forbidden(specPath.Child("filters"), "filters must not be set together with approval"))
+	} else {
+		allErrs = append(allErrs, validateFilters(r.Spec.Filters, specPath.Child("filters"))...)
+	}
+	if r.Spec.FilterMode == FilterModeChanged && !tracksObjects(r.Spec.ObserverType()) {
+		allErrs = append(allErrs, field.Forbidden(specPath.Child("filterMode"),
+			fmt.Sprintf("filter mode %s is not supported for %s", FilterModeChanged, r.Spec.ObservedKind())))
+	}
+	allErrs = append(allErrs, validateStableFor(&r.Spec, specPath.Child("stableFor"))...)
+	if r.Spec.Count != nil {
+		allErrs = append(allErrs, validateCount(&r.Spec, specPath.Child("count"))...)
+	}
+
+	if len(allErrs) == 0 {
+		return nil
+	}
+
+	return apierrors.NewInvalid(GroupVersion.WithKind("Await").GroupKind(), r.Name, allErrs)
+}
+
+func validateWorkflow(workflow *NamespacedWorkflow, fldPath *field.Path) field.ErrorList {
+	var allErrs field.ErrorList
+
+	if workflow.Name == "" {
+		allErrs = append(allErrs, field.Required(fldPath.Child("name"), "workflow name must be specified"))
+	}
+	if workflow.Namespace == "" {
+		allErrs = append(allErrs, field.Required(fldPath.Child("namespace"), "workflow namespace must be specified"))
+	}
+
+	return allErrs
+}
+
+// targetKinds are the kinds of the objects which can be resumed
+var targetKinds = []string{
+	TargetWorkflow, TargetCronWorkflow, TargetPipelineRun, TargetDeployment, TargetJob,
+}
+
+func validateTarget(target *Target, fldPath *field.Path) field.ErrorList {
+	var allErrs field.ErrorList
+
+	supported := false
+	for _, kind := range targetKinds {
+		if target.Kind == kind {
+			supported = true
+		}
+	}
+	if !supported {
+		allErrs = append(allErrs, field.NotSupported(fldPath.Child("kind"), target.Kind, targetKinds))
+	}
+	if target.Name == "" {
+		allErrs = append(allErrs, field.Required(fldPath.Child("name"), "target name must be specified"))
+	}
+	if target.Namespace == "" {
+		allErrs = append(allErrs, field.Required(fldPath.Child("namespace"), "target namespace must be specified"))
+	}
+
+	return allErrs
+}
+
+// tracksObjects returns whether the observers of the type keep the state of the objects
+// to evaluate the filters against and to hold them back until stable, which the plugins may do as well
+func tracksObjects(observerType string) bool {
+	switch observerType {
+	case ObserverTypeResource, ObserverTypeHTTP:
+		return true
+	}
+	return observerType != "" && !IsBuiltinObserver(observerType)
+}
+
+// validateStableFor checks that the stable window is supported by the observer,
+// a transition can not be held for a duration
+func validateStableFor(spec *AwaitSpec, fldPath *field.Path) field.ErrorList {
+	var allErrs field.ErrorList
+	if spec.StableFor == nil {
+		return allErrs
+	}
+
+	switch {
+	case spec.StableFor.Duration < 0:
+		allErrs = append(allErrs, field.Invalid(fldPath, spec.StableFor.Duration.String(), "must not be negative"))
+	case !tracksObjects(spec.ObserverType()):
+		allErrs = append(allErrs, field.Forbidden(fldPath,
+			fmt.Sprintf("stable window is not supported for %s", spec.ObservedKind())))
+	case spec.FilterMode == FilterModeChanged:
+		allErrs = append(allErrs, field.Forbidden(fldPath,
+			fmt.Sprintf("stable window is not supported with filter mode %s", FilterModeChanged)))
+	}
+	return allErrs
+}
+
+// validateCount checks that the Count condition is evaluated over the resources,
+// the transitions of the resources can not be counted
+func validateCount(spec *AwaitSpec, fldPath *field.Path) field.ErrorList {
+	var allErrs field.ErrorList
+	count := spec.Count
+
+	if spec.ObserverType() != ObserverTypeResource {
+		allErrs = append(allErrs, field.Forbidden(fldPath,
+			fmt.Sprintf("count is not supported for %s", spec.ObservedKind())))
+	}
+	if spec.FilterMode == FilterModeChanged {
+		allErrs = append(allErrs, field.Forbidden(fldPath,
+			fmt.Sprintf("count is not supported with filter mode %s", FilterModeChanged)))
+	}
+
+	switch count.Operator {
+	case CountAtLeast:
+		if count.Value < 0 {
+			allErrs = append(allErrs, field.Invalid(fldPath.Child("value"), count.Value, "must not be negative"))
+		}
+	case CountAll, CountNone:
+		if count.Value != 0 {
+			allErrs = append(allErrs, field.Forbidden(fldPath.Child("value"),
+				fmt.Sprintf("value is only supported with operator %s", CountAtLeast)))
+		}
+	default:
+		allErrs = append(allErrs, field.NotSupported(fldPath.Child("operator"), count.Operator,
+			[]string{string(CountAtLeast), string(CountAll), string(CountNone)}))
+	}
+
+	if count.Selector != nil {
+		allErrs = append(allErrs, metav1validation.ValidateLabelSelector(count.Selector, fldPath.Child("selector"))...)
+	}
+	return allErrs
+}
+
+// validateObserved checks that exactly one of the Resource or the alternatives
+// of it is awaited, including the observers of the types other than the built-in ones
+func validateObserved(spec *AwaitSpec, fldPath *field.Path) field.ErrorList {
+	var allErrs field.ErrorList
+
+	var observed []string
+	if spec.HTTP != nil {
+		observed = append(observed, "http")
+		allErrs = append(allErrs, validateHTTP(spec.HTTP, fldPath.Child("http"))...)
+	}
+	if spec.Schedule != nil {
+		observed = append(observed, "schedule")
+		allErrs = append(allErrs, validateSchedule(spec.Schedule, fldPath.Child("schedule"))...)
+	}
+
+	if spec.Completion != nil {
+		observed = append(observed, "completion")
+		allErrs = append(allErrs, validateCompletion(spec.Completion, fldPath.Child("completion"))...)
+	}
+
+	if spec.Pod != nil {
+		observed = append(observed, "pod")
+		allErrs = append(allErrs, validatePod(spec.Pod, fldPath.Child("pod"))...)
+	}
+
+	if spec.Event != nil {
+		observed = append(observed, "event")
+	}
+
+	if spec.Approval != nil {
+		observed = append(observed, "approval")
+		if spec.Approval.Approvals < 0 {
+			allErrs = append(allErrs, field.Invalid(fldPath.Child("approval", "approvals"), spec.Approval.Approvals, "must be positive"))
+		}
+	}
+
+	if spec.Observer != nil {
+		observerPath := fldPath.Child("observer", "type")
+		switch builtin := spec.builtinObserverType(); {
+		case spec.Observer.Type == "":
+			allErrs = append(allErrs, field.Required(observerPath, "observer type must be specified"))
+		case !IsBuiltinObserver(spec.Observer.Type):
+			observed = append(observed, "observer")
+		case spec.Observer.Type != builtin:
+			allErrs = append(allErrs, field.Invalid(observerPath, spec.Observer.Type,
+				fmt.Sprintf("observer type does not match the awaited %s", spec.ObservedKind())))
+		}
+	}
+
+	if len(observed) == 0 {
+		return append(allErrs, validateResource(&spec.Resource, fldPath.Child("resource"))...)
+	}
+	if spec.Resource != (Resource{}) {
+		observed = append(observed, "resource")
+	}
+	if len(observed) > 1 {
+		allErrs = append(allErrs, field.Forbidden(fldPath,
+			fmt.Sprintf("only one of %s may be awaited", strings.Join(observed, ", "))))
+	}
+
+	return allErrs
+}
+
+// workflowOutcomes are the outcomes of the Workflows which can be awaited
+var workflowOutcomes = []string{
+	string(OutcomeSucceeded), string(OutcomeFailed), string(OutcomeAny),
+}
+
+func validateCompletion(completion *WorkflowCompletion, fldPath *field.Path) field.ErrorList {
+	var allErrs field.ErrorList
+
+	if completion.Name == "" && completion.Selector == nil {
+		allErrs = append(allErrs, field.Required(fldPath, "either name or selector must be set"))
+	}
+	if completion.Selector != nil {
+		allErrs = append(allErrs, metav1validation.ValidateLabelSelector(completion.Selector, fldPath.Child("selector"))...)
+	}
+	if completion.Outcome != "" {
+		supported := false
+		for _, outcome := range workflowOutcomes {
+			if string(completion.Outcome) == outcome {
+				supported = true
+			}
+		}
+		if !supported {
+			allErrs = append(allErrs, field.NotSupported(fldPath.Child("outcome"), completion.Outcome, workflowOutcomes))
+		}
+	}
+
+	return allErrs
+}
+
+func validatePod(pod *PodMatch, fldPath *field.Path) field.ErrorList {
+	var allErrs field.ErrorList
+
+	if pod.Name == "" && pod.Selector == nil {
+		allErrs = append(allErrs, field.Required(fldPath, "either name or selector must be set"))
+	}
+	if pod.Selector != nil {
+		allErrs = append(allErrs, metav1validation.ValidateLabelSelector(pod.Selector, fldPath.Child("selector"))...)
+	}
+
+	switch {
+	case pod.ExitCode == nil && pod.LogPattern == "":
+		allErrs = append(allErrs, field.Required(fldPath, "either exitCode or logPattern must be set"))
+	case pod.ExitCode != nil && pod.LogPattern != "":
+		allErrs = append(allErrs, field.Forbidden(fldPath.Child("logPattern"), "logPattern must not be set together with exitCode"))
+	case pod.LogPattern != "":
+		if _, err := regexp.Compile(pod.LogPattern); err != nil {
+			allErrs = append(allErrs, field.Invalid(fldPath.Child("logPattern"), pod.LogPattern, err.Error()))
+		}
+	}
+
+	return allErrs
+}
+
+func validateSchedule(s *Schedule, fldPath *field.Path) field.ErrorList {
+	var allErrs field.ErrorList
+
+	switch {
+	case s.At == nil && s.Cron == "":
+		allErrs = append(allErrs, field.Required(fldPath, "either at or cron must be set"))
+	case s.At != nil && s.Cron != "":
+		allErrs = append(allErrs, field.Forbidden(fldPath.Child("cron"), "cron must not be set together with at"))
+	case s.At != nil && s.TimeZone != "":
+		allErrs = append(allErrs, field.Forbidden(fldPath.Child("timeZone"), "time zone applies only to cron"))
+	case s.Cron != "":
+		if _, err := schedule.Cron(s.Cron, s.TimeZone); err != nil {
+			allErrs = append(allErrs, field.Invalid(fldPath.Child("cron"), s.Cron, err.Error()))
+		}
+	}
+
+	return allErrs
+}
+
+func validateHTTP(endpoint *HTTPEndpoint, fldPath *field.Path) field.ErrorList {
+	var allErrs field.ErrorList
+
+	if err := validateURL(endpoint.URL); err != nil {
+		allErrs = append(allErrs, field.Invalid(fldPath.Child("url"), endpoint.URL, err.Error()))
+	}
+	if endpoint.Interval != nil && endpoint.Interval.Duration <= 0 {
+		allErrs = append(allErrs, field.Invalid(fldPath.Child("interval"), endpoint.Interval.Duration.String(), "interval must be positive"))
+	}
+	if endpoint.StatusCode != 0 && (endpoint.StatusCode < 100 || endpoint.StatusCode > 599) {
+		allErrs = append(allErrs, field.Invalid(fldPath.Child("statusCode"), endpoint.StatusCode, "status code must be between 100 and 599"))
+	}
+
+	return allErrs
+}
+
+// validateURL checks the URL is an absolute http or https URL
+func validateURL(rawURL string) error {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return err
+	}
+	if (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("url must be an absolute http or https URL")
+	}
+
+	return nil
+}
+
+func validateCallback(callback *HTTPCallback, fldPath *field.Path) field.ErrorList {
+	var allErrs field.ErrorList
+
+	if err := validateURL(callback.URL); err != nil {
+		allErrs = append(allErrs, field.Invalid(fldPath.Child("url"), callback.URL, err.Error()))
+	}
+
+	if callback.SecretRef != nil {
+		if callback.SecretRef.Name == "" {
+			allErrs = append(allErrs, field.Required(fldPath.Child("secretRef", "name"), "secret name must be specified"))
+		}
+		if callback.SecretRef.Key == "" {
+			allErrs = append(allErrs, field.Required(fldPath.Child("secretRef", "key"), "secret key must be specified"))
+		}
+	}
+
+	return allErrs
+}
+
+func validateResource(res *Resource, fldPath *field.Path) field.ErrorList {
+	var allErrs field.ErrorList
+
+	if res.Kind == "" {
+		allErrs = append(allErrs, field.Required(fldPath.Child("kind"), "resource kind must be specified"))
+	}
+	if res.Name == "" {
+		allErrs = append(allErrs, field.Required(fldPath.Child("name"), "resource name must be specified"))
+	}
+	if len(allErrs) > 0 || restMapper == nil {
+		return allErrs
+	}
+
+	gk := schema.GroupKind{Group: res.Group, Kind: res.Kind}
+	mapping, err := restMapper.RESTMapping(gk, res.Version)
+	if err != nil {
+		return append(allErrs, field.Invalid(fldPath.Child("kind"), res.Kind, err.Error()))
+	}
+
+	if mapping.Resource.Resource != res.Name {
+		allErrs = append(allErrs, field.Invalid(
+			fldPath.Child("name"), res.Name, "resource name must be the plural name of the kind: "+mapping.Resource.Resource))
+	}
+
+	return allErrs
+}
+
+func validateFilters(filters []string, fldPath *field.Path) field.ErrorList {
+	var allErrs field.ErrorList
+
+	for i, f := range filters {
+		if err := filter.Validate(f); err != nil {
+			allErrs = append(allErrs, field.Invalid(fldPath.Index(i), f, err.Error()))
+		}
+	}
+
+	return allErrs
+}
@@ -0,0 +1,579 @@
+/*
+Copyright 2019 Marek Cermak <macermak@redhat.com>.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+func newFakeRESTMapper() meta.RESTMapper {
+	gv := schema.GroupVersion{Group: "", Version: "v1"}
+
+	appsGV := schema.GroupVersion{Group: "apps", Version: "v1"}
+
+	mapper := meta.NewDefaultRESTMapper([]schema.GroupVersion{gv, appsGV})
+	mapper.Add(gv.WithKind("ConfigMap"), meta.RESTScopeNamespace)
+	mapper.Add(appsGV.WithKind("Deployment"), meta.RESTScopeNamespace)
+
+	return mapper
+}
+
+func TestAwait_Default(t *testing.T) {
+	restMapper = newFakeRESTMapper()
+	defer func() { restMapper = nil }()
+
+	tests := []struct {
+		name string
+		spec AwaitSpec
+		want AwaitSpec
+	}{
+		{
+			name: "workflow namespace defaults to the await namespace",
+			spec: AwaitSpec{
+				Workflow: NamespacedWorkflow{Name: "workflow"},
+				Resource: Resource{Name: "configmaps", Version: "v1", Kind: "ConfigMap"},
+			},
+			want: AwaitSpec{
+				Workflow: NamespacedWorkflow{Name: "workflow", Namespace: "default"},
+				Resource: Resource{Name: "configmaps", Version: "v1", Kind: "ConfigMap"},
+			},
+		},
+		{
+			name: "plural name and version from kind",
+			spec: AwaitSpec{
+				Workflow: NamespacedWorkflow{Name: "workflow", Namespace: "argo"},
+				Resource: Resource{Group: "apps", Kind: "Deployment"},
+			},
+			want: AwaitSpec{
+				Workflow: NamespacedWorkflow{Name: "workflow", Namespace: "argo"},
+				Resource: Resource{Name: "deployments", Group: "apps", Version: "v1", Kind: "Deployment"},
+			},
+		},
+		{
+			name: "group, version and kind from plural name",
+			spec: AwaitSpec{
+				Workflow: NamespacedWorkflow{Name: "workflow", Namespace: "argo"},
+				Resource: Resource{Name: "deployments"},
+			},
+			want: AwaitSpec{
+				Workflow: NamespacedWorkflow{Name: "workflow", Namespace: "argo"},
+				Resource: Resource{Name: "deployments", Group: "apps", Version: "v1", Kind: "Deployment"},
+			},
+		},
+		{
+			name: "target namespace defaults to the await namespace",
+			spec: AwaitSpec{
+				Target:   &Target{Kind: TargetDeployment, Name: "deployment"},
+				Resource: Resource{Name: "configmaps", Version: "v1", Kind: "ConfigMap"},
+			},
+			want: AwaitSpec{
+				Target:   &Target{Kind: TargetDeployment, Name: "deployment", Namespace: "default"},
+				Resource: Resource{Name: "configmaps", Version: "v1", Kind: "ConfigMap"},
+			},
+		},
+		{
+			name: "count value defaults",
+			spec: AwaitSpec{
+				Workflow: NamespacedWorkflow{Name: "workflow", Namespace: "argo"},
+				Resource: Resource{Name: "configmaps", Version: "v1", Kind: "ConfigMap"},
+				Count:    &CountCondition{Operator: CountAtLeast},
+			},
+			want: AwaitSpec{
+				Workflow: NamespacedWorkflow{Name: "workflow", Namespace: "argo"},
+				Resource: Resource{Name: "configmaps", Version: "v1", Kind: "ConfigMap"},
+				Count:    &CountCondition{Operator: CountAtLeast, Value: 1},
+			},
+		},
+		{
+			name: "http interval defaults",
+			spec: AwaitSpec{
+				Workflow: NamespacedWorkflow{Name: "workflow", Namespace: "argo"},
+				HTTP:     &HTTPEndpoint{URL: "https://example.com/ready"},
+			},
+			want: AwaitSpec{
+				Workflow: NamespacedWorkflow{Name: "workflow", Namespace: "argo"},
+				HTTP:     &HTTPEndpoint{URL: "https://example.com/ready", Interval: &metav1.Duration{Duration: DefaultHTTPInterval}},
+			},
+		},
+		{
+			name: "workflow completion defaults",
+			spec: AwaitSpec{
+				Workflow:   NamespacedWorkflow{Name: "workflow", Namespace: "argo"},
+				Completion: &WorkflowCompletion{Name: "upstream"},
+			},
+			want: AwaitSpec{
+				Workflow:   NamespacedWorkflow{Name: "workflow", Namespace: "argo"},
+				Completion: &WorkflowCompletion{Name: "upstream", Namespace: "default", Outcome: OutcomeSucceeded},
+			},
+		},
+		{
+			name: "approval defaults to a single approver",
+			spec: AwaitSpec{
+				Workflow: NamespacedWorkflow{Name: "workflow", Namespace: "argo"},
+				Approval: &ApprovalPolicy{Groups: []string{"release"}},
+			},
+			want: AwaitSpec{
+				Workflow: NamespacedWorkflow{Name: "workflow", Namespace: "argo"},
+				Approval: &ApprovalPolicy{Approvals: 1, Groups: []string{"release"}},
+			},
+		},
+		{
+			name: "unknown kind is left untouched",
+			spec: AwaitSpec{
+				Workflow: NamespacedWorkflow{Name: "workflow", Namespace: "argo"},
+				Resource: Resource{Kind: "Unknown"},
+			},
+			want: AwaitSpec{
+				Workflow: NamespacedWorkflow{Name: "workflow", Namespace: "argo"},
+				Resource: Resource{Kind: "Unknown"},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := &Await{
+				ObjectMeta: metav1.ObjectMeta{Name: "await", Namespace: "default"},
+				Spec:       tt.spec,
+			}
+			r.Default()
+
+			if !reflect.DeepEqual(r.Spec, tt.want) {
+				t.Errorf("Await.Default() = %v, want %v", r.Spec, tt.want)
+			}
+		})
+	}
+}
+
+func TestAwait_ValidateCreate(t *testing.T) {
+	restMapper = newFakeRESTMapper()
+	defer func() { restMapper = nil }()
+
+	validSpec := func() AwaitSpec {
+		return AwaitSpec{
+			Workflow: NamespacedWorkflow{Name: "workflow", Namespace: "default"},
+			Resource: Resource{Name: "configmaps", Version: "v1", Kind: "ConfigMap"},
+			Filters:  []string{"metadata.name==fake-name"},
+		}
+	}
+
+	tests := []struct {
+		name    string
+		mutate  func(spec *AwaitSpec)
+		wantErr bool
+	}{
+		{
+			name:    "valid spec",
+			mutate:  func(spec *AwaitSpec) {},
+			wantErr: false,
+		},
+		{
+			name:    "missing workflow name",
+			mutate:  func(spec *AwaitSpec) { spec.Workflow.Name = "" },
+			wantErr: true,
+		},
+		{
+			name:    "missing workflow namespace",
+			mutate:  func(spec *AwaitSpec) { spec.Workflow.Namespace = "" },
+			wantErr: true,
+		},
+		{
+			name: "valid target",
+			mutate: func(spec *AwaitSpec) {
+				spec.Workflow = NamespacedWorkflow{}
+				spec.Target = &Target{Kind: TargetJob, Name: "job", Namespace: "default"}
+			},
+			wantErr: false,
+		},
+		{
+			name: "unsupported target kind",
+			mutate: func(spec *AwaitSpec) {
+				spec.Target = &Target{Kind: "Pod", Name: "pod", Namespace: "default"}
+			},
+			wantErr: true,
+		},
+		{
+			name: "missing target name",
+			mutate: func(spec *AwaitSpec) {
+				spec.Target = &Target{Kind: TargetJob, Namespace: "default"}
+			},
+			wantErr: true,
+		},
+		{
+			name: "callback without workflow",
+			mutate: func(spec *AwaitSpec) {
+				spec.Workflow = NamespacedWorkflow{}
+				spec.Callback = &HTTPCallback{URL: "https://example.com/hook"}
+			},
+			wantErr: false,
+		},
+		{
+			name: "relative callback url",
+			mutate: func(spec *AwaitSpec) {
+				spec.Callback = &HTTPCallback{URL: "/hook"}
+			},
+			wantErr: true,
+		},
+		{
+			name: "callback secret without key",
+			mutate: func(spec *AwaitSpec) {
+				spec.Callback = &HTTPCallback{
+					URL:       "https://example.com/hook",
+					SecretRef: &corev1.SecretKeySelector{LocalObjectReference: corev1.LocalObjectReference{Name: "hmac"}},
+				}
+			},
+			wantErr: true,
+		},
+		{
+			name: "http endpoint",
+			mutate: func(spec *AwaitSpec) {
+				spec.Resource = Resource{}
+				spec.HTTP = &HTTPEndpoint{URL: "https://example.com/ready", StatusCode: 200}
+			},
+			wantErr: false,
+		},
+		{
+			name: "http endpoint together with resource",
+			mutate: func(spec *AwaitSpec) {
+				spec.HTTP = &HTTPEndpoint{URL: "https://example.com/ready"}
+			},
+			wantErr: true,
+		},
+		{
+			name: "http endpoint with invalid status code",
+			mutate: func(spec *AwaitSpec) {
+				spec.Resource = Resource{}
+				spec.HTTP = &HTTPEndpoint{URL: "https://example.com/ready", StatusCode: 42}
+			},
+			wantErr: true,
+		},
+		{
+			name: "cron schedule",
+			mutate: func(spec *AwaitSpec) {
+				spec.Resource = Resource{}
+				spec.Filters = nil
+				spec.Schedule = &Schedule{Cron: "0 9 * * 1-5", TimeZone: "Europe/Prague"}
+			},
+			wantErr: false,
+		},
+		{
+			name: "absolute schedule",
+			mutate: func(spec *AwaitSpec) {
+				spec.Resource = Resource{}
+				spec.Filters = nil
+				spec.Schedule = &Schedule{At: &metav1.Time{Time: time.Now()}}
+			},
+			wantErr: false,
+		},
+		{
+			name: "invalid cron schedule",
+			mutate: func(spec *AwaitSpec) {
+				spec.Resource = Resource{}
+				spec.Filters = nil
+				spec.Schedule = &Schedule{Cron: "0 25 * * *"}
+			},
+			wantErr: true,
+		},
+		{
+			name: "never due cron schedule",
+			mutate: func(spec *AwaitSpec) {
+				spec.Resource = Resource{}
+				spec.Filters = nil
+				spec.Schedule = &Schedule{Cron: "0 0 30 2 *"}
+			},
+			wantErr: true,
+		},
+		{
+			name: "empty schedule",
+			mutate: func(spec *AwaitSpec) {
+				spec.Resource = Resource{}
+				spec.Filters = nil
+				spec.Schedule = &Schedule{}
+			},
+			wantErr: true,
+		},
+		{
+			name: "schedule with filters",
+			mutate: func(spec *AwaitSpec) {
+				spec.Resource = Resource{}
+				spec.Schedule = &Schedule{Cron: "0 9 * * 1-5"}
+			},
+			wantErr: true,
+		},
+		{
+			name: "workflow completion",
+			mutate: func(spec *AwaitSpec) {
+				spec.Resource = Resource{}
+				spec.Completion = &WorkflowCompletion{
+					Selector: &metav1.LabelSelector{MatchLabels: map[string]string{"app": "upstream"}},
+					Outcome:  OutcomeAny,
+				}
+			},
+			wantErr: false,
+		},
+		{
+			name: "workflow completion without name or selector",
+			mutate: func(spec *AwaitSpec) {
+				spec.Resource = Resource{}
+				spec.Completion = &WorkflowCompletion{Outcome: OutcomeFailed}
+			},
+			wantErr: true,
+		},
+		{
+			name: "workflow completion with unknown outcome",
+			mutate: func(spec *AwaitSpec) {
+				spec.Resource = Resource{}
+				spec.Completion = &WorkflowCompletion{Name: "upstream", Outcome: "Skipped"}
+			},
+			wantErr: true,
+		},
+		{
+			name: "pod exit code",
+			mutate: func(spec *AwaitSpec) {
+				exitCode := int32(0)
+				spec.Resource = Resource{}
+				spec.Pod = &PodMatch{Name: "etl", Container: "main", ExitCode: &exitCode}
+			},
+			wantErr: false,
+		},
+		{
+			name: "pod log pattern",
+			mutate: func(spec *AwaitSpec) {
+				spec.Resource = Resource{}
+				spec.Pod = &PodMatch{
+					Selector:   &metav1.LabelSelector{MatchLabels: map[string]string{"app": "etl"}},
+					LogPattern: `^loaded \d+ rows$`,
+				}
+			},
+			wantErr: false,
+		},
+		{
+			name: "pod invalid log pattern",
+			mutate: func(spec *AwaitSpec) {
+				spec.Resource = Resource{}
+				spec.Pod = &PodMatch{Name: "etl", LogPattern: "loaded ("}
+			},
+			wantErr: true,
+		},
+		{
+			name: "pod without exit code or log pattern",
+			mutate: func(spec *AwaitSpec) {
+				spec.Resource = Resource{}
+				spec.Pod = &PodMatch{Name: "etl"}
+			},
+			wantErr: true,
+		},
+		{
+			name: "cloud event",
+			mutate: func(spec *AwaitSpec) {
+				spec.Resource = Resource{}
+				spec.Event = &CloudEventMatch{Type: "com.example.build"}
+			},
+			wantErr: false,
+		},
+		{
+			name: "cloud event together with pod",
+			mutate: func(spec *AwaitSpec) {
+				exitCode := int32(0)
+				spec.Resource = Resource{}
+				spec.Event = &CloudEventMatch{Type: "com.example.build"}
+				spec.Pod = &PodMatch{Name: "etl", ExitCode: &exitCode}
+			},
+			wantErr: true,
+		},
+		{
+			name: "approval",
+			mutate: func(spec *AwaitSpec) {
+				spec.Resource = Resource{}
+				spec.Filters = nil
+				spec.Approval = &ApprovalPolicy{Approvals: 2}
+			},
+			wantErr: false,
+		},
+		{
+			name: "approval with filters",
+			mutate: func(spec *AwaitSpec) {
+				spec.Resource = Resource{}
+				spec.Approval = &ApprovalPolicy{Approvals: 1}
+			},
+			wantErr: true,
+		},
+		{
+			name: "custom observer",
+			mutate: func(spec *AwaitSpec) {
+				spec.Resource = Resource{}
+				spec.Observer = &ObserverSpec{Type: "grpc", Parameters: map[string]string{"plugin": "jira"}}
+			},
+			wantErr: false,
+		},
+		{
+			name: "custom observer together with resource",
+			mutate: func(spec *AwaitSpec) {
+				spec.Observer = &ObserverSpec{Type: "grpc"}
+			},
+			wantErr: true,
+		},
+		{
+			name: "built-in observer type",
+			mutate: func(spec *AwaitSpec) {
+				spec.Observer = &ObserverSpec{Type: ObserverTypeResource}
+			},
+			wantErr: false,
+		},
+		{
+			name: "mismatched built-in observer type",
+			mutate: func(spec *AwaitSpec) {
+				spec.Observer = &ObserverSpec{Type: ObserverTypeHTTP}
+			},
+			wantErr: true,
+		},
+		{
+			name: "missing observer type",
+			mutate: func(spec *AwaitSpec) {
+				spec.Observer = &ObserverSpec{}
+			},
+			wantErr: true,
+		},
+		{
+			name: "changed filter mode",
+			mutate: func(spec *AwaitSpec) {
+				spec.FilterMode = FilterModeChanged
+			},
+			wantErr: false,
+		},
+		{
+			name: "changed filter mode of cloud event",
+			mutate: func(spec *AwaitSpec) {
+				spec.Resource = Resource{}
+				spec.Event = &CloudEventMatch{Type: "com.example.build"}
+				spec.FilterMode = FilterModeChanged
+			},
+			wantErr: true,
+		},
+		{
+			name: "stable window",
+			mutate: func(spec *AwaitSpec) {
+				spec.StableFor = &metav1.Duration{Duration: time.Minute}
+			},
+			wantErr: false,
+		},
+		{
+			name: "negative stable window",
+			mutate: func(spec *AwaitSpec) {
+				spec.StableFor = &metav1.Duration{Duration: -time.Minute}
+			},
+			wantErr: true,
+		},
+		{
+			name: "stable window with changed filter mode",
+			mutate: func(spec *AwaitSpec) {
+				spec.FilterMode = FilterModeChanged
+				spec.StableFor = &metav1.Duration{Duration: time.Minute}
+			},
+			wantErr: true,
+		},
+		{
+			name: "count of selected resources",
+			mutate: func(spec *AwaitSpec) {
+				spec.Count = &CountCondition{
+					Operator: CountAll,
+					Selector: &metav1.LabelSelector{MatchLabels: map[string]string{"app": "etl"}},
+				}
+			},
+			wantErr: false,
+		},
+		{
+			name: "count with negative value",
+			mutate: func(spec *AwaitSpec) {
+				spec.Count = &CountCondition{Operator: CountAtLeast, Value: -1}
+			},
+			wantErr: true,
+		},
+		{
+			name: "count with value of none",
+			mutate: func(spec *AwaitSpec) {
+				spec.Count = &CountCondition{Operator: CountNone, Value: 2}
+			},
+			wantErr: true,
+		},
+		{
+			name: "count with unknown operator",
+			mutate: func(spec *AwaitSpec) {
+				spec.Count = &CountCondition{Operator: "AtMost"}
+			},
+			wantErr: true,
+		},
+		{
+			name: "count with changed filter mode",
+			mutate: func(spec *AwaitSpec) {
+				spec.FilterMode = FilterModeChanged
+				spec.Count = &CountCondition{Operator: CountAtLeast, Value: 3}
+			},
+			wantErr: true,
+		},
+		{
+			name: "count of http endpoint",
+			mutate: func(spec *AwaitSpec) {
+				spec.Resource = Resource{}
+				spec.HTTP = &HTTPEndpoint{URL: "https://example.com/ready"}
+				spec.Count = &CountCondition{Operator: CountAtLeast, Value: 3}
+			},
+			wantErr: true,
+		},
+		{
+			name: "stable window of schedule",
+			mutate: func(spec *AwaitSpec) {
+				spec.Resource = Resource{}
+				spec.Filters = nil
+				spec.Schedule = &Schedule{Cron: "0 * * * *"}
+				spec.StableFor = &metav1.Duration{Duration: time.Minute}
+			},
+			wantErr: true,
+		},
+		{
+			name:    "unknown resource kind",
+			mutate:  func(spec *AwaitSpec) { spec.Resource.Kind = "Unknown" },
+			wantErr: true,
+		},
+		{
+			name:    "resource name is not the plural of kind",
+			mutate:  func(spec *AwaitSpec) { spec.Resource.Name = "configmap" },
+			wantErr: true,
+		},
+		{
+			name:    "unparsable filter",
+			mutate:  func(spec *AwaitSpec) { spec.Filters = append(spec.Filters, "metadata.#(name") },
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := &Await{Spec: validSpec()}
+			tt.mutate(&r.Spec)
+
+			if err := r.ValidateCreate(); (err != nil) != tt.wantErr {
+				t.Errorf("Await.ValidateCreate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
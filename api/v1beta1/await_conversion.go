@@ -0,0 +1,291 @@
+/*
+Copyright 2019 Marek Cermak <macermak@redhat.com>.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1beta1
+
+import (
+	"github.com/cermakm/argo-await-operator/api/v1alpha1"
+
+	"sigs.k8s.io/controller-runtime/pkg/conversion"
+)
+
+// ConvertTo converts this Await to the Hub version (v1alpha1).
+func (src *Await) ConvertTo(dstRaw conversion.Hub) error {
+	dst := dstRaw.(*v1alpha1.Await)
+
+	dst.ObjectMeta = src.ObjectMeta
+
+	dst.Spec.Workflow = v1alpha1.NamespacedWorkflow{
+		Name:      src.Spec.WorkflowRef.Name,
+		Namespace: src.Spec.WorkflowRef.Namespace,
+	}
+	dst.Spec.Target = nil
+	if src.Spec.TargetRef != nil {
+		dst.Spec.Target = &v1alpha1.Target{
+			Kind:      src.Spec.TargetRef.Kind,
+			Name:      src.Spec.TargetRef.Name,
+			Namespace: src.Spec.TargetRef.Namespace,
+		}
+	}
+	dst.Spec.Callback = nil
+	if src.Spec.Callback != nil {
+		dst.Spec.Callback = &v1alpha1.HTTPCallback{
+			URL:       src.Spec.Callback.URL,
+			SecretRef: src.Spec.Callback.SecretRef,
+		}
+	}
+	dst.Spec.HTTP = nil
+	if src.Spec.HTTP != nil {
+		dst.Spec.HTTP = &v1alpha1.HTTPEndpoint{
+			URL:        src.Spec.HTTP.URL,
+			Interval:   src.Spec.HTTP.Interval,
+			StatusCode: src.Spec.HTTP.StatusCode,
+		}
+	}
+	dst.Spec.Schedule = nil
+	if src.Spec.Schedule != nil {
+		dst.Spec.Schedule = &v1alpha1.Schedule{
+			At:       src.Spec.Schedule.At,
+			Cron:     src.Spec.Schedule.Cron,
+			TimeZone: src.Spec.Schedule.TimeZone,
+		}
+	}
+	dst.Spec.Completion = nil
+	if src.Spec.Completion != nil {
+		dst.Spec.Completion = &v1alpha1.WorkflowCompletion{
+			Name:      src.Spec.Completion.Name,
+			Namespace: src.Spec.Completion.Namespace,
+			Selector:  src.Spec.Completion.Selector,
+			Node:      src.Spec.Completion.Node,
+			Outcome:   v1alpha1.WorkflowOutcome(src.Spec.Completion.Outcome),
+		}
+	}
+	dst.Spec.Pod = nil
+	if src.Spec.Pod != nil {
+		dst.Spec.Pod = &v1alpha1.PodMatch{
+			Name:       src.Spec.Pod.Name,
+			Namespace:  src.Spec.Pod.Namespace,
+			Selector:   src.Spec.Pod.Selector,
+			Container:  src.Spec.Pod.Container,
+			ExitCode:   src.Spec.Pod.ExitCode,
+			LogPattern: src.Spec.Pod.LogPattern,
+		}
+	}
+	dst.Spec.Event = nil
+	if src.Spec.Event != nil {
+		dst.Spec.Event = &v1alpha1.CloudEventMatch{
+			Type:   src.Spec.Event.Type,
+			Source: src.Spec.Event.Source,
+		}
+	}
+	dst.Spec.Approval = nil
+	if src.Spec.Approval != nil {
+		dst.Spec.Approval = &v1alpha1.ApprovalPolicy{
+			Approvals: src.Spec.Approval.Approvals,
+			Groups:    src.Spec.Approval.Groups,
+		}
+	}
+	dst.Spec.Observer = nil
+	if src.Spec.Observer != nil {
+		dst.Spec.Observer = &v1alpha1.ObserverSpec{
+			Type:       src.Spec.Observer.Type,
+			Parameters: src.Spec.Observer.Parameters,
+		}
+	}
+	dst.Spec.Resource = v1alpha1.Resource{
+		Name:    src.Spec.Resource.Plural,
+		Group:   src.Spec.Resource.Group,
+		Version: src.Spec.Resource.Version,
+		Kind:    src.Spec.Resource.Kind,
+	}
+
+	dst.Spec.Filters = nil
+	if src.Spec.Filters != nil {
+		dst.Spec.Filters = make([]string, len(src.Spec.Filters))
+		for i, f := range src.Spec.Filters {
+			dst.Spec.Filters[i] = f.Expression
+		}
+	}
+	dst.Spec.FilterMode = v1alpha1.FilterMode(src.Spec.FilterMode)
+	dst.Spec.StableFor = src.Spec.StableFor
+	dst.Spec.Count = nil
+	if src.Spec.Count != nil {
+		dst.Spec.Count = &v1alpha1.CountCondition{
+			Operator: v1alpha1.CountOperator(src.Spec.Count.Operator),
+			Value:    src.Spec.Count.Value,
+			Selector: src.Spec.Count.Selector,
+		}
+	}
+
+	dst.Status = v1alpha1.AwaitStatus{
+		Phase:           v1alpha1.AwaitPhase(src.Status.Phase),
+		StartedAt:       src.Status.StartedAt,
+		FinishedAt:      src.Status.FinishedAt,
+		ObservedKind:    src.Status.ObservedKind,
+		MatchedObject:   src.Status.MatchedObject,
+		MatchedCount:    src.Status.MatchedCount,
+		SuspendedNode:   src.Status.SuspendedNode,
+		Outputs:         src.Status.Outputs,
+		Message:         src.Status.Message,
+		ResumeAttempts:  src.Status.ResumeAttempts,
+		LastResumeError: src.Status.LastResumeError,
+	}
+	for _, a := range src.Status.Approvals {
+		dst.Status.Approvals = append(dst.Status.Approvals, v1alpha1.ApprovalRecord{
+			Name:     a.Name,
+			Approver: a.Approver,
+			Decision: v1alpha1.ApprovalDecision(a.Decision),
+			Comment:  a.Comment,
+			Time:     a.Time,
+		})
+	}
+
+	return nil
+}
+
+// ConvertFrom converts from the Hub version (v1alpha1) to this version.
+func (dst *Await) ConvertFrom(srcRaw conversion.Hub) error {
+	src := srcRaw.(*v1alpha1.Await)
+
+	dst.ObjectMeta = src.ObjectMeta
+
+	dst.Spec.WorkflowRef = WorkflowReference{
+		Name:      src.Spec.Workflow.Name,
+		Namespace: src.Spec.Workflow.Namespace,
+	}
+	dst.Spec.TargetRef = nil
+	if src.Spec.Target != nil {
+		dst.Spec.TargetRef = &TargetReference{
+			Kind:      src.Spec.Target.Kind,
+			Name:      src.Spec.Target.Name,
+			Namespace: src.Spec.Target.Namespace,
+		}
+	}
+	dst.Spec.Callback = nil
+	if src.Spec.Callback != nil {
+		dst.Spec.Callback = &HTTPCallback{
+			URL:       src.Spec.Callback.URL,
+			SecretRef: src.Spec.Callback.SecretRef,
+		}
+	}
+	dst.Spec.HTTP = nil
+	if src.Spec.HTTP != nil {
+		dst.Spec.HTTP = &HTTPEndpoint{
+			URL:        src.Spec.HTTP.URL,
+			Interval:   src.Spec.HTTP.Interval,
+			StatusCode: src.Spec.HTTP.StatusCode,
+		}
+	}
+	dst.Spec.Schedule = nil
+	if src.Spec.Schedule != nil {
+		dst.Spec.Schedule = &Schedule{
+			At:       src.Spec.Schedule.At,
+			Cron:     src.Spec.Schedule.Cron,
+			TimeZone: src.Spec.Schedule.TimeZone,
+		}
+	}
+	dst.Spec.Completion = nil
+	if src.Spec.Completion != nil {
+		dst.Spec.Completion = &WorkflowCompletion{
+			Name:      src.Spec.Completion.Name,
+			Namespace: src.Spec.Completion.Namespace,
+			Selector:  src.Spec.Completion.Selector,
+			Node:      src.Spec.Completion.Node,
+			Outcome:   WorkflowOutcome(src.Spec.Completion.Outcome),
+		}
+	}
+	dst.Spec.Pod = nil
+	if src.Spec.Pod != nil {
+		dst.Spec.Pod = &PodMatch{
+			Name:       src.Spec.Pod.Name,
+			Namespace:  src.Spec.Pod.Namespace,
+			Selector:   src.Spec.Pod.Selector,
+			Container:  src.Spec.Pod.Container,
+			ExitCode:   src.Spec.Pod.ExitCode,
+			LogPattern: src.Spec.Pod.LogPattern,
+		}
+	}
+	dst.Spec.Event = nil
+	if src.Spec.Event != nil {
+		dst.Spec.Event = &CloudEventMatch{
+			Type:   src.Spec.Event.Type,
+			Source: src.Spec.Event.Source,
+		}
+	}
+	dst.Spec.Approval = nil
+	if src.Spec.Approval != nil {
+		dst.Spec.Approval = &ApprovalPolicy{
+			Approvals: src.Spec.Approval.Approvals,
+			Groups:    src.Spec.Approval.Groups,
+		}
+	}
+	dst.Spec.Observer = nil
+	if src.Spec.Observer != nil {
+		dst.Spec.Observer = &ObserverSpec{
+			Type:       src.Spec.Observer.Type,
+			Parameters: src.Spec.Observer.Parameters,
+		}
+	}
+	dst.Spec.Resource = ResourceReference{
+		Group:   src.Spec.Resource.Group,
+		Version: src.Spec.Resource.Version,
+		Kind:    src.Spec.Resource.Kind,
+		Plural:  src.Spec.Resource.Name,
+	}
+
+	dst.Spec.Filters = nil
+	if src.Spec.Filters != nil {
+		dst.Spec.Filters = make([]Filter, len(src.Spec.Filters))
+		for i, f := range src.Spec.Filters {
+			dst.Spec.Filters[i] = Filter{Expression: f}
+		}
+	}
+	dst.Spec.FilterMode = FilterMode(src.Spec.FilterMode)
+	dst.Spec.StableFor = src.Spec.StableFor
+	dst.Spec.Count = nil
+	if src.Spec.Count != nil {
+		dst.Spec.Count = &CountCondition{
+			Operator: CountOperator(src.Spec.Count.Operator),
+			Value:    src.Spec.Count.Value,
+			Selector: src.Spec.Count.Selector,
+		}
+	}
+
+	dst.Status = AwaitStatus{
+		Phase:           AwaitPhase(src.Status.Phase),
+		StartedAt:       src.Status.StartedAt,
+		FinishedAt:      src.Status.FinishedAt,
+		ObservedKind:    src.Status.ObservedKind,
+		MatchedObject:   src.Status.MatchedObject,
+		MatchedCount:    src.Status.MatchedCount,
+		SuspendedNode:   src.Status.SuspendedNode,
+		Outputs:         src.Status.Outputs,
+		Message:         src.Status.Message,
+		ResumeAttempts:  src.Status.ResumeAttempts,
+		LastResumeError: src.Status.LastResumeError,
+	}
+	for _, a := range src.Status.Approvals {
+		dst.Status.Approvals = append(dst.Status.Approvals, ApprovalRecord{
+			Name:     a.Name,
+			Approver: a.Approver,
+			Decision: ApprovalDecision(a.Decision),
+			Comment:  a.Comment,
+			Time:     a.Time,
+		})
+	}
+
+	return nil
+}
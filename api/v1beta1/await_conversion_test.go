@@ -0,0 +1,120 @@
+/*
+Copyright 2019 Marek Cermak <macermak@redhat.com>.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1beta1
+
+import (
+	"testing"
+
+	"github.com/cermakm/argo-await-operator/api/v1alpha1"
+	fuzz "github.com/google/gofuzz"
+
+	apiequality "k8s.io/apimachinery/pkg/api/equality"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/diff"
+)
+
+const fuzzIters = 1000
+
+func newFuzzer() *fuzz.Fuzzer {
+	return fuzz.New().NilChance(.5).NumElements(0, 3).Funcs(
+		// TypeMeta is set by the conversion webhook, not by the conversion itself
+		func(t *metav1.TypeMeta, c fuzz.Continue) {},
+	)
+}
+
+func TestAwait_RoundTripFromHub(t *testing.T) {
+	f := newFuzzer()
+
+	for i := 0; i < fuzzIters; i++ {
+		hub := &v1alpha1.Await{}
+		f.Fuzz(hub)
+
+		spoke := &Await{}
+		if err := spoke.ConvertFrom(hub); err != nil {
+			t.Fatalf("ConvertFrom() error = %v", err)
+		}
+
+		got := &v1alpha1.Await{}
+		if err := spoke.ConvertTo(got); err != nil {
+			t.Fatalf("ConvertTo() error = %v", err)
+		}
+
+		if !apiequality.Semantic.DeepEqual(hub, got) {
+			t.Fatalf("round trip from v1alpha1 failed:\n%s", diff.ObjectReflectDiff(hub, got))
+		}
+	}
+}
+
+func TestAwait_RoundTripToHub(t *testing.T) {
+	f := newFuzzer()
+
+	for i := 0; i < fuzzIters; i++ {
+		spoke := &Await{}
+		f.Fuzz(spoke)
+
+		hub := &v1alpha1.Await{}
+		if err := spoke.ConvertTo(hub); err != nil {
+			t.Fatalf("ConvertTo() error = %v", err)
+		}
+
+		got := &Await{}
+		if err := got.ConvertFrom(hub); err != nil {
+			t.Fatalf("ConvertFrom() error = %v", err)
+		}
+
+		if !apiequality.Semantic.DeepEqual(spoke, got) {
+			t.Fatalf("round trip from v1beta1 failed:\n%s", diff.ObjectReflectDiff(spoke, got))
+		}
+	}
+}
+
+func TestAwait_ConvertIntoExisting(t *testing.T) {
+	f := newFuzzer()
+
+	for i := 0; i < fuzzIters; i++ {
+		spoke := &Await{}
+		f.Fuzz(spoke)
+
+		// The fields missing in the source are not left over from the destination
+		want, got := &v1alpha1.Await{}, &v1alpha1.Await{}
+		f.Fuzz(got)
+		if err := spoke.ConvertTo(want); err != nil {
+			t.Fatalf("ConvertTo() error = %v", err)
+		}
+		if err := spoke.ConvertTo(got); err != nil {
+			t.Fatalf("ConvertTo() error = %v", err)
+		}
+		if !apiequality.Semantic.DeepEqual(want, got) {
+			t.Fatalf("conversion to an existing v1alpha1 failed:\n%s", diff.ObjectReflectDiff(want, got))
+		}
+
+		hub := &v1alpha1.Await{}
+		f.Fuzz(hub)
+
+		wantSpoke, gotSpoke := &Await{}, &Await{}
+		f.Fuzz(gotSpoke)
+		if err := wantSpoke.ConvertFrom(hub); err != nil {
+			t.Fatalf("ConvertFrom() error = %v", err)
+		}
+		if err := gotSpoke.ConvertFrom(hub); err != nil {
+			t.Fatalf("ConvertFrom() error = %v", err)
+		}
+		if !apiequality.Semantic.DeepEqual(wantSpoke, gotSpoke) {
+			t.Fatalf("conversion to an existing v1beta1 failed:\n%s", diff.ObjectReflectDiff(wantSpoke, gotSpoke))
+		}
+	}
+}
@@ -0,0 +1,445 @@
+/*
+Copyright 2019 Marek Cermak <macermak@redhat.com>.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1beta1
+
+import (
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// +kubebuilder:object:root=true
+// +kubebuilder:resource:shortName=aw,categories=argo
+// +kubebuilder:subresource:status
+// +kubebuilder:printcolumn:name="Workflow",type="string",JSONPath=".spec.workflowRef.name"
+// +kubebuilder:printcolumn:name="Kind",type="string",JSONPath=".status.observedKind"
+// +kubebuilder:printcolumn:name="Phase",type="string",JSONPath=".status.phase"
+// +kubebuilder:printcolumn:name="Waiting",type="date",JSONPath=".status.startedAt"
+// +kubebuilder:printcolumn:name="Matched",type="string",JSONPath=".status.matchedObject.name"
+// +kubebuilder:printcolumn:name="Age",type="date",JSONPath=".metadata.creationTimestamp"
+
+// Await is the Schema for the awaits API
+type Await struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   AwaitSpec   `json:"spec,omitempty"`
+	Status AwaitStatus `json:"status,omitempty"`
+}
+
+// AwaitSpec defines the desired state of Await
+// +k8s:openapi-gen=true
+type AwaitSpec struct {
+	// WorkflowRef references the suspended Workflow to be resumed, ignored if the TargetRef is set
+	// +optional
+	WorkflowRef WorkflowReference `json:"workflowRef,omitempty"`
+
+	// TargetRef references the object to be resumed, for the kinds other than Workflow
+	// +optional
+	TargetRef *TargetReference `json:"targetRef,omitempty"`
+
+	// Callback is the HTTP request sent once the Resource has been awaited,
+	// the WorkflowRef or TargetRef are optional if it is set
+	// +optional
+	Callback *HTTPCallback `json:"callback,omitempty"`
+
+	// Resource references the kind of the resource to be awaited
+	// +optional
+	Resource ResourceReference `json:"resource,omitempty"`
+
+	// HTTP is the external endpoint to be awaited instead of the Resource
+	// +optional
+	HTTP *HTTPEndpoint `json:"http,omitempty"`
+
+	// Schedule is the time to be awaited instead of the Resource
+	// +optional
+	Schedule *Schedule `json:"schedule,omitempty"`
+
+	// Completion is the completion of other Argo Workflows to be awaited instead of the Resource
+	// +optional
+	Completion *WorkflowCompletion `json:"completion,omitempty"`
+
+	// Pod is the exit code or log line of a Pod container to be awaited instead of the Resource
+	// +optional
+	Pod *PodMatch `json:"pod,omitempty"`
+
+	// Event is the CloudEvent to be awaited instead of the Resource
+	// +optional
+	Event *CloudEventMatch `json:"event,omitempty"`
+
+	// Approval is the manual approval to be awaited instead of the Resource
+	// +optional
+	Approval *ApprovalPolicy `json:"approval,omitempty"`
+
+	// Observer selects the type of the observer of the Await, the type
+	// is given by the awaited Resource, HTTP, Completion, Pod or Event if not set
+	// +optional
+	Observer *ObserverSpec `json:"observer,omitempty"`
+
+	// Filters have to be all passed by the awaited resource
+	// +optional
+	Filters []Filter `json:"filters,omitempty"`
+
+	// FilterMode is either Level, the filters are passed whenever they match,
+	// or Changed, the filters are passed only when they start to match. The filters
+	// may reference the previous state of the object as _old, e.g. _old.status.phase.
+	// Level if not set.
+	// +kubebuilder:validation:Enum=Level;Changed
+	// +optional
+	FilterMode FilterMode `json:"filterMode,omitempty"`
+
+	// StableFor is the duration the filters have to be passed continuously for
+	// before the Await is fulfilled, the window is reset whenever they are not passed.
+	// The Await is fulfilled right away if not set.
+	// +optional
+	StableFor *metav1.Duration `json:"stableFor,omitempty"`
+
+	// Count is the aggregate condition over the awaited resources, the Await
+	// is fulfilled by the first resource passing the filters if not set
+	// +optional
+	Count *CountCondition `json:"count,omitempty"`
+}
+
+// ResourceReference defines the Resource to be awaited
+// +k8s:openapi-gen=true
+type ResourceReference struct {
+	// Group is the API group of the resource, empty for the core group
+	// +optional
+	Group string `json:"group,omitempty"`
+
+	// Version is the API version of the resource
+	// +optional
+	Version string `json:"version,omitempty"`
+
+	// Kind is the kind of the resource
+	Kind string `json:"kind"`
+
+	// Plural is the plural name of the resource
+	Plural string `json:"plural"`
+}
+
+// WorkflowReference defines the Workflow to be resumed
+// +k8s:openapi-gen=true
+type WorkflowReference struct {
+	Name      string `json:"name"`
+	Namespace string `json:"namespace"`
+}
+
+// TargetReference defines the object to be resumed
+// +k8s:openapi-gen=true
+type TargetReference struct {
+	// Kind is the kind of the object
+	// +kubebuilder:validation:Enum=Workflow;CronWorkflow;PipelineRun;Deployment;Job
+	Kind      string `json:"kind"`
+	Name      string `json:"name"`
+	Namespace string `json:"namespace"`
+}
+
+// HTTPEndpoint defines the external HTTP endpoint to be awaited. The endpoint is polled
+// until it responds with the status code and a JSON body passing the filters.
+// +k8s:openapi-gen=true
+type HTTPEndpoint struct {
+	// URL is the http or https URL to be polled
+	URL string `json:"url"`
+
+	// Interval is the interval between the requests, defaults to 10s
+	// +optional
+	Interval *metav1.Duration `json:"interval,omitempty"`
+
+	// StatusCode is the expected status code of the response, any 2xx code if not set
+	// +optional
+	StatusCode int32 `json:"statusCode,omitempty"`
+}
+
+// Schedule defines the time the Await is fulfilled at, either At or Cron has to be set
+// +k8s:openapi-gen=true
+type Schedule struct {
+	// At is the absolute time the Await is fulfilled at
+	// +optional
+	At *metav1.Time `json:"at,omitempty"`
+
+	// Cron is the standard cron expression, the Await is fulfilled at the next time
+	// matching it after the Await has started waiting
+	// +optional
+	Cron string `json:"cron,omitempty"`
+
+	// TimeZone is the IANA time zone of the Cron, UTC if not set
+	// +optional
+	TimeZone string `json:"timeZone,omitempty"`
+}
+
+// CountOperator is the aggregate condition over the awaited resources
+type CountOperator string
+
+const (
+	// CountAtLeast is fulfilled once at least Value resources pass the filters
+	CountAtLeast CountOperator = "AtLeast"
+	// CountAll is fulfilled once all the selected resources pass the filters, there has to be at least one
+	CountAll CountOperator = "All"
+	// CountNone is fulfilled once none of the selected resources pass the filters,
+	// i.e. once no resource remains if there are no filters
+	CountNone CountOperator = "None"
+)
+
+// CountCondition defines the aggregate condition over the awaited resources
+// +k8s:openapi-gen=true
+type CountCondition struct {
+	// Operator is either AtLeast, All or None
+	// +kubebuilder:validation:Enum=AtLeast;All;None
+	Operator CountOperator `json:"operator"`
+
+	// Value is the minimum number of the resources passing the filters for AtLeast, 1 if not set
+	// +optional
+	Value int32 `json:"value,omitempty"`
+
+	// Selector selects the resources the condition is evaluated over,
+	// all the resources of the kind in the namespace if not set
+	// +optional
+	Selector *metav1.LabelSelector `json:"selector,omitempty"`
+}
+
+// WorkflowOutcome is the awaited outcome of a Workflow
+type WorkflowOutcome string
+
+const (
+	// OutcomeSucceeded is awaited by default, the Workflow has to succeed
+	OutcomeSucceeded WorkflowOutcome = "Succeeded"
+	// OutcomeFailed means the Workflow has to fail or error
+	OutcomeFailed WorkflowOutcome = "Failed"
+	// OutcomeAny means the Workflow has to complete with any outcome
+	OutcomeAny WorkflowOutcome = "Any"
+)
+
+// WorkflowCompletion defines the Argo Workflows whose completion is awaited,
+// either Name or Selector has to be set
+// +k8s:openapi-gen=true
+type WorkflowCompletion struct {
+	// Name is the name of the awaited Workflow
+	// +optional
+	Name string `json:"name,omitempty"`
+
+	// Namespace is the namespace of the awaited Workflows, the Await namespace if not set
+	// +optional
+	Namespace string `json:"namespace,omitempty"`
+
+	// Selector selects the awaited Workflows by their labels,
+	// the completion of any of them fulfills the Await
+	// +optional
+	Selector *metav1.LabelSelector `json:"selector,omitempty"`
+
+	// Node is the name of the node whose outcome is awaited instead of the whole Workflow
+	// +optional
+	Node string `json:"node,omitempty"`
+
+	// Outcome is the awaited outcome, Succeeded if not set
+	// +kubebuilder:validation:Enum=Succeeded;Failed;Any
+	// +optional
+	Outcome WorkflowOutcome `json:"outcome,omitempty"`
+}
+
+// PodMatch defines the Pods whose container exit code or log line is awaited,
+// either Name or Selector and either ExitCode or LogPattern have to be set
+// +k8s:openapi-gen=true
+type PodMatch struct {
+	// Name is the name of the awaited Pod
+	// +optional
+	Name string `json:"name,omitempty"`
+
+	// Namespace is the namespace of the awaited Pods, the Await namespace if not set
+	// +optional
+	Namespace string `json:"namespace,omitempty"`
+
+	// Selector selects the awaited Pods by their labels
+	// +optional
+	Selector *metav1.LabelSelector `json:"selector,omitempty"`
+
+	// Container is the name of the awaited container, any container is matched
+	// by the ExitCode and the first one is used for the LogPattern if not set
+	// +optional
+	Container string `json:"container,omitempty"`
+
+	// ExitCode is the awaited exit code of the terminated container
+	// +optional
+	ExitCode *int32 `json:"exitCode,omitempty"`
+
+	// LogPattern is the regular expression matched against the log lines of the container
+	// +optional
+	LogPattern string `json:"logPattern,omitempty"`
+}
+
+// CloudEventMatch defines the CloudEvent to be awaited, the event is received
+// by the operator for the namespace of the Await and has to pass the filters as well
+// +k8s:openapi-gen=true
+type CloudEventMatch struct {
+	// Type is the awaited type of the event, any type if not set
+	// +optional
+	Type string `json:"type,omitempty"`
+
+	// Source is the awaited source of the event, any source if not set
+	// +optional
+	Source string `json:"source,omitempty"`
+}
+
+// FilterMode is the mode the filters are evaluated in
+type FilterMode string
+
+const (
+	// FilterModeLevel passes the filters whenever they match
+	FilterModeLevel FilterMode = "Level"
+	// FilterModeChanged passes the filters only when they start to match
+	FilterModeChanged FilterMode = "Changed"
+)
+
+// ObserverSpec selects the observer of the Await
+// +k8s:openapi-gen=true
+type ObserverSpec struct {
+	// Type is the type of the observer the observers are registered with
+	Type string `json:"type"`
+
+	// Parameters configure the observers of the types other than the built-in ones
+	// +optional
+	Parameters map[string]string `json:"parameters,omitempty"`
+}
+
+// ApprovalPolicy defines the manual approval to be awaited, the Await is approved
+// by creating the Approvals referencing it
+// +k8s:openapi-gen=true
+type ApprovalPolicy struct {
+	// Approvals is the number of the distinct approvers required, 1 if not set
+	// +kubebuilder:validation:Minimum=1
+	// +optional
+	Approvals int32 `json:"approvals,omitempty"`
+
+	// Groups restricts the approvers to the members of any of the groups
+	// +optional
+	Groups []string `json:"groups,omitempty"`
+}
+
+// ApprovalDecision is the decision of the approver, either Approve or Reject
+type ApprovalDecision string
+
+// ApprovalRecord is the audit record of an Approval counted by the controller
+// +k8s:openapi-gen=true
+type ApprovalRecord struct {
+	// Name is the name of the Approval
+	Name     string           `json:"name"`
+	Approver string           `json:"approver"`
+	Decision ApprovalDecision `json:"decision"`
+	Comment  string           `json:"comment,omitempty"`
+	Time     metav1.Time      `json:"time"`
+}
+
+// HTTPCallback defines the HTTP request sent once the Resource has been awaited.
+// The Await metadata and the matched object are POSTed as JSON to the URL.
+// +k8s:openapi-gen=true
+type HTTPCallback struct {
+	// URL is the http or https URL the payload is sent to
+	URL string `json:"url"`
+
+	// SecretRef selects the key of a Secret in the Await namespace, the payload
+	// is signed with HMAC-SHA256 using its value if set
+	// +optional
+	SecretRef *corev1.SecretKeySelector `json:"secretRef,omitempty"`
+}
+
+// Filter defines a condition on the awaited resource
+// +k8s:openapi-gen=true
+type Filter struct {
+	// Expression is a GJSON query which the resource has to match
+	Expression string `json:"expression"`
+}
+
+// AwaitPhase is the current phase of the Await
+type AwaitPhase string
+
+const (
+	// AwaitPending means that the Workflow has not been suspended yet
+	AwaitPending AwaitPhase = "Pending"
+	// AwaitWaiting means that the Resource is being awaited
+	AwaitWaiting AwaitPhase = "Waiting"
+	// AwaitFulfilled means that the Resource has been awaited, but the Workflow not resumed yet
+	AwaitFulfilled AwaitPhase = "Fulfilled"
+	// AwaitResumed means that the Resource has been awaited and the Workflow resumed
+	AwaitResumed AwaitPhase = "Resumed"
+	// AwaitCompleted means that the Resource has been awaited and the Callback sent,
+	// with no Workflow or Target to be resumed
+	AwaitCompleted AwaitPhase = "Completed"
+	// AwaitSkipped means that the Resource has been awaited, but the Workflow
+	// was no longer suspended at the node the Await was created for
+	AwaitSkipped AwaitPhase = "Skipped"
+	// AwaitFailed means that the Resource could not be awaited or the Workflow resumed
+	AwaitFailed AwaitPhase = "Failed"
+)
+
+// AwaitStatus defines the observed state of Await
+// +k8s:openapi-gen=true
+type AwaitStatus struct {
+	Phase      AwaitPhase  `json:"phase,omitempty"`
+	StartedAt  metav1.Time `json:"startedAt,omitempty"`
+	FinishedAt metav1.Time `json:"finishedAt,omitempty"`
+
+	// ObservedKind is the kind of the awaited Resource, or the kind of whatever
+	// else is awaited, e.g. HTTP or Approval, set once the Await starts waiting
+	// +optional
+	ObservedKind string `json:"observedKind,omitempty"`
+
+	// MatchedObject references the resource which fulfilled the Await
+	MatchedObject *corev1.ObjectReference `json:"matchedObject,omitempty"`
+
+	// MatchedCount is the number of the resources passing the filters
+	// when the Count condition was fulfilled
+	// +optional
+	MatchedCount int32 `json:"matchedCount,omitempty"`
+
+	// SuspendedNode is the ID of the Workflow suspend node the Await was created for,
+	// empty if the whole Workflow has been suspended
+	// +optional
+	SuspendedNode string `json:"suspendedNode,omitempty"`
+
+	// Outputs are the output parameters of the awaited Workflow, they are set
+	// as the outputs of the suspend node when the Workflow is resumed
+	// +optional
+	Outputs map[string]string `json:"outputs,omitempty"`
+
+	// Approvals are the decisions of the approvers of the Await
+	// +optional
+	Approvals []ApprovalRecord `json:"approvals,omitempty"`
+
+	// Message is a human readable description of the current phase
+	// +optional
+	Message string `json:"message,omitempty"`
+
+	// ResumeAttempts is the number of failed attempts to resume the Workflow
+	// +optional
+	ResumeAttempts int32 `json:"resumeAttempts,omitempty"`
+
+	// LastResumeError is the error of the last failed attempt to resume the Workflow
+	// +optional
+	LastResumeError string `json:"lastResumeError,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+
+// AwaitList contains a list of Await
+type AwaitList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []Await `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&Await{}, &AwaitList{})
+}
@@ -0,0 +1,87 @@
+/*
+Copyright 2019 Marek Cermak <macermak@redhat.com>.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1beta1
+
+import (
+	"github.com/cermakm/argo-await-operator/api/v1alpha1"
+
+	"k8s.io/apimachinery/pkg/runtime"
+
+	ctrl "sigs.k8s.io/controller-runtime"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/webhook"
+)
+
+var awaitlog = logf.Log.WithName("await-resource")
+
+// SetupWebhookWithManager registers the Await webhooks with the manager
+func (r *Await) SetupWebhookWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewWebhookManagedBy(mgr).
+		For(r).
+		Complete()
+}
+
+// +kubebuilder:webhook:path=/mutate-await-argoproj-io-v1beta1-await,mutating=true,failurePolicy=fail,groups=await.argoproj.io,resources=awaits,verbs=create;update,versions=v1beta1,name=mawait.v1beta1.kb.io
+
+var _ webhook.Defaulter = &Await{}
+
+// Default implements webhook.Defaulter by defaulting the Hub version
+func (r *Await) Default() {
+	hub := &v1alpha1.Await{}
+	if err := r.ConvertTo(hub); err != nil {
+		awaitlog.Error(err, "unable to convert to the hub version", "name", r.Name)
+		return
+	}
+
+	hub.Default()
+
+	if err := r.ConvertFrom(hub); err != nil {
+		awaitlog.Error(err, "unable to convert from the hub version", "name", r.Name)
+	}
+}
+
+// +kubebuilder:webhook:verbs=create;update,path=/validate-await-argoproj-io-v1beta1-await,mutating=false,failurePolicy=fail,groups=await.argoproj.io,resources=awaits,versions=v1beta1,name=vawait.v1beta1.kb.io
+
+var _ webhook.Validator = &Await{}
+
+// ValidateCreate implements webhook.Validator by validating the Hub version
+func (r *Await) ValidateCreate() error {
+	hub := &v1alpha1.Await{}
+	if err := r.ConvertTo(hub); err != nil {
+		return err
+	}
+
+	return hub.ValidateCreate()
+}
+
+// ValidateUpdate implements webhook.Validator by validating the Hub version
+func (r *Await) ValidateUpdate(old runtime.Object) error {
+	hub, oldHub := &v1alpha1.Await{}, &v1alpha1.Await{}
+	if err := r.ConvertTo(hub); err != nil {
+		return err
+	}
+	if err := old.(*Await).ConvertTo(oldHub); err != nil {
+		return err
+	}
+
+	return hub.ValidateUpdate(oldHub)
+}
+
+// ValidateDelete implements webhook.Validator
+func (r *Await) ValidateDelete() error {
+	return nil
+}
@@ -17,4 +17,17 @@ const (
 	// PodNameEnvVar is the constant for env variable POD_NAME
 	// which is the name of the current pod.
 	PodNameEnvVar = "POD_NAME"
+
+	// EnableWebhooksEnvVar is the constant for env variable ENABLE_WEBHOOKS
+	// which can be set to "false" to run the operator without the admission webhooks,
+	// e.g. when running locally without serving certificates.
+	EnableWebhooksEnvVar = "ENABLE_WEBHOOKS"
+
+	// ArgoTokenEnvVar is the constant for env variable ARGO_TOKEN
+	// which is the token used to authenticate to the Argo Server.
+	ArgoTokenEnvVar = "ARGO_TOKEN"
+
+	// EventsSecretEnvVar is the constant for env variable EVENTS_SECRET
+	// which is the secret the senders of the CloudEvents authenticate with.
+	EventsSecretEnvVar = "EVENTS_SECRET"
 )
@@ -0,0 +1,236 @@
+/*
+Copyright 2019 Marek Cermak <macermak@redhat.com>.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+
+	v1alpha1 "github.com/cermakm/argo-await-operator/api/v1alpha1"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+// approvalResult is the outcome of the Approvals of an Await
+type approvalResult struct {
+	// records are the decisions of the eligible approvers
+	records []v1alpha1.ApprovalRecord
+	// approved is the Approval the required approvals were reached with
+	approved *v1alpha1.Approval
+	// rejected is the first Approval rejecting the Await
+	rejected *v1alpha1.Approval
+}
+
+// requiredApprovals returns the number of the approvers required by the policy
+func requiredApprovals(policy *v1alpha1.ApprovalPolicy) int {
+	if policy.Approvals < 1 {
+		return 1
+	}
+	return int(policy.Approvals)
+}
+
+// eligible returns whether the approver may decide on the Await, the Approvals
+// without an approver have not been admitted by the webhook and are never eligible
+func eligible(policy *v1alpha1.ApprovalPolicy, approver *v1alpha1.Approver) bool {
+	if approver == nil || approver.Username == "" {
+		return false
+	}
+	if len(policy.Groups) == 0 {
+		return true
+	}
+
+	for _, group := range approver.Groups {
+		for _, allowed := range policy.Groups {
+			if group == allowed {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// evaluateApprovals counts the decisions of the distinct eligible approvers in the
+// order the Approvals have been created, only the first decision of each approver counts
+func evaluateApprovals(policy *v1alpha1.ApprovalPolicy, approvals []v1alpha1.Approval) approvalResult {
+	sorted := make([]v1alpha1.Approval, len(approvals))
+	copy(sorted, approvals)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		ti, tj := sorted[i].CreationTimestamp, sorted[j].CreationTimestamp
+		if !ti.Equal(&tj) {
+			return ti.Before(&tj)
+		}
+		return sorted[i].Name < sorted[j].Name
+	})
+
+	var result approvalResult
+	decided := map[string]bool{}
+	approvers := 0
+	for i := range sorted {
+		approval := &sorted[i]
+		approver := approval.Spec.Approver
+		if !eligible(policy, approver) || decided[approver.Username] {
+			continue
+		}
+		decided[approver.Username] = true
+
+		decision := approval.Spec.Decision
+		if decision == "" {
+			decision = v1alpha1.ApprovalApprove
+		}
+		result.records = append(result.records, v1alpha1.ApprovalRecord{
+			Name:     approval.Name,
+			Approver: approver.Username,
+			Decision: decision,
+			Comment:  approval.Spec.Comment,
+			Time:     approval.CreationTimestamp,
+		})
+
+		switch {
+		case decision == v1alpha1.ApprovalReject && result.rejected == nil:
+			result.rejected = approval
+		case decision == v1alpha1.ApprovalApprove:
+			approvers++
+			if approvers == requiredApprovals(policy) {
+				result.approved = approval
+			}
+		}
+	}
+
+	if result.rejected != nil {
+		// A single rejection is enough, regardless of the approvals
+		result.approved = nil
+	}
+
+	return result
+}
+
+// approves returns whether the Approval has been created for the Await, the Approvals
+// left over from a deleted Await of the same name are never counted. The Approvals
+// admitted by the webhook are bound to the UID, the others are checked to have been
+// created after the Await.
+func approves(approval *v1alpha1.Approval, res *v1alpha1.Await) bool {
+	if approval.Spec.AwaitName != res.Name {
+		return false
+	}
+	if approval.Spec.AwaitUID != "" {
+		return approval.Spec.AwaitUID == res.UID
+	}
+	return !approval.CreationTimestamp.Before(&res.CreationTimestamp)
+}
+
+// describeApproval returns a human readable description of the ApprovalPolicy
+func describeApproval(policy *v1alpha1.ApprovalPolicy) string {
+	desc := fmt.Sprintf("%d approval(s)", requiredApprovals(policy))
+	if len(policy.Groups) > 0 {
+		desc += " of the members of " + strings.Join(policy.Groups, ", ")
+	}
+	return desc
+}
+
+// awaitApproval checks the Approvals of the waiting Await and records them in its
+// status, the Await is fulfilled once approved by enough approvers and fails once
+// rejected. The Await is reconciled again whenever its Approvals change.
+func (r *AwaitReconciler) awaitApproval(ctx context.Context, res *v1alpha1.Await, target runtime.Object) (ctrl.Result, error) {
+	key := types.NamespacedName{Namespace: res.Namespace, Name: res.Name}
+
+	list := &v1alpha1.ApprovalList{}
+	if err := r.List(ctx, list, client.InNamespace(res.Namespace)); err != nil {
+		return ctrl.Result{}, err
+	}
+	var approvals []v1alpha1.Approval
+	for i := range list.Items {
+		if approves(&list.Items[i], res) {
+			approvals = append(approvals, list.Items[i])
+		}
+	}
+
+	result := evaluateApprovals(res.Spec.Approval, approvals)
+	if !reflect.DeepEqual(result.records, res.Status.Approvals) {
+		err := r.updateStatus(key, func(status *v1alpha1.AwaitStatus) {
+			status.Approvals = result.records
+		})
+		if err != nil {
+			return ctrl.Result{}, err
+		}
+	}
+
+	if result.rejected == nil && result.approved == nil {
+		// Wait for more approvals
+		return ctrl.Result{}, nil
+	}
+
+	target = r.eventTarget(ctx, res, target)
+
+	if rejected := result.rejected; rejected != nil {
+		message := fmt.Sprintf("rejected by %s", rejected.Spec.Approver.Username)
+		if rejected.Spec.Comment != "" {
+			message += ": " + rejected.Spec.Comment
+		}
+		r.recordEvent(res, target, corev1.EventTypeWarning, ReasonRejected, "Approval %s %s", rejected.Name, message)
+
+		// The rejection is final, don't requeue
+		return ctrl.Result{}, r.updateStatus(key, func(status *v1alpha1.AwaitStatus) {
+			status.Phase = v1alpha1.AwaitFailed
+			status.FinishedAt = metav1.Now()
+			status.Message = message
+			observeDuration(res, status)
+		})
+	}
+
+	obj, err := approvalToUnstructured(result.approved)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+	return ctrl.Result{}, r.awaitFulfilledCallback(res.DeepCopy(), target)(ctx, obj)
+}
+
+// approvalToUnstructured converts the Approval to be reported as the matched object
+func approvalToUnstructured(approval *v1alpha1.Approval) (*unstructured.Unstructured, error) {
+	content, err := runtime.DefaultUnstructuredConverter.ToUnstructured(approval)
+	if err != nil {
+		return nil, err
+	}
+
+	obj := &unstructured.Unstructured{Object: content}
+	obj.SetAPIVersion(v1alpha1.GroupVersion.String())
+	obj.SetKind("Approval")
+	return obj, nil
+}
+
+// approvalRequests maps an Approval to the Await it decides on
+func approvalRequests(obj handler.MapObject) []reconcile.Request {
+	approval, ok := obj.Object.(*v1alpha1.Approval)
+	if !ok || approval.Spec.AwaitName == "" {
+		return nil
+	}
+
+	return []reconcile.Request{
+		{NamespacedName: types.NamespacedName{Namespace: approval.Namespace, Name: approval.Spec.AwaitName}},
+	}
+}
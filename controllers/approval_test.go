@@ -0,0 +1,240 @@
+/*
+Copyright 2019 Marek Cermak <macermak@redhat.com>.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	workflowv1alpha1 "github.com/argoproj/argo/pkg/apis/workflow/v1alpha1"
+
+	v1alpha1 "github.com/cermakm/argo-await-operator/api/v1alpha1"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+
+	ctrl "sigs.k8s.io/controller-runtime"
+)
+
+var approvalCreated = time.Date(2019, 10, 1, 9, 0, 0, 0, time.UTC)
+
+func newApproval(name, username string, decision v1alpha1.ApprovalDecision, minutes int, groups ...string) v1alpha1.Approval {
+	return v1alpha1.Approval{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:              name,
+			Namespace:         "default",
+			CreationTimestamp: metav1.NewTime(approvalCreated.Add(time.Duration(minutes) * time.Minute)),
+		},
+		Spec: v1alpha1.ApprovalSpec{
+			AwaitName: "await",
+			Decision:  decision,
+			Approver:  &v1alpha1.Approver{Username: username, Groups: groups},
+		},
+	}
+}
+
+func Test_evaluateApprovals(t *testing.T) {
+	unadmitted := newApproval("unadmitted", "", v1alpha1.ApprovalApprove, 0)
+	unadmitted.Spec.Approver = nil
+
+	tests := []struct {
+		name         string
+		policy       v1alpha1.ApprovalPolicy
+		approvals    []v1alpha1.Approval
+		wantRecords  int
+		wantApproved string
+		wantRejected string
+	}{
+		{
+			name:         "Single approval",
+			policy:       v1alpha1.ApprovalPolicy{Approvals: 1},
+			approvals:    []v1alpha1.Approval{newApproval("a", "jane", v1alpha1.ApprovalApprove, 0)},
+			wantRecords:  1,
+			wantApproved: "a",
+		},
+		{
+			name:   "Approver is counted once",
+			policy: v1alpha1.ApprovalPolicy{Approvals: 2},
+			approvals: []v1alpha1.Approval{
+				newApproval("a", "jane", v1alpha1.ApprovalApprove, 0),
+				newApproval("b", "jane", v1alpha1.ApprovalApprove, 1),
+			},
+			wantRecords: 1,
+		},
+		{
+			name:   "Approved in the order of creation",
+			policy: v1alpha1.ApprovalPolicy{Approvals: 2},
+			approvals: []v1alpha1.Approval{
+				newApproval("c", "joe", v1alpha1.ApprovalApprove, 2),
+				newApproval("a", "jane", v1alpha1.ApprovalApprove, 0),
+				newApproval("b", "john", v1alpha1.ApprovalApprove, 1),
+			},
+			wantRecords:  3,
+			wantApproved: "b",
+		},
+		{
+			name:   "Only the members of the groups are eligible",
+			policy: v1alpha1.ApprovalPolicy{Approvals: 1, Groups: []string{"release"}},
+			approvals: []v1alpha1.Approval{
+				newApproval("a", "jane", v1alpha1.ApprovalApprove, 0, "dev"),
+				newApproval("b", "john", v1alpha1.ApprovalApprove, 1, "dev", "release"),
+			},
+			wantRecords:  1,
+			wantApproved: "b",
+		},
+		{
+			name:        "Approvals without an approver are ignored",
+			policy:      v1alpha1.ApprovalPolicy{Approvals: 1},
+			approvals:   []v1alpha1.Approval{unadmitted},
+			wantRecords: 0,
+		},
+		{
+			name:   "Rejection overrides the approvals",
+			policy: v1alpha1.ApprovalPolicy{Approvals: 1},
+			approvals: []v1alpha1.Approval{
+				newApproval("a", "jane", v1alpha1.ApprovalApprove, 0),
+				newApproval("b", "john", v1alpha1.ApprovalReject, 1),
+			},
+			wantRecords:  2,
+			wantRejected: "b",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := evaluateApprovals(&tt.policy, tt.approvals)
+
+			if len(result.records) != tt.wantRecords {
+				t.Errorf("evaluateApprovals() records = %v, want %v", result.records, tt.wantRecords)
+			}
+			var approved, rejected string
+			if result.approved != nil {
+				approved = result.approved.Name
+			}
+			if result.rejected != nil {
+				rejected = result.rejected.Name
+			}
+			if approved != tt.wantApproved {
+				t.Errorf("evaluateApprovals() approved = %v, want %v", approved, tt.wantApproved)
+			}
+			if rejected != tt.wantRejected {
+				t.Errorf("evaluateApprovals() rejected = %v, want %v", rejected, tt.wantRejected)
+			}
+		})
+	}
+}
+
+func TestAwaitReconciler_awaitApproval(t *testing.T) {
+	leftover := newApproval("a", "jane", v1alpha1.ApprovalApprove, 1)
+	leftover.Spec.AwaitUID = "5b0c1e7d-9f1a-4d2e-8c3b-6a4f2e1d0c9b"
+	bound := newApproval("b", "john", v1alpha1.ApprovalApprove, 1)
+	bound.Spec.AwaitUID = newAwait("", "").UID
+
+	tests := []struct {
+		name      string
+		phase     v1alpha1.AwaitPhase
+		created   int
+		approvals []v1alpha1.Approval
+		want      v1alpha1.AwaitPhase
+		wantAudit int
+	}{
+		{
+			name: "Approval is started",
+			want: v1alpha1.AwaitWaiting,
+		},
+		{
+			name:      "Approval is not sufficient yet",
+			phase:     v1alpha1.AwaitWaiting,
+			approvals: []v1alpha1.Approval{newApproval("a", "jane", v1alpha1.ApprovalApprove, 0)},
+			want:      v1alpha1.AwaitWaiting,
+			wantAudit: 1,
+		},
+		{
+			name:  "Await is approved",
+			phase: v1alpha1.AwaitWaiting,
+			approvals: []v1alpha1.Approval{
+				newApproval("a", "jane", v1alpha1.ApprovalApprove, 0),
+				newApproval("b", "john", v1alpha1.ApprovalApprove, 1),
+			},
+			want:      v1alpha1.AwaitFulfilled,
+			wantAudit: 2,
+		},
+		{
+			name:      "Await is rejected",
+			phase:     v1alpha1.AwaitWaiting,
+			approvals: []v1alpha1.Approval{newApproval("a", "jane", v1alpha1.ApprovalReject, 0)},
+			want:      v1alpha1.AwaitFailed,
+			wantAudit: 1,
+		},
+		{
+			name:    "Approvals of the deleted Await of the same name",
+			phase:   v1alpha1.AwaitWaiting,
+			created: 5,
+			approvals: []v1alpha1.Approval{
+				newApproval("a", "jane", v1alpha1.ApprovalApprove, 0),
+				newApproval("b", "john", v1alpha1.ApprovalApprove, 1),
+			},
+			want:      v1alpha1.AwaitWaiting,
+			wantAudit: 0,
+		},
+		{
+			name:      "Approvals bound to the Await UIDs",
+			phase:     v1alpha1.AwaitWaiting,
+			approvals: []v1alpha1.Approval{leftover, bound},
+			want:      v1alpha1.AwaitWaiting,
+			wantAudit: 1,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			res := newAwait(tt.phase, "")
+			res.Spec.Resource = v1alpha1.Resource{}
+			res.Spec.Approval = &v1alpha1.ApprovalPolicy{Approvals: 2}
+			res.CreationTimestamp = metav1.NewTime(approvalCreated.Add(time.Duration(tt.created) * time.Minute))
+			res.Status.StartedAt = metav1.NewTime(approvalCreated)
+
+			objs := []runtime.Object{res}
+			for i := range tt.approvals {
+				objs = append(objs, &tt.approvals[i])
+			}
+			// Approvals of the other Awaits are not counted
+			other := newApproval("other", "joe", v1alpha1.ApprovalReject, 0)
+			other.Spec.AwaitName = "other"
+			objs = append(objs, &other)
+
+			workflows := &fakeWorkflowClient{workflow: newWorkflow(true, workflowv1alpha1.NodeRunning, nil)}
+			r := newReconciler(t, workflows, objs...)
+
+			key := types.NamespacedName{Namespace: res.Namespace, Name: res.Name}
+			if _, err := r.Reconcile(ctrl.Request{NamespacedName: key}); err != nil {
+				t.Fatalf("Reconcile() error = %v", err)
+			}
+
+			got := &v1alpha1.Await{}
+			if err := r.Get(context.TODO(), key, got); err != nil {
+				t.Fatal(err)
+			}
+			if got.Status.Phase != tt.want {
+				t.Errorf("Reconcile() phase = %v, want %v", got.Status.Phase, tt.want)
+			}
+			if len(got.Status.Approvals) != tt.wantAudit {
+				t.Errorf("Reconcile() approvals = %v, want %v", got.Status.Approvals, tt.wantAudit)
+			}
+		})
+	}
+}
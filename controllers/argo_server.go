@@ -0,0 +1,163 @@
+/*
+Copyright 2019 Marek Cermak <macermak@redhat.com>.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+
+	workflowv1alpha1 "github.com/argoproj/argo/pkg/apis/workflow/v1alpha1"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/wait"
+)
+
+var workflowsResource = schema.GroupResource{Group: "argoproj.io", Resource: "workflows"}
+
+// argoServerResumer resumes the Workflows using the Argo Server API
+type argoServerResumer struct {
+	url     string
+	token   string
+	client  *http.Client
+	backoff wait.Backoff
+}
+
+// workflowResumeRequest is the body of the Argo Server resume request
+type workflowResumeRequest struct {
+	Name              string `json:"name"`
+	Namespace         string `json:"namespace"`
+	NodeFieldSelector string `json:"nodeFieldSelector,omitempty"`
+}
+
+// NewArgoServerResumer creates a Resumer using the resume endpoint of the Argo Server
+// at the given URL. The token is sent as the bearer token of the requests, if set.
+// Transient errors are retried with the given backoff.
+func NewArgoServerResumer(serverURL, token string, client *http.Client, backoff wait.Backoff) Resumer {
+	if client == nil {
+		client = http.DefaultClient
+	}
+	if token != "" && !strings.HasPrefix(token, "Bearer ") {
+		token = "Bearer " + token
+	}
+
+	return &argoServerResumer{
+		url:     strings.TrimSuffix(serverURL, "/"),
+		token:   token,
+		client:  client,
+		backoff: backoff,
+	}
+}
+
+// Resume resumes the Workflow using the Argo Server, the outputs are ignored
+// since the resume endpoint does not support setting them
+func (r *argoServerResumer) Resume(ctx context.Context, key types.NamespacedName, nodeID string, outputs map[string]string) error {
+	var lastErr error
+	err := wait.ExponentialBackoff(r.backoff, func() (bool, error) {
+		lastErr = r.resume(ctx, key, nodeID)
+		if lastErr == nil {
+			return true, nil
+		}
+		if _, ok := lastErr.(*ResumeSkipped); ok {
+			return false, lastErr
+		}
+		return false, retriableOrNil(lastErr)
+	})
+	if err == wait.ErrWaitTimeout {
+		return lastErr
+	}
+
+	return err
+}
+
+// resume verifies the Workflow is still suspended at the node and resumes it
+func (r *argoServerResumer) resume(ctx context.Context, key types.NamespacedName, nodeID string) error {
+	wf := &workflowv1alpha1.Workflow{}
+	if err := r.do(ctx, http.MethodGet, key, "", nil, wf); err != nil {
+		return err
+	}
+	if skipped := checkResumable(wf, nodeID); skipped != nil {
+		return skipped
+	}
+
+	body := workflowResumeRequest{Name: key.Name, Namespace: key.Namespace}
+	if nodeID != "" {
+		body.NodeFieldSelector = "id=" + nodeID
+	}
+
+	return r.do(ctx, http.MethodPut, key, "/resume", body, nil)
+}
+
+// do sends the request for the Workflow to the Argo Server and decodes the response
+// into out, if given. Unsuccessful responses are returned as API errors, so that they
+// can be checked the same way as the errors of the API server.
+func (r *argoServerResumer) do(ctx context.Context, method string, key types.NamespacedName, subpath string, in, out interface{}) error {
+	path := fmt.Sprintf("/api/v1/workflows/%s/%s%s", url.PathEscape(key.Namespace), url.PathEscape(key.Name), subpath)
+
+	var body io.Reader
+	if in != nil {
+		data, err := json.Marshal(in)
+		if err != nil {
+			return err
+		}
+		body = bytes.NewReader(data)
+	}
+
+	req, err := http.NewRequest(method, r.url+path, body)
+	if err != nil {
+		return err
+	}
+	req = req.WithContext(ctx)
+	req.Header.Set("Accept", "application/json")
+	if in != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if r.token != "" {
+		req.Header.Set("Authorization", r.token)
+	}
+
+	resp, err := r.client.Do(req)
+	if err != nil {
+		// The server could not be reached, try again later
+		return apierrors.NewServiceUnavailable(err.Error())
+	}
+	defer resp.Body.Close()
+
+	data, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+
+	if resp.StatusCode < http.StatusOK || resp.StatusCode >= http.StatusMultipleChoices {
+		return apierrors.NewGenericServerResponse(
+			resp.StatusCode, method, workflowsResource, key.Name, strings.TrimSpace(string(data)), 0, true)
+	}
+
+	if out == nil {
+		return nil
+	}
+
+	return json.Unmarshal(data, out)
+}
@@ -0,0 +1,133 @@
+/*
+Copyright 2019 Marek Cermak <macermak@redhat.com>.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	workflowv1alpha1 "github.com/argoproj/argo/pkg/apis/workflow/v1alpha1"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+)
+
+// argoServer is a stand-in for the Argo Server serving a single Workflow
+type argoServer struct {
+	workflow *workflowv1alpha1.Workflow
+
+	// failures is the number of requests failing with the failure status code
+	failures int
+	failure  int
+
+	resumes []workflowResumeRequest
+}
+
+func (s *argoServer) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	if req.Header.Get("Authorization") != "Bearer token" {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+	if s.failures > 0 {
+		s.failures--
+		http.Error(w, "failure", s.failure)
+		return
+	}
+
+	path := "/api/v1/workflows/" + s.workflow.Namespace + "/" + s.workflow.Name
+	switch {
+	case req.Method == http.MethodGet && req.URL.Path == path:
+		_ = json.NewEncoder(w).Encode(s.workflow)
+	case req.Method == http.MethodPut && req.URL.Path == path+"/resume":
+		body := workflowResumeRequest{}
+		if err := json.NewDecoder(req.Body).Decode(&body); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		s.resumes = append(s.resumes, body)
+		_ = json.NewEncoder(w).Encode(s.workflow)
+	default:
+		http.NotFound(w, req)
+	}
+}
+
+func TestArgoServerResumer_Resume(t *testing.T) {
+	suspended := map[string]workflowv1alpha1.NodePhase{"node": workflowv1alpha1.NodeRunning}
+
+	tests := []struct {
+		name        string
+		server      *argoServer
+		token       string
+		wantErr     func(error) bool
+		wantResumes int
+	}{
+		{
+			name:        "Resumes the suspended node",
+			server:      &argoServer{workflow: newWorkflow(false, workflowv1alpha1.NodeRunning, suspended)},
+			token:       "token",
+			wantResumes: 1,
+		},
+		{
+			name:        "Retries unavailable server",
+			server:      &argoServer{workflow: newWorkflow(false, workflowv1alpha1.NodeRunning, suspended), failures: 2, failure: http.StatusServiceUnavailable},
+			token:       "Bearer token",
+			wantResumes: 1,
+		},
+		{
+			name:   "Skips a completed workflow",
+			server: &argoServer{workflow: newWorkflow(false, workflowv1alpha1.NodeSucceeded, nil)},
+			token:  "token",
+			wantErr: func(err error) bool {
+				skipped, ok := err.(*ResumeSkipped)
+				return ok && skipped.Reason == ReasonWorkflowCompleted
+			},
+		},
+		{
+			name:    "Fails unauthorized",
+			server:  &argoServer{workflow: newWorkflow(false, workflowv1alpha1.NodeRunning, suspended)},
+			token:   "invalid",
+			wantErr: apierrors.IsUnauthorized,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewServer(tt.server)
+			defer server.Close()
+
+			r := NewArgoServerResumer(server.URL, tt.token, server.Client(), testBackoff)
+			err := r.Resume(context.TODO(), namespacedName(tt.server.workflow), "node", nil)
+
+			if tt.wantErr == nil && err != nil {
+				t.Fatalf("Resume() error = %v", err)
+			}
+			if tt.wantErr != nil && !tt.wantErr(err) {
+				t.Fatalf("Resume() unexpected error = %v", err)
+			}
+
+			if len(tt.server.resumes) != tt.wantResumes {
+				t.Fatalf("Resume() resumes = %v, want %v", len(tt.server.resumes), tt.wantResumes)
+			}
+			for _, resume := range tt.server.resumes {
+				if resume.NodeFieldSelector != "id=node" {
+					t.Errorf("Resume() node field selector = %v, want %v", resume.NodeFieldSelector, "id=node")
+				}
+			}
+		})
+	}
+}
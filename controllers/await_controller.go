@@ -18,150 +18,734 @@ package controllers
 
 import (
 	"context"
-
-	workflowv1alpha1 "github.com/argoproj/argo/pkg/apis/workflow/v1alpha1"
-	argoprojv1alpha1 "github.com/argoproj/argo/pkg/client/clientset/versioned/typed/workflow/v1alpha1"
-	workflowutil "github.com/argoproj/argo/workflow/util"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
 
 	v1alpha1 "github.com/cermakm/argo-await-operator/api/v1alpha1"
+	"github.com/cermakm/argo-await-operator/metrics"
+	"github.com/cermakm/argo-await-operator/tracing"
 
-	"github.com/cermakm/argo-await-operator/observers/resource"
+	"github.com/cermakm/argo-await-operator/observers"
+	"github.com/cermakm/argo-await-operator/observers/cloudevents"
+	"github.com/cermakm/argo-await-operator/observers/filter"
+	workflowobserver "github.com/cermakm/argo-await-operator/observers/workflow"
 	"github.com/go-logr/logr"
+	"go.opentelemetry.io/otel/label"
 
+	corev1 "k8s.io/api/core/v1"
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/wait"
 	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/record"
+	"k8s.io/client-go/util/retry"
 
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/source"
+)
+
+// Reasons of the events recorded on the Await
+const (
+	ReasonTargetNotFound    = "TargetNotFound"
+	ReasonUnsupportedTarget = "UnsupportedTarget"
+	ReasonWaitingForSuspend = "WaitingForSuspend"
+	ReasonWatchStarted      = "WatchStarted"
+	ReasonFilterError       = "FilterError"
+	ReasonUnexpectedOutcome = "UnexpectedOutcome"
+	ReasonInvalidSchedule   = "InvalidSchedule"
+	ReasonUnknownObserver   = "UnknownObserver"
+	ReasonObserverFailed    = "ObserverFailed"
+	ReasonRejected          = "Rejected"
+	ReasonMatched           = "Matched"
+	ReasonResumed           = "Resumed"
+	ReasonResumeFailed      = "ResumeFailed"
+	ReasonCallbackSent      = "CallbackSent"
+	ReasonCallbackFailed    = "CallbackFailed"
+
+	ReasonWorkflowCompleted    = "WorkflowCompleted"
+	ReasonWorkflowNotSuspended = "WorkflowNotSuspended"
+	ReasonSuspendNodeChanged   = "SuspendNodeChanged"
+	ReasonTargetNotSuspended   = "TargetNotSuspended"
 )
 
+// DefaultResumeBackoff is the backoff of the attempts to resume a target
+// before the Await is requeued
+var DefaultResumeBackoff = wait.Backoff{
+	Steps:    5,
+	Duration: 100 * time.Millisecond,
+	Factor:   2.0,
+	Jitter:   0.1,
+}
+
+// observerRestartDelay is the delay before the Await whose observing has failed is observed again
+var observerRestartDelay = 10 * time.Second
+
 // AwaitReconciler reconciles a Await object
 type AwaitReconciler struct {
 	client.Client
 
-	Log    logr.Logger
-	Config *rest.Config
+	Log      logr.Logger
+	Config   *rest.Config
+	Recorder record.EventRecorder
+
+	// Actions resume the targets, keyed by the kind of the target
+	Actions map[string]TargetAction
+
+	// APIReader reads the Secrets with the callback keys from the API server
+	APIReader client.Reader
+	// Events receives the CloudEvents, the Awaits of the events fail to be observed if not set
+	Events *cloudevents.Receiver
+
+	// Observers create the observers of the Awaits by the observer type,
+	// only the built-in observers are available if not set
+	Observers *observers.Registry
+
+	// HTTPClient sends the callbacks and polls the HTTP endpoints,
+	// http.DefaultClient is used if not set
+	HTTPClient *http.Client
+
+	// WorkflowEvents enables recording events on the resumed target as well
+	WorkflowEvents bool
+
+	// observers holds the observations of the Awaits which are being observed
+	// by their UIDs
+	observers sync.Map
+	// restarts requeues the Awaits whose observing has failed
+	restarts chan event.GenericEvent
+}
+
+// observation is an Await being observed, the observing stops once it is cancelled
+type observation struct {
+	key    types.NamespacedName
+	cancel context.CancelFunc
 }
 
 // +kubebuilder:rbac:groups=await.argoproj.io,resources=awaits,verbs=get;list;watch;create;update;patch;delete
 // +kubebuilder:rbac:groups=await.argoproj.io,resources=awaits/status,verbs=get;update;patch
-
-func (r *AwaitReconciler) Reconcile(req ctrl.Request) (ctrl.Result, error) {
-	_ = context.Background()
+// +kubebuilder:rbac:groups=await.argoproj.io,resources=approvals,verbs=get;list;watch
+// +kubebuilder:rbac:groups="",resources=events,verbs=create;patch
+// +kubebuilder:rbac:groups="",resources=secrets,verbs=get
+// +kubebuilder:rbac:groups="",resources=configmaps,verbs=get
+// +kubebuilder:rbac:groups="",resources=pods,verbs=get;list;watch
+// +kubebuilder:rbac:groups="",resources=pods/log,verbs=get
+// +kubebuilder:rbac:groups=argoproj.io,resources=workflows,verbs=get;list;watch;update;patch
+// +kubebuilder:rbac:groups=argoproj.io,resources=cronworkflows,verbs=get;update;patch
+// +kubebuilder:rbac:groups=tekton.dev,resources=pipelineruns,verbs=get;update;patch
+// +kubebuilder:rbac:groups=apps,resources=deployments,verbs=get;update;patch
+// +kubebuilder:rbac:groups=batch,resources=jobs,verbs=get;update;patch
+
+func (r *AwaitReconciler) Reconcile(req ctrl.Request) (result ctrl.Result, err error) {
 	log := r.Log.WithValues("request", req)
 
 	// Fetch the Await instance
 	res := &v1alpha1.Await{}
-	err := r.Get(context.TODO(), req.NamespacedName, res)
+	err = r.Get(context.TODO(), req.NamespacedName, res)
 	if err != nil {
 		if apierrors.IsNotFound(err) {
 			// Request object not found, could have been deleted after reconcile request.
 			// Owned objects are automatically garbage collected. For additional cleanup logic use finalizers.
-			// Return and don't requeue
+			// Stop observing the deleted Await, return and don't requeue
+			r.stopObservingKey(req.NamespacedName)
 			return ctrl.Result{}, nil
 		}
 		// Error reading the object - requeue the request.
 		return ctrl.Result{}, err
 	}
 
-	wf, err := r.getWorkflowResource(res.Spec.Workflow)
-	if err != nil {
-		log.Error(err, "the requested Workflow was not found", "workflow", res.Spec.Workflow)
+	ctx, span := tracing.StartSpan(tracing.ContextForAwait(context.Background(), res.UID), "Reconcile",
+		tracing.AwaitUIDKey.String(string(res.UID)),
+		label.String("await.name", res.Name),
+		label.String("await.namespace", res.Namespace),
+	)
+	defer func() {
+		if err != nil {
+			tracing.RecordError(ctx, span, err)
+		}
+		span.End()
+	}()
 
-		// The Workflow to be resumed does not exist, don't reque
-		return ctrl.Result{Requeue: false}, err
+	switch res.Status.Phase {
+	case v1alpha1.AwaitResumed, v1alpha1.AwaitCompleted, v1alpha1.AwaitSkipped, v1alpha1.AwaitFailed:
+		// The Await has already been finished, nothing to do
+		r.stopObserving(res.UID)
+		return ctrl.Result{}, nil
+	case v1alpha1.AwaitFulfilled:
+		// The Resource has been awaited, but the target not resumed yet
+		r.stopObserving(res.UID)
+		return r.resumeTarget(ctx, res)
+	case v1alpha1.AwaitWaiting:
+		if res.Spec.Schedule != nil {
+			// The target has been suspended before the schedule started
+			return r.awaitSchedule(ctx, res, nil)
+		}
+		if res.Spec.Approval != nil {
+			// The Approvals of the Await have changed
+			return r.awaitApproval(ctx, res, nil)
+		}
 	}
 
-	if workflowutil.IsWorkflowSuspended(wf) != true {
-		status := getWorkflowStatus(wf)
-		log.Info("workflow is not suspended, reconciling", "status", status)
-
-		// The Workflow exists, but is not suspended (possibly yet), reque
+	if _, observed := r.observers.Load(res.UID); observed {
+		// The Await is already being observed
 		return ctrl.Result{}, nil
 	}
 
-	observer, err := resource.NewObserverForResource(r.Config, &res.Spec.Resource, res.Spec.Filters)
+	// The target, if any, has to be suspended before the Resource is awaited
+	var obj runtime.Object
+	var point string
+	if target := res.Spec.TargetRef(); target != nil {
+		action, ok := r.Actions[target.Kind]
+		if !ok {
+			r.Recorder.Eventf(res, corev1.EventTypeWarning, ReasonUnsupportedTarget,
+				"Target kind %s is not supported", target.Kind)
+
+			// The target can never be resumed, don't requeue
+			return ctrl.Result{}, r.updateStatus(req.NamespacedName, func(status *v1alpha1.AwaitStatus) {
+				status.Phase = v1alpha1.AwaitFailed
+				status.FinishedAt = metav1.Now()
+				status.Message = "unsupported target kind " + target.Kind
+			})
+		}
+
+		obj, err = action.Get(ctx, *target)
+		if err != nil {
+			log.Error(err, "the requested target was not found", "target", target)
+			r.Recorder.Eventf(res, corev1.EventTypeWarning, ReasonTargetNotFound,
+				"%s %s/%s was not found: %v", target.Kind, target.Namespace, target.Name, err)
+
+			// The target to be resumed does not exist, don't reque
+			return ctrl.Result{Requeue: false}, err
+		}
+
+		var suspended bool
+		suspended, point = action.Suspended(obj)
+		if !suspended {
+			log.Info("target is not suspended, reconciling", "target", target)
+
+			if res.Status.Phase != v1alpha1.AwaitPending {
+				res.Status.Phase = v1alpha1.AwaitPending
+				if err := r.Status().Update(context.TODO(), res); err != nil {
+					return ctrl.Result{}, err
+				}
+				r.Recorder.Eventf(res, corev1.EventTypeNormal, ReasonWaitingForSuspend,
+					"Waiting for %s %s/%s to be suspended", target.Kind, target.Namespace, target.Name)
+			}
+
+			// The target exists, but is not suspended (possibly yet), reque
+			return ctrl.Result{}, nil
+		}
+	}
+
+	if res.Spec.Schedule != nil {
+		if err := r.startWaiting(res, point); err != nil {
+			log.Error(err, "failed to update the await status")
+			return ctrl.Result{}, err
+		}
+
+		r.Recorder.Eventf(res, corev1.EventTypeNormal, ReasonWatchStarted,
+			"Waiting for %s", describeSchedule(res.Spec.Schedule))
+
+		// The Await is requeued until the schedule is due
+		return r.awaitSchedule(ctx, res, obj)
+	}
+
+	if res.Spec.Approval != nil {
+		if err := r.startWaiting(res, point); err != nil {
+			log.Error(err, "failed to update the await status")
+			return ctrl.Result{}, err
+		}
+
+		r.Recorder.Eventf(res, corev1.EventTypeNormal, ReasonWatchStarted,
+			"Waiting for %s", describeApproval(res.Spec.Approval))
+
+		// The Await is reconciled again on the changes of its Approvals
+		return r.awaitApproval(ctx, res, obj)
+	}
+
+	observer, err := r.newObserver(res)
+	if unknown, ok := err.(*observers.UnknownTypeError); ok {
+		r.Recorder.Event(res, corev1.EventTypeWarning, ReasonUnknownObserver, unknown.Error())
+
+		// No observer of the type can be created, don't requeue
+		return ctrl.Result{}, r.updateStatus(req.NamespacedName, func(status *v1alpha1.AwaitStatus) {
+			status.Phase = v1alpha1.AwaitFailed
+			status.FinishedAt = metav1.Now()
+			status.Message = unknown.Error()
+		})
+	}
 	if err != nil {
 		log.Error(err, "observer could not be created")
 		return ctrl.Result{Requeue: false}, err
 	}
 
-	// Await the requested Resource and then resume the Workflow
-	callback := r.workflowResumeCallback(wf)
-	go observer.Await(callback)
+	if err := r.startWaiting(res, point); err != nil {
+		log.Error(err, "failed to update the await status")
+		return ctrl.Result{}, err
+	}
+
+	// Await the requested Resource and then resume the target
+	callback := r.awaitFulfilledCallback(res.DeepCopy(), obj)
+
+	// The observing outlives the reconciliation until the Await is deleted or finished
+	ctx, cancel := context.WithCancel(ctx)
+	r.observers.Store(res.UID, observation{key: req.NamespacedName, cancel: cancel})
+	go func(res *v1alpha1.Await) {
+		defer r.observers.Delete(res.UID)
+		defer cancel()
+
+		observer.Start(ctx)
+		var result observers.Result
+		select {
+		case <-ctx.Done():
+			log.Info("stopped observing the await")
+			return
+		case result = <-observer.Result():
+		}
+		if result.Err == context.Canceled {
+			return
+		}
+
+		err := result.Err
+		if err == nil {
+			err = callback(ctx, result.Object)
+		}
+		if outcome, ok := err.(*workflowobserver.UnexpectedOutcome); ok {
+			r.Recorder.Event(res, corev1.EventTypeWarning, ReasonUnexpectedOutcome, outcome.Error())
+
+			if err := r.updateStatus(req.NamespacedName, func(status *v1alpha1.AwaitStatus) {
+				status.Phase = v1alpha1.AwaitFailed
+				status.FinishedAt = metav1.Now()
+				status.Message = outcome.Error()
+				observeDuration(res, status)
+			}); err != nil {
+				log.Error(err, "failed to update the await status")
+			}
+		}
+		if failure, ok := err.(*observers.Failure); ok {
+			r.Recorder.Event(res, corev1.EventTypeWarning, failure.Reason, failure.Message)
+
+			if err := r.updateStatus(req.NamespacedName, func(status *v1alpha1.AwaitStatus) {
+				status.Phase = v1alpha1.AwaitFailed
+				status.FinishedAt = metav1.Now()
+				status.Message = failure.Message
+				observeDuration(res, status)
+			}); err != nil {
+				log.Error(err, "failed to update the await status")
+			}
+		}
+		if err == filter.ErrInvalidFilters {
+			r.Recorder.Eventf(res, corev1.EventTypeWarning, ReasonFilterError,
+				"Filters could not be evaluated: %v", err)
+
+			if err := r.updateStatus(req.NamespacedName, func(status *v1alpha1.AwaitStatus) {
+				status.Phase = v1alpha1.AwaitFailed
+				status.FinishedAt = metav1.Now()
+				observeDuration(res, status)
+			}); err != nil {
+				log.Error(err, "failed to update the await status")
+			}
+		}
+		if err != nil {
+			log.Error(err, "failed to await the resource")
+		}
+		if err != nil && !finalError(err) {
+			// The error may be transient, e.g. of the watch or of the plugin,
+			// the Await keeps waiting and is observed again
+			r.Recorder.Eventf(res, corev1.EventTypeWarning, ReasonObserverFailed,
+				"Observing failed, restarting in %v: %v", observerRestartDelay, err)
+			r.observers.Delete(res.UID)
+			r.restartObserving(res)
+		}
+	}(res.DeepCopy())
+
+	r.Recorder.Event(res, corev1.EventTypeNormal, ReasonWatchStarted, observer.Describe())
 
 	// Observer created successfully - don't requeue
 	return ctrl.Result{}, nil
 }
 
-type workflowStatus struct {
-	Phase workflowv1alpha1.NodePhase `json:"phase"`
-	Nodes []nodeStatus               `json:"nodes"`
+// finalError returns whether the Await has been failed with the error of the observer
+func finalError(err error) bool {
+	switch err.(type) {
+	case *workflowobserver.UnexpectedOutcome, *observers.Failure:
+		return true
+	}
+	return err == filter.ErrInvalidFilters
 }
 
-type nodeStatus struct {
-	// Name is unique name in the node tree used to generate the node ID
-	Name  string                     `json:"name"`
-	Type  workflowv1alpha1.NodeType  `json:"type"`
-	Phase workflowv1alpha1.NodePhase `json:"phase"`
+// restartObserving requeues the Await once the restart delay has passed,
+// the reconciliation starts observing it again
+func (r *AwaitReconciler) restartObserving(res *v1alpha1.Await) {
+	if r.restarts == nil {
+		return
+	}
+
+	time.AfterFunc(observerRestartDelay, func() {
+		r.restarts <- event.GenericEvent{Meta: res, Object: res}
+	})
 }
 
-func getWorkflowStatus(workflow *workflowv1alpha1.Workflow) *workflowStatus {
-	status := &workflowStatus{Phase: workflow.Status.Phase}
+// stopObserving cancels the observation of the Await, if any
+func (r *AwaitReconciler) stopObserving(uid types.UID) {
+	if value, ok := r.observers.Load(uid); ok {
+		value.(observation).cancel()
+		r.observers.Delete(uid)
+	}
+}
 
-	for _, node := range workflow.Status.Nodes {
-		ns := nodeStatus{
-			Name:  node.Name,
-			Type:  node.Type,
-			Phase: node.Phase,
+// stopObservingKey cancels the observations of the Awaits of the given name,
+// the UID of a deleted Await is not known anymore
+func (r *AwaitReconciler) stopObservingKey(key types.NamespacedName) {
+	r.observers.Range(func(uid, value interface{}) bool {
+		if value.(observation).key == key {
+			value.(observation).cancel()
+			r.observers.Delete(uid)
 		}
-		status.Nodes = append(status.Nodes, ns)
+		return true
+	})
+}
+
+// startWaiting sets the Await waiting for the target suspended at the given point
+func (r *AwaitReconciler) startWaiting(res *v1alpha1.Await, point string) error {
+	res.Status.Phase = v1alpha1.AwaitWaiting
+	if res.Status.StartedAt.IsZero() {
+		// The Await re-observed, e.g. after a restart, keeps waiting since it started
+		res.Status.StartedAt = metav1.Now()
 	}
+	res.Status.SuspendedNode = point
+	res.Status.ObservedKind = res.Spec.ObservedKind()
 
-	return status
+	return r.Status().Update(context.TODO(), res)
 }
 
-// getWorkflowResource retrieves the Workflow resource from the given namespace which requested the await
-func (r *AwaitReconciler) getWorkflowResource(workflow v1alpha1.NamespacedWorkflow) (*workflowv1alpha1.Workflow, error) {
-	clientset := argoprojv1alpha1.NewForConfigOrDie(r.Config)
-	workflows := clientset.Workflows(workflow.Namespace)
+// newObserver creates the observer of the Await by the registered observer types
+func (r *AwaitReconciler) newObserver(res *v1alpha1.Await) (observers.Observer, error) {
+	registry := r.Observers
+	if registry == nil {
+		registry = observers.NewRegistry()
+	}
 
-	wf, err := workflows.Get(workflow.Name, metav1.GetOptions{})
-	if err != nil {
-		return nil, err
+	return registry.New(res, observers.Options{
+		Config:     r.Config,
+		HTTPClient: r.HTTPClient,
+		Events:     r.Events,
+	})
+}
+
+// updateStatus fetches the latest Await and updates its status,
+// retrying on conflicts
+func (r *AwaitReconciler) updateStatus(key types.NamespacedName, mutate func(*v1alpha1.AwaitStatus)) error {
+	return retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		res := &v1alpha1.Await{}
+		if err := r.Get(context.TODO(), key, res); err != nil {
+			return err
+		}
+
+		mutate(&res.Status)
+		return r.Status().Update(context.TODO(), res)
+	})
+}
+
+// observeDuration records the duration of a finished Await
+func observeDuration(res *v1alpha1.Await, status *v1alpha1.AwaitStatus) {
+	duration := status.FinishedAt.Sub(status.StartedAt.Time)
+	metrics.AwaitDuration.WithLabelValues(res.Spec.ObservedKind(), res.Namespace).Observe(duration.Seconds())
+}
+
+// objectReference returns a reference to the given object,
+// nil if it is not a single Kubernetes object, e.g. an HTTP response body or a List
+func objectReference(obj *unstructured.Unstructured) *corev1.ObjectReference {
+	if obj.GetKind() == "" || obj.IsList() {
+		return nil
 	}
 
-	return wf, nil
+	return &corev1.ObjectReference{
+		APIVersion:      obj.GetAPIVersion(),
+		Kind:            obj.GetKind(),
+		Namespace:       obj.GetNamespace(),
+		Name:            obj.GetName(),
+		UID:             obj.GetUID(),
+		ResourceVersion: obj.GetResourceVersion(),
+	}
 }
 
-// workflowResumeCallback returns the callback function
-// which should be run after the resource has been awaited
-func (r *AwaitReconciler) workflowResumeCallback(workflow *workflowv1alpha1.Workflow) func() error {
-	f := func() error {
-		log := r.Log.WithValues(
-			"Workflow.Name", workflow.Name, "Workflow.Namespace", workflow.Namespace)
-		log.Info("resuming workflow")
+// matchedCount returns the number of the resources passing the filters
+// when the Count condition was fulfilled, zero if there is none
+func matchedCount(res *v1alpha1.Await, obj *unstructured.Unstructured) int32 {
+	if res.Spec.Count == nil || !obj.IsList() {
+		return 0
+	}
+
+	items, _, _ := unstructured.NestedSlice(obj.Object, "items")
+	return int32(len(items))
+}
 
-		clientset := argoprojv1alpha1.NewForConfigOrDie(r.Config)
-		workflows := clientset.Workflows(workflow.Namespace)
+// eventTarget returns the target of the Await to record the events on, the target
+// is fetched if not given and nil if there is none or it could not be fetched
+func (r *AwaitReconciler) eventTarget(ctx context.Context, res *v1alpha1.Await, target runtime.Object) runtime.Object {
+	ref := res.Spec.TargetRef()
+	if ref == nil || target != nil {
+		return target
+	}
 
-		err := workflowutil.ResumeWorkflow(workflows, workflow.Name)
-		if err != nil {
-			log.Error(err, "failed to resume workflow")
-			return err
+	if action, ok := r.Actions[ref.Kind]; ok {
+		if obj, err := action.Get(ctx, *ref); err == nil {
+			return obj
 		}
+	}
+	return nil
+}
 
-		log.Info("workflow successfully resumed.")
-		return nil
+// recordEvent records an event on the Await and, if enabled, on the target
+func (r *AwaitReconciler) recordEvent(res *v1alpha1.Await, target runtime.Object, eventtype, reason, messageFmt string, args ...interface{}) {
+	r.Recorder.Eventf(res, eventtype, reason, messageFmt, args...)
+
+	if r.WorkflowEvents && target != nil {
+		r.Recorder.Eventf(target, eventtype, reason, messageFmt, args...)
+	}
+}
+
+// awaitFulfilledCallback returns the callback function which should be run
+// after the resource has been awaited, the target is resumed by the reconciler
+func (r *AwaitReconciler) awaitFulfilledCallback(res *v1alpha1.Await, target runtime.Object) func(context.Context, *unstructured.Unstructured) error {
+	key := types.NamespacedName{Namespace: res.Namespace, Name: res.Name}
+
+	f := func(ctx context.Context, obj *unstructured.Unstructured) error {
+		if res.Spec.Count != nil {
+			r.recordEvent(res, target, corev1.EventTypeNormal, ReasonMatched,
+				"%d %s matched the %s count condition", matchedCount(res, obj), res.Spec.Resource.Kind, res.Spec.Count.Operator)
+		} else if obj.GetKind() != "" {
+			r.recordEvent(res, target, corev1.EventTypeNormal, ReasonMatched,
+				"%s %s/%s matched the filters", obj.GetKind(), obj.GetNamespace(), obj.GetName())
+		} else {
+			r.recordEvent(res, target, corev1.EventTypeNormal, ReasonMatched,
+				"%s await has been fulfilled", res.Spec.ObservedKind())
+		}
+
+		outputs := r.matchedOutputs(res, obj)
+
+		if res.Spec.Callback != nil {
+			if err := r.httpCallback(ctx, res, target, obj); err != nil {
+				return r.updateStatus(key, func(status *v1alpha1.AwaitStatus) {
+					status.Phase = v1alpha1.AwaitFailed
+					status.FinishedAt = metav1.Now()
+					status.MatchedObject = objectReference(obj)
+					status.MatchedCount = matchedCount(res, obj)
+					status.Message = err.Error()
+					observeDuration(res, status)
+				})
+			}
+		}
+
+		if res.Spec.TargetRef() == nil {
+			// There is nothing to be resumed
+			return r.updateStatus(key, func(status *v1alpha1.AwaitStatus) {
+				status.Phase = v1alpha1.AwaitCompleted
+				status.FinishedAt = metav1.Now()
+				status.MatchedObject = objectReference(obj)
+				status.MatchedCount = matchedCount(res, obj)
+				status.Outputs = outputs
+				observeDuration(res, status)
+			})
+		}
+
+		// Persist the match first so that the resume is never lost,
+		// the status update triggers the reconciliation which resumes the target
+		return r.updateStatus(key, func(status *v1alpha1.AwaitStatus) {
+			status.Phase = v1alpha1.AwaitFulfilled
+			status.MatchedObject = objectReference(obj)
+			status.MatchedCount = matchedCount(res, obj)
+			status.Outputs = outputs
+		})
 	}
 
 	return f
 }
 
+// matchedOutputs returns the output parameters of the awaited Workflow, nil if
+// no Workflow completion is awaited
+func (r *AwaitReconciler) matchedOutputs(res *v1alpha1.Await, obj *unstructured.Unstructured) map[string]string {
+	if res.Spec.Completion == nil {
+		return nil
+	}
+
+	outputs, err := workflowobserver.Outputs(obj, res.Spec.Completion.Node)
+	if err != nil {
+		r.Log.Error(err, "failed to read the outputs of the workflow", "workflow", obj.GetName())
+	}
+
+	return outputs
+}
+
+// httpCallback sends the matched object to the callback URL of the Await
+func (r *AwaitReconciler) httpCallback(ctx context.Context, res *v1alpha1.Await, target runtime.Object, obj *unstructured.Unstructured) error {
+	ctx, span := tracing.StartSpan(ctx, "Callback",
+		tracing.AwaitUIDKey.String(string(res.UID)),
+	)
+	defer span.End()
+
+	client := r.HTTPClient
+	if client == nil {
+		client = http.DefaultClient
+	}
+
+	key, err := r.callbackKey(ctx, res)
+	if err == nil {
+		err = sendCallback(ctx, client, res, obj, key, DefaultResumeBackoff)
+	}
+	if err != nil {
+		r.Log.Error(err, "failed to send the callback", "url", res.Spec.Callback.URL)
+		tracing.RecordError(ctx, span, err)
+		r.recordEvent(res, target, corev1.EventTypeWarning, ReasonCallbackFailed,
+			"Callback to %s failed: %v", res.Spec.Callback.URL, err)
+		return err
+	}
+
+	r.recordEvent(res, target, corev1.EventTypeNormal, ReasonCallbackSent,
+		"Callback sent to %s", res.Spec.Callback.URL)
+	return nil
+}
+
+// resumeTarget resumes the target of a fulfilled Await, retrying with
+// exponential backoff on transient errors. The target is resumed only if it is
+// still suspended at the point the Await was created for, otherwise the Await is skipped.
+// If the target still could not be resumed, the failure is recorded
+// and the error returned to requeue the Await.
+func (r *AwaitReconciler) resumeTarget(ctx context.Context, res *v1alpha1.Await) (ctrl.Result, error) {
+	key := types.NamespacedName{Namespace: res.Namespace, Name: res.Name}
+	target := res.Spec.TargetRef()
+	if target == nil {
+		// Only the callback has been set, there is nothing to be resumed
+		return ctrl.Result{}, r.updateStatus(key, func(status *v1alpha1.AwaitStatus) {
+			status.Phase = v1alpha1.AwaitCompleted
+			status.FinishedAt = metav1.Now()
+			observeDuration(res, status)
+		})
+	}
+
+	log := r.Log.WithValues(
+		"Target.Kind", target.Kind, "Target.Name", target.Name, "Target.Namespace", target.Namespace)
+
+	ctx, span := tracing.StartSpan(ctx, "Resume",
+		tracing.AwaitUIDKey.String(string(res.UID)),
+		label.String("target.kind", target.Kind),
+		label.String("target.name", target.Name),
+		label.String("target.namespace", target.Namespace),
+	)
+	defer span.End()
+
+	fail := func(err error) error {
+		return r.updateStatus(key, func(status *v1alpha1.AwaitStatus) {
+			status.Phase = v1alpha1.AwaitFailed
+			status.FinishedAt = metav1.Now()
+			status.LastResumeError = err.Error()
+			observeDuration(res, status)
+		})
+	}
+
+	action, ok := r.Actions[target.Kind]
+	if !ok {
+		r.Recorder.Eventf(res, corev1.EventTypeWarning, ReasonUnsupportedTarget,
+			"Target kind %s is not supported", target.Kind)
+
+		return ctrl.Result{}, fail(fmt.Errorf("unsupported target kind %s", target.Kind))
+	}
+
+	obj, err := action.Get(ctx, *target)
+	if err != nil {
+		if !apierrors.IsNotFound(err) {
+			return ctrl.Result{}, err
+		}
+		log.Error(err, "the requested target was not found")
+		r.Recorder.Eventf(res, corev1.EventTypeWarning, ReasonTargetNotFound,
+			"%s %s/%s was not found: %v", target.Kind, target.Namespace, target.Name, err)
+
+		return ctrl.Result{}, fail(err)
+	}
+
+	log.Info("resuming target", "point", res.Status.SuspendedNode)
+
+	err = action.Resume(ctx, *target, res.Status.SuspendedNode, res.Status.Outputs)
+	if skipped, ok := err.(*ResumeSkipped); ok {
+		log.Info("skipping the target resume", "reason", skipped.Reason, "message", skipped.Message)
+		r.recordEvent(res, obj, corev1.EventTypeNormal, skipped.Reason, "%s", skipped.Message)
+
+		return ctrl.Result{}, r.updateStatus(key, func(status *v1alpha1.AwaitStatus) {
+			status.Phase = v1alpha1.AwaitSkipped
+			status.FinishedAt = metav1.Now()
+			status.Message = skipped.Message
+			observeDuration(res, status)
+		})
+	}
+
+	if err != nil {
+		log.Error(err, "failed to resume the target")
+		tracing.RecordError(ctx, span, err)
+		r.recordEvent(res, obj, corev1.EventTypeWarning, ReasonResumeFailed,
+			"%s %s/%s could not be resumed: %v", target.Kind, target.Namespace, target.Name, err)
+		metrics.WorkflowResumes.WithLabelValues(target.Namespace, metrics.ResultFailure).Inc()
+
+		resumeErr, retriable := err, isRetriable(err)
+		if err := r.updateStatus(key, func(status *v1alpha1.AwaitStatus) {
+			status.ResumeAttempts++
+			status.LastResumeError = resumeErr.Error()
+			if !retriable {
+				status.Phase = v1alpha1.AwaitFailed
+				status.FinishedAt = metav1.Now()
+				observeDuration(res, status)
+			}
+		}); err != nil {
+			log.Error(err, "failed to update the await status")
+		}
+
+		if !retriable {
+			// The target can not be resumed, don't requeue
+			return ctrl.Result{}, nil
+		}
+		// Requeue with the rate limited backoff of the controller
+		return ctrl.Result{}, err
+	}
+
+	metrics.WorkflowResumes.WithLabelValues(target.Namespace, metrics.ResultSuccess).Inc()
+	log.Info("target successfully resumed.")
+	r.recordEvent(res, obj, corev1.EventTypeNormal, ReasonResumed,
+		"%s %s/%s resumed", target.Kind, target.Namespace, target.Name)
+
+	return ctrl.Result{}, r.updateStatus(key, func(status *v1alpha1.AwaitStatus) {
+		status.Phase = v1alpha1.AwaitResumed
+		status.FinishedAt = metav1.Now()
+		status.LastResumeError = ""
+		observeDuration(res, status)
+	})
+}
+
+// isRetriable returns whether the error is transient and the request may be retried
+func isRetriable(err error) bool {
+	return apierrors.IsConflict(err) ||
+		apierrors.IsServerTimeout(err) ||
+		apierrors.IsTimeout(err) ||
+		apierrors.IsTooManyRequests(err) ||
+		apierrors.IsServiceUnavailable(err) ||
+		apierrors.IsInternalError(err) ||
+		apierrors.IsUnexpectedServerError(err)
+}
+
 // SetupWithManager sets up the controller
 func (r *AwaitReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	r.restarts = make(chan event.GenericEvent)
+
 	return ctrl.NewControllerManagedBy(mgr).
 		For(&v1alpha1.Await{}).
+		Watches(&source.Kind{Type: &v1alpha1.Approval{}}, &handler.EnqueueRequestsFromMapFunc{
+			ToRequests: handler.ToRequestsFunc(approvalRequests),
+		}).
+		Watches(&source.Channel{Source: r.restarts}, &handler.EnqueueRequestForObject{}).
 		Complete(r)
 }
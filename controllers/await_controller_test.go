@@ -0,0 +1,374 @@
+/*
+Copyright 2019 Marek Cermak <macermak@redhat.com>.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	workflowv1alpha1 "github.com/argoproj/argo/pkg/apis/workflow/v1alpha1"
+
+	v1alpha1 "github.com/cermakm/argo-await-operator/api/v1alpha1"
+	"github.com/cermakm/argo-await-operator/observers"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
+
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+func newReconciler(t *testing.T, workflows WorkflowClient, objs ...runtime.Object) *AwaitReconciler {
+	scheme := runtime.NewScheme()
+	if err := v1alpha1.AddToScheme(scheme); err != nil {
+		t.Fatal(err)
+	}
+
+	return &AwaitReconciler{
+		Client:   fake.NewFakeClientWithScheme(scheme, objs...),
+		Log:      logf.Log.WithName("test"),
+		Recorder: record.NewFakeRecorder(10),
+		Actions: map[string]TargetAction{
+			v1alpha1.TargetWorkflow: NewWorkflowAction(workflows, NewPatchResumer(workflows, testBackoff)),
+		},
+	}
+}
+
+func newAwait(phase v1alpha1.AwaitPhase, nodeID string) *v1alpha1.Await {
+	return &v1alpha1.Await{
+		ObjectMeta: metav1.ObjectMeta{Name: "await", Namespace: "default", UID: "2d6f2c0e-7e3a-4c5b-9b1e-0f5f7c2a9d41"},
+		Spec: v1alpha1.AwaitSpec{
+			Workflow: v1alpha1.NamespacedWorkflow{Name: "workflow", Namespace: "default"},
+			Resource: v1alpha1.Resource{Name: "configmaps", Version: "v1", Kind: "ConfigMap"},
+		},
+		Status: v1alpha1.AwaitStatus{Phase: phase, SuspendedNode: nodeID},
+	}
+}
+
+func TestAwaitReconciler_Reconcile(t *testing.T) {
+	unknownObserver := newAwait("", "")
+	unknownObserver.Spec.Resource = v1alpha1.Resource{}
+	unknownObserver.Spec.Observer = &v1alpha1.ObserverSpec{Type: "unknown"}
+
+	tests := []struct {
+		name     string
+		await    *v1alpha1.Await
+		workflow *workflowv1alpha1.Workflow
+		want     v1alpha1.AwaitPhase
+		wantNode workflowv1alpha1.NodePhase
+	}{
+		{
+			name:     "Workflow not suspended",
+			await:    newAwait("", ""),
+			workflow: newWorkflow(false, workflowv1alpha1.NodeRunning, nil),
+			want:     v1alpha1.AwaitPending,
+		},
+		{
+			name:     "Fulfilled await resumes the workflow",
+			await:    newAwait(v1alpha1.AwaitFulfilled, "node"),
+			workflow: newWorkflow(false, workflowv1alpha1.NodeRunning, map[string]workflowv1alpha1.NodePhase{"node": workflowv1alpha1.NodeRunning}),
+			want:     v1alpha1.AwaitResumed,
+			wantNode: workflowv1alpha1.NodeSucceeded,
+		},
+		{
+			name:     "Fulfilled await skips a completed workflow",
+			await:    newAwait(v1alpha1.AwaitFulfilled, "node"),
+			workflow: newWorkflow(false, workflowv1alpha1.NodeSucceeded, map[string]workflowv1alpha1.NodePhase{"node": workflowv1alpha1.NodeSucceeded}),
+			want:     v1alpha1.AwaitSkipped,
+			wantNode: workflowv1alpha1.NodeSucceeded,
+		},
+		{
+			name:     "Unknown observer type fails the await",
+			await:    unknownObserver,
+			workflow: newWorkflow(true, workflowv1alpha1.NodeRunning, nil),
+			want:     v1alpha1.AwaitFailed,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			workflows := &fakeWorkflowClient{workflow: tt.workflow}
+			r := newReconciler(t, workflows, tt.await)
+
+			key := types.NamespacedName{Namespace: tt.await.Namespace, Name: tt.await.Name}
+			if _, err := r.Reconcile(ctrl.Request{NamespacedName: key}); err != nil {
+				t.Fatalf("Reconcile() error = %v", err)
+			}
+
+			got := &v1alpha1.Await{}
+			if err := r.Get(context.TODO(), key, got); err != nil {
+				t.Fatal(err)
+			}
+			if got.Status.Phase != tt.want {
+				t.Errorf("Reconcile() phase = %v, want %v", got.Status.Phase, tt.want)
+			}
+			if tt.wantNode != "" {
+				if phase := workflows.workflow.Status.Nodes["node"].Phase; phase != tt.wantNode {
+					t.Errorf("Reconcile() node phase = %v, want %v", phase, tt.wantNode)
+				}
+			}
+		})
+	}
+}
+
+func TestAwaitReconciler_awaitSchedule(t *testing.T) {
+	now := time.Now()
+
+	tests := []struct {
+		name        string
+		phase       v1alpha1.AwaitPhase
+		at          time.Time
+		cron        string
+		want        v1alpha1.AwaitPhase
+		wantRequeue bool
+	}{
+		{
+			name:        "Schedule is started",
+			at:          now.Add(time.Hour),
+			want:        v1alpha1.AwaitWaiting,
+			wantRequeue: true,
+		},
+		{
+			name:        "Schedule is not due yet",
+			phase:       v1alpha1.AwaitWaiting,
+			at:          now.Add(time.Hour),
+			want:        v1alpha1.AwaitWaiting,
+			wantRequeue: true,
+		},
+		{
+			name:  "Schedule is due",
+			phase: v1alpha1.AwaitWaiting,
+			at:    now.Add(-time.Minute),
+			want:  v1alpha1.AwaitFulfilled,
+		},
+		{
+			name:  "Schedule is never due",
+			phase: v1alpha1.AwaitWaiting,
+			cron:  "0 0 30 2 *",
+			want:  v1alpha1.AwaitFailed,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			res := newAwait(tt.phase, "")
+			res.Spec.Resource = v1alpha1.Resource{}
+			res.Spec.Schedule = &v1alpha1.Schedule{At: &metav1.Time{Time: tt.at}}
+			if tt.cron != "" {
+				res.Spec.Schedule = &v1alpha1.Schedule{Cron: tt.cron}
+			}
+			res.Status.StartedAt = metav1.NewTime(now.Add(-time.Hour))
+
+			workflows := &fakeWorkflowClient{workflow: newWorkflow(true, workflowv1alpha1.NodeRunning, nil)}
+			r := newReconciler(t, workflows, res)
+
+			key := types.NamespacedName{Namespace: res.Namespace, Name: res.Name}
+			result, err := r.Reconcile(ctrl.Request{NamespacedName: key})
+			if err != nil {
+				t.Fatalf("Reconcile() error = %v", err)
+			}
+			if (result.RequeueAfter > 0) != tt.wantRequeue {
+				t.Errorf("Reconcile() requeue after = %v, want requeue %v", result.RequeueAfter, tt.wantRequeue)
+			}
+
+			got := &v1alpha1.Await{}
+			if err := r.Get(context.TODO(), key, got); err != nil {
+				t.Fatal(err)
+			}
+			if got.Status.Phase != tt.want {
+				t.Errorf("Reconcile() phase = %v, want %v", got.Status.Phase, tt.want)
+			}
+		})
+	}
+}
+
+// blockingObserver observes until its context is cancelled
+type blockingObserver struct {
+	started chan context.Context
+	result  chan observers.Result
+}
+
+func (o *blockingObserver) Start(ctx context.Context) {
+	o.started <- ctx
+}
+
+func (o *blockingObserver) Result() <-chan observers.Result {
+	return o.result
+}
+
+func (o *blockingObserver) Describe() string {
+	return "blocking"
+}
+
+func TestAwaitReconciler_stopObserving(t *testing.T) {
+	tests := []struct {
+		name string
+		stop func(t *testing.T, r *AwaitReconciler, res *v1alpha1.Await)
+	}{
+		{
+			name: "Deleted await",
+			stop: func(t *testing.T, r *AwaitReconciler, res *v1alpha1.Await) {
+				if err := r.Delete(context.TODO(), res); err != nil {
+					t.Fatal(err)
+				}
+			},
+		},
+		{
+			name: "Finished await",
+			stop: func(t *testing.T, r *AwaitReconciler, res *v1alpha1.Await) {
+				res.Status.Phase = v1alpha1.AwaitCompleted
+				if err := r.Status().Update(context.TODO(), res); err != nil {
+					t.Fatal(err)
+				}
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			await := newAwait("", "")
+			await.Spec.Workflow = v1alpha1.NamespacedWorkflow{}
+			await.Spec.Callback = &v1alpha1.HTTPCallback{URL: "http://example.com"}
+			await.Spec.Observer = &v1alpha1.ObserverSpec{Type: "blocking"}
+
+			observer := &blockingObserver{started: make(chan context.Context, 1), result: make(chan observers.Result)}
+			r := newReconciler(t, &fakeWorkflowClient{}, await)
+			r.Observers = observers.NewRegistry()
+			if err := r.Observers.Register("blocking", func(*v1alpha1.Await, observers.Options) (observers.Observer, error) {
+				return observer, nil
+			}); err != nil {
+				t.Fatal(err)
+			}
+
+			key := types.NamespacedName{Namespace: await.Namespace, Name: await.Name}
+			if _, err := r.Reconcile(ctrl.Request{NamespacedName: key}); err != nil {
+				t.Fatalf("Reconcile() error = %v", err)
+			}
+			ctx := <-observer.started
+
+			res := &v1alpha1.Await{}
+			if err := r.Get(context.TODO(), key, res); err != nil {
+				t.Fatal(err)
+			}
+			tt.stop(t, r, res)
+			if _, err := r.Reconcile(ctrl.Request{NamespacedName: key}); err != nil {
+				t.Fatalf("Reconcile() error = %v", err)
+			}
+
+			select {
+			case <-ctx.Done():
+			case <-time.After(5 * time.Second):
+				t.Fatal("the observer has not been cancelled")
+			}
+			if _, observed := r.observers.Load(await.UID); observed {
+				t.Error("the await is still being observed")
+			}
+		})
+	}
+}
+
+func TestAwaitReconciler_startWaiting(t *testing.T) {
+	started := metav1.NewTime(time.Now().Add(-time.Hour).Truncate(time.Second))
+	tests := []struct {
+		name      string
+		startedAt metav1.Time
+		http      *v1alpha1.HTTPEndpoint
+		wantKept  bool
+		wantKind  string
+	}{
+		{name: "Pending await", wantKept: false, wantKind: "ConfigMap"},
+		{name: "Re-observed await", startedAt: started, wantKept: true, wantKind: "ConfigMap"},
+		{name: "HTTP await", http: &v1alpha1.HTTPEndpoint{URL: "http://example.com"}, wantKind: v1alpha1.ObservedKindHTTP},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			await := newAwait(v1alpha1.AwaitWaiting, "")
+			await.Status.StartedAt = tt.startedAt
+			await.Spec.HTTP = tt.http
+			r := newReconciler(t, &fakeWorkflowClient{}, await)
+
+			res := await.DeepCopy()
+			if err := r.startWaiting(res, "node"); err != nil {
+				t.Fatalf("startWaiting() error = %v", err)
+			}
+
+			got := &v1alpha1.Await{}
+			if err := r.Get(context.TODO(), types.NamespacedName{Namespace: await.Namespace, Name: await.Name}, got); err != nil {
+				t.Fatal(err)
+			}
+			if got.Status.StartedAt.IsZero() {
+				t.Fatal("startWaiting() StartedAt is not set")
+			}
+			if kept := got.Status.StartedAt.Equal(&started); kept != tt.wantKept {
+				t.Errorf("startWaiting() StartedAt = %v, kept = %v, want %v", got.Status.StartedAt, kept, tt.wantKept)
+			}
+			if got.Status.ObservedKind != tt.wantKind {
+				t.Errorf("startWaiting() ObservedKind = %v, want %v", got.Status.ObservedKind, tt.wantKind)
+			}
+		})
+	}
+}
+
+func TestAwaitReconciler_restartObserving(t *testing.T) {
+	observerRestartDelay = 0
+	defer func() { observerRestartDelay = 10 * time.Second }()
+
+	await := newAwait("", "")
+	await.Spec.Workflow = v1alpha1.NamespacedWorkflow{}
+	await.Spec.Callback = &v1alpha1.HTTPCallback{URL: "http://example.com"}
+	await.Spec.Observer = &v1alpha1.ObserverSpec{Type: "blocking"}
+
+	observer := &blockingObserver{started: make(chan context.Context, 1), result: make(chan observers.Result, 1)}
+	r := newReconciler(t, &fakeWorkflowClient{}, await)
+	r.restarts = make(chan event.GenericEvent, 1)
+	r.Observers = observers.NewRegistry()
+	if err := r.Observers.Register("blocking", func(*v1alpha1.Await, observers.Options) (observers.Observer, error) {
+		return observer, nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	key := types.NamespacedName{Namespace: await.Namespace, Name: await.Name}
+	if _, err := r.Reconcile(ctrl.Request{NamespacedName: key}); err != nil {
+		t.Fatalf("Reconcile() error = %v", err)
+	}
+	<-observer.started
+	observer.result <- observers.Result{Err: fmt.Errorf("the watch could not be created")}
+
+	select {
+	case evt := <-r.restarts:
+		if evt.Meta.GetName() != await.Name {
+			t.Errorf("restarted %v, want %v", evt.Meta.GetName(), await.Name)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("the await has not been requeued")
+	}
+
+	got := &v1alpha1.Await{}
+	if err := r.Get(context.TODO(), key, got); err != nil {
+		t.Fatal(err)
+	}
+	if got.Status.Phase != v1alpha1.AwaitWaiting {
+		t.Errorf("Reconcile() phase = %v, want %v", got.Status.Phase, v1alpha1.AwaitWaiting)
+	}
+	if _, observed := r.observers.Load(await.UID); observed {
+		t.Error("the failed observer is still registered")
+	}
+}
@@ -0,0 +1,159 @@
+/*
+Copyright 2019 Marek Cermak <macermak@redhat.com>.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+
+	v1alpha1 "github.com/cermakm/argo-await-operator/api/v1alpha1"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/wait"
+)
+
+// SignatureHeader is the header of the callback requests
+// with the hex encoded HMAC-SHA256 signature of the payload
+const SignatureHeader = "X-Await-Signature-256"
+
+// CallbackPayload is the JSON payload of the callback requests
+type CallbackPayload struct {
+	Await         CallbackAwait          `json:"await"`
+	MatchedObject map[string]interface{} `json:"matchedObject"`
+}
+
+// CallbackAwait is the metadata of the fulfilled Await
+type CallbackAwait struct {
+	Name        string            `json:"name"`
+	Namespace   string            `json:"namespace"`
+	UID         types.UID         `json:"uid"`
+	Labels      map[string]string `json:"labels,omitempty"`
+	Annotations map[string]string `json:"annotations,omitempty"`
+}
+
+// callbackError is returned when the callback request was answered
+// with an unsuccessful status code
+type callbackError struct {
+	StatusCode int
+	Body       string
+}
+
+func (e *callbackError) Error() string {
+	return fmt.Sprintf("callback failed with status %d: %s", e.StatusCode, e.Body)
+}
+
+// retriable returns whether the request may succeed when sent again
+func (e *callbackError) retriable() bool {
+	return e.StatusCode == http.StatusTooManyRequests || e.StatusCode >= http.StatusInternalServerError
+}
+
+// sendCallback POSTs the payload describing the Await and the matched object
+// to the callback URL, signed with the key if given. Failed requests are retried
+// with the given backoff, unless the server refuses the payload.
+func sendCallback(ctx context.Context, client *http.Client, res *v1alpha1.Await, obj *unstructured.Unstructured, key []byte, backoff wait.Backoff) error {
+	payload := CallbackPayload{
+		Await: CallbackAwait{
+			Name:        res.Name,
+			Namespace:   res.Namespace,
+			UID:         res.UID,
+			Labels:      res.Labels,
+			Annotations: res.Annotations,
+		},
+		MatchedObject: obj.Object,
+	}
+
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+
+	var signature string
+	if key != nil {
+		mac := hmac.New(sha256.New, key)
+		mac.Write(data)
+		signature = "sha256=" + hex.EncodeToString(mac.Sum(nil))
+	}
+
+	var lastErr error
+	err = wait.ExponentialBackoff(backoff, func() (bool, error) {
+		req, err := http.NewRequest(http.MethodPost, res.Spec.Callback.URL, bytes.NewReader(data))
+		if err != nil {
+			return false, err
+		}
+		req = req.WithContext(ctx)
+		req.Header.Set("Content-Type", "application/json")
+		if signature != "" {
+			req.Header.Set(SignatureHeader, signature)
+		}
+
+		resp, err := client.Do(req)
+		if err != nil {
+			// The server could not be reached, try again
+			lastErr = err
+			return false, nil
+		}
+		defer resp.Body.Close()
+
+		if resp.StatusCode >= http.StatusOK && resp.StatusCode < http.StatusMultipleChoices {
+			_, _ = io.Copy(ioutil.Discard, resp.Body)
+			return true, nil
+		}
+
+		body, _ := ioutil.ReadAll(io.LimitReader(resp.Body, 1024))
+		cbErr := &callbackError{StatusCode: resp.StatusCode, Body: string(bytes.TrimSpace(body))}
+		if cbErr.retriable() {
+			lastErr = cbErr
+			return false, nil
+		}
+		return false, cbErr
+	})
+	if err == wait.ErrWaitTimeout {
+		return lastErr
+	}
+
+	return err
+}
+
+// callbackKey retrieves the key the callback payload is signed with, nil if not set
+func (r *AwaitReconciler) callbackKey(ctx context.Context, res *v1alpha1.Await) ([]byte, error) {
+	ref := res.Spec.Callback.SecretRef
+	if ref == nil {
+		return nil, nil
+	}
+
+	secret := &corev1.Secret{}
+	if err := r.APIReader.Get(ctx, types.NamespacedName{Namespace: res.Namespace, Name: ref.Name}, secret); err != nil {
+		return nil, err
+	}
+
+	key, ok := secret.Data[ref.Key]
+	if !ok {
+		return nil, fmt.Errorf("key %s not found in secret %s/%s", ref.Key, res.Namespace, ref.Name)
+	}
+
+	return key, nil
+}
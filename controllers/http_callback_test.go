@@ -0,0 +1,174 @@
+/*
+Copyright 2019 Marek Cermak <macermak@redhat.com>.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	v1alpha1 "github.com/cermakm/argo-await-operator/api/v1alpha1"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+// callbackServer is a stand-in for the receiver of the callbacks
+type callbackServer struct {
+	// failures is the number of requests failing with the failure status code
+	failures int
+	failure  int
+
+	requests   int
+	payload    CallbackPayload
+	signatures []string
+	body       []byte
+}
+
+func (s *callbackServer) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	s.requests++
+	if s.failures > 0 {
+		s.failures--
+		http.Error(w, "failure", s.failure)
+		return
+	}
+
+	s.body, _ = ioutil.ReadAll(req.Body)
+	if err := json.Unmarshal(s.body, &s.payload); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	s.signatures = append(s.signatures, req.Header.Get(SignatureHeader))
+}
+
+func TestSendCallback(t *testing.T) {
+	key := []byte("secret")
+	obj := &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": "v1",
+		"kind":       "ConfigMap",
+		"metadata":   map[string]interface{}{"name": "matched", "namespace": "default"},
+	}}
+
+	tests := []struct {
+		name         string
+		server       *callbackServer
+		key          []byte
+		wantErr      bool
+		wantRequests int
+	}{
+		{
+			name:         "Unsigned payload",
+			server:       &callbackServer{},
+			wantRequests: 1,
+		},
+		{
+			name:         "Signed payload",
+			server:       &callbackServer{},
+			key:          key,
+			wantRequests: 1,
+		},
+		{
+			name:         "Retries unavailable server",
+			server:       &callbackServer{failures: 2, failure: http.StatusServiceUnavailable},
+			wantRequests: 3,
+		},
+		{
+			name:         "Refused payload is not retried",
+			server:       &callbackServer{failures: 1, failure: http.StatusBadRequest},
+			wantErr:      true,
+			wantRequests: 1,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewServer(tt.server)
+			defer server.Close()
+
+			res := newAwait(v1alpha1.AwaitWaiting, "")
+			res.Spec.Callback = &v1alpha1.HTTPCallback{URL: server.URL}
+
+			err := sendCallback(context.TODO(), server.Client(), res, obj, tt.key, testBackoff)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("sendCallback() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.server.requests != tt.wantRequests {
+				t.Errorf("sendCallback() requests = %v, want %v", tt.server.requests, tt.wantRequests)
+			}
+			if tt.wantErr {
+				return
+			}
+
+			if tt.server.payload.Await.Name != res.Name || tt.server.payload.Await.UID != res.UID {
+				t.Errorf("sendCallback() await = %v, want %v", tt.server.payload.Await, res.ObjectMeta)
+			}
+			matched := unstructured.Unstructured{Object: tt.server.payload.MatchedObject}
+			if matched.GetName() != obj.GetName() {
+				t.Errorf("sendCallback() matched object = %v, want %v", matched.GetName(), obj.GetName())
+			}
+
+			want := ""
+			if tt.key != nil {
+				mac := hmac.New(sha256.New, tt.key)
+				mac.Write(tt.server.body)
+				want = "sha256=" + hex.EncodeToString(mac.Sum(nil))
+			}
+			if got := tt.server.signatures[0]; got != want {
+				t.Errorf("sendCallback() signature = %v, want %v", got, want)
+			}
+		})
+	}
+}
+
+func TestAwaitReconciler_callbackKey(t *testing.T) {
+	scheme := runtime.NewScheme()
+	if err := corev1.AddToScheme(scheme); err != nil {
+		t.Fatal(err)
+	}
+	secret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: "hmac", Namespace: "default"},
+		Data:       map[string][]byte{"key": []byte("secret")},
+	}
+	r := &AwaitReconciler{APIReader: fake.NewFakeClientWithScheme(scheme, secret)}
+
+	res := newAwait(v1alpha1.AwaitWaiting, "")
+	res.Spec.Callback = &v1alpha1.HTTPCallback{URL: "https://example.com"}
+	if key, err := r.callbackKey(context.TODO(), res); err != nil || key != nil {
+		t.Errorf("callbackKey() = %v, %v, want no key", key, err)
+	}
+
+	res.Spec.Callback.SecretRef = &corev1.SecretKeySelector{
+		LocalObjectReference: corev1.LocalObjectReference{Name: "hmac"},
+		Key:                  "key",
+	}
+	if key, err := r.callbackKey(context.TODO(), res); err != nil || string(key) != "secret" {
+		t.Errorf("callbackKey() = %v, %v, want %v", string(key), err, "secret")
+	}
+
+	res.Spec.Callback.SecretRef.Key = "missing"
+	if _, err := r.callbackKey(context.TODO(), res); err == nil {
+		t.Errorf("callbackKey() expected an error for a missing key")
+	}
+}
@@ -0,0 +1,57 @@
+/*
+Copyright 2019 Marek Cermak <macermak@redhat.com>.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/wait"
+)
+
+// The kinds of the Resumers
+const (
+	// ResumerPatch updates the Workflow resources directly
+	ResumerPatch = "patch"
+	// ResumerArgoServer uses the resume endpoint of the Argo Server
+	ResumerArgoServer = "argo-server"
+)
+
+// Resumer resumes the suspended Workflows
+type Resumer interface {
+	// Resume resumes the Workflow suspended at the given node, an empty node ID
+	// stands for the suspension of the whole Workflow. The outputs are set as
+	// the output parameters of the node, if supported. If the Workflow is no longer
+	// suspended at the node, *ResumeSkipped is returned.
+	Resume(ctx context.Context, key types.NamespacedName, nodeID string, outputs map[string]string) error
+}
+
+// patchResumer resumes the Workflows by updating the Workflow resources directly
+type patchResumer struct {
+	workflows WorkflowClient
+	backoff   wait.Backoff
+}
+
+// NewPatchResumer creates a Resumer updating the Workflow resources directly,
+// transient errors are retried with the given backoff
+func NewPatchResumer(workflows WorkflowClient, backoff wait.Backoff) Resumer {
+	return &patchResumer{workflows: workflows, backoff: backoff}
+}
+
+func (r *patchResumer) Resume(ctx context.Context, key types.NamespacedName, nodeID string, outputs map[string]string) error {
+	return resumeWorkflowNode(ctx, r.workflows, key, nodeID, outputs, r.backoff)
+}
@@ -0,0 +1,102 @@
+/*
+Copyright 2019 Marek Cermak <macermak@redhat.com>.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	v1alpha1 "github.com/cermakm/argo-await-operator/api/v1alpha1"
+	"github.com/cermakm/argo-await-operator/observers/schedule"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+
+	ctrl "sigs.k8s.io/controller-runtime"
+)
+
+// newSchedule creates the Schedule of the Await
+func newSchedule(s *v1alpha1.Schedule) (schedule.Schedule, error) {
+	if s.At != nil {
+		return schedule.At(s.At.Time), nil
+	}
+
+	return schedule.Cron(s.Cron, s.TimeZone)
+}
+
+// describeSchedule returns a human readable description of the Schedule
+func describeSchedule(s *v1alpha1.Schedule) string {
+	if s.At != nil {
+		return s.At.UTC().Format(time.RFC3339)
+	}
+
+	timeZone := s.TimeZone
+	if timeZone == "" {
+		timeZone = schedule.DefaultTimeZone
+	}
+	return fmt.Sprintf("cron schedule %q in %s", s.Cron, timeZone)
+}
+
+// awaitSchedule requeues the waiting Await until its Schedule is due and then
+// fulfills it, the target is resumed by the next reconciliation as usual
+func (r *AwaitReconciler) awaitSchedule(ctx context.Context, res *v1alpha1.Await, target runtime.Object) (ctrl.Result, error) {
+	key := types.NamespacedName{Namespace: res.Namespace, Name: res.Name}
+
+	s, err := newSchedule(res.Spec.Schedule)
+	if err != nil {
+		r.Recorder.Eventf(res, corev1.EventTypeWarning, ReasonInvalidSchedule,
+			"Schedule could not be parsed: %v", err)
+
+		// The schedule can never be due, don't requeue
+		return ctrl.Result{}, r.updateStatus(key, func(status *v1alpha1.AwaitStatus) {
+			status.Phase = v1alpha1.AwaitFailed
+			status.FinishedAt = metav1.Now()
+			status.Message = err.Error()
+			observeDuration(res, status)
+		})
+	}
+
+	// The start time is truncated the same way it is persisted
+	// so that the schedule is stable across the reconciliations
+	startedAt := res.Status.StartedAt.Rfc3339Copy().Time
+	next := s.Next(startedAt)
+	if next.IsZero() {
+		r.Recorder.Event(res, corev1.EventTypeWarning, ReasonInvalidSchedule, "Schedule is never due")
+
+		// The zero time is not due at once but never, don't requeue
+		return ctrl.Result{}, r.updateStatus(key, func(status *v1alpha1.AwaitStatus) {
+			status.Phase = v1alpha1.AwaitFailed
+			status.FinishedAt = metav1.Now()
+			status.Message = "the schedule is never due"
+			observeDuration(res, status)
+		})
+	}
+	if remaining := schedule.Remaining(s, startedAt, time.Now()); remaining > 0 {
+		return ctrl.Result{RequeueAfter: remaining}, nil
+	}
+
+	target = r.eventTarget(ctx, res, target)
+
+	obj := &unstructured.Unstructured{Object: map[string]interface{}{
+		"scheduledAt": next.UTC().Format(time.RFC3339),
+	}}
+	return ctrl.Result{}, r.awaitFulfilledCallback(res.DeepCopy(), target)(ctx, obj)
+}
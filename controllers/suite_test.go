@@ -24,6 +24,7 @@ import (
 	. "github.com/onsi/gomega"
 
 	awaitv1alpha1 "github.com/cermakm/argo-await-operator/api/v1alpha1"
+	awaitv1beta1 "github.com/cermakm/argo-await-operator/api/v1beta1"
 	"k8s.io/client-go/kubernetes/scheme"
 	"k8s.io/client-go/rest"
 	"sigs.k8s.io/controller-runtime/pkg/client"
@@ -64,6 +65,9 @@ var _ = BeforeSuite(func(done Done) {
 	err = awaitv1alpha1.AddToScheme(scheme.Scheme)
 	Expect(err).NotTo(HaveOccurred())
 
+	err = awaitv1beta1.AddToScheme(scheme.Scheme)
+	Expect(err).NotTo(HaveOccurred())
+
 	// +kubebuilder:scaffold:scheme
 
 	k8sClient, err = client.New(cfg, client.Options{Scheme: scheme.Scheme})
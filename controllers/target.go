@@ -0,0 +1,198 @@
+/*
+Copyright 2019 Marek Cermak <macermak@redhat.com>.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+
+	workflowv1alpha1 "github.com/argoproj/argo/pkg/apis/workflow/v1alpha1"
+	workflowutil "github.com/argoproj/argo/workflow/util"
+
+	v1alpha1 "github.com/cermakm/argo-await-operator/api/v1alpha1"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/dynamic"
+)
+
+// pipelineRunPending is the status of a Tekton PipelineRun which has not been started yet
+const pipelineRunPending = "PipelineRunPending"
+
+// TargetAction resumes the objects of a single kind
+type TargetAction interface {
+	// Get retrieves the object to be resumed
+	Get(ctx context.Context, target v1alpha1.Target) (runtime.Object, error)
+
+	// Suspended returns whether the object is suspended together with the point of
+	// the suspension the Await is created for, e.g. the Workflow suspend node
+	Suspended(obj runtime.Object) (bool, string)
+
+	// Resume resumes the object suspended at the given point passing it the outputs
+	// of the awaited Workflow, if supported. *ResumeSkipped is returned
+	// if it is no longer suspended there.
+	Resume(ctx context.Context, target v1alpha1.Target, point string, outputs map[string]string) error
+}
+
+// NewTargetActions creates the actions for all the supported kinds of targets
+func NewTargetActions(workflows WorkflowClient, resumer Resumer, client dynamic.Interface, backoff wait.Backoff) map[string]TargetAction {
+	return map[string]TargetAction{
+		v1alpha1.TargetWorkflow: NewWorkflowAction(workflows, resumer),
+		v1alpha1.TargetCronWorkflow: &unstructuredAction{
+			client:   client,
+			resource: schema.GroupVersionResource{Group: "argoproj.io", Version: "v1alpha1", Resource: "cronworkflows"},
+			backoff:  backoff,
+			suspended: func(obj *unstructured.Unstructured) bool {
+				suspend, _, _ := unstructured.NestedBool(obj.Object, "spec", "suspend")
+				return suspend
+			},
+			resume: func(obj *unstructured.Unstructured) error {
+				return unstructured.SetNestedField(obj.Object, false, "spec", "suspend")
+			},
+		},
+		v1alpha1.TargetPipelineRun: &unstructuredAction{
+			client:   client,
+			resource: schema.GroupVersionResource{Group: "tekton.dev", Version: "v1beta1", Resource: "pipelineruns"},
+			backoff:  backoff,
+			suspended: func(obj *unstructured.Unstructured) bool {
+				status, _, _ := unstructured.NestedString(obj.Object, "spec", "status")
+				return status == pipelineRunPending
+			},
+			resume: func(obj *unstructured.Unstructured) error {
+				unstructured.RemoveNestedField(obj.Object, "spec", "status")
+				return nil
+			},
+		},
+		v1alpha1.TargetDeployment: &unstructuredAction{
+			client:   client,
+			resource: schema.GroupVersionResource{Group: "apps", Version: "v1", Resource: "deployments"},
+			backoff:  backoff,
+			suspended: func(obj *unstructured.Unstructured) bool {
+				paused, _, _ := unstructured.NestedBool(obj.Object, "spec", "paused")
+				return paused
+			},
+			resume: func(obj *unstructured.Unstructured) error {
+				return unstructured.SetNestedField(obj.Object, false, "spec", "paused")
+			},
+		},
+		v1alpha1.TargetJob: &unstructuredAction{
+			client:   client,
+			resource: schema.GroupVersionResource{Group: "batch", Version: "v1", Resource: "jobs"},
+			backoff:  backoff,
+			suspended: func(obj *unstructured.Unstructured) bool {
+				suspend, _, _ := unstructured.NestedBool(obj.Object, "spec", "suspend")
+				return suspend
+			},
+			resume: func(obj *unstructured.Unstructured) error {
+				return unstructured.SetNestedField(obj.Object, false, "spec", "suspend")
+			},
+		},
+	}
+}
+
+// workflowAction resumes the Argo Workflows
+type workflowAction struct {
+	workflows WorkflowClient
+	resumer   Resumer
+}
+
+// NewWorkflowAction creates a TargetAction reading the Workflows
+// using the WorkflowClient and resuming them using the Resumer
+func NewWorkflowAction(workflows WorkflowClient, resumer Resumer) TargetAction {
+	return &workflowAction{workflows: workflows, resumer: resumer}
+}
+
+func (a *workflowAction) Get(ctx context.Context, target v1alpha1.Target) (runtime.Object, error) {
+	return a.workflows.Get(ctx, targetKey(target))
+}
+
+func (a *workflowAction) Suspended(obj runtime.Object) (bool, string) {
+	wf := obj.(*workflowv1alpha1.Workflow)
+	if !workflowutil.IsWorkflowSuspended(wf) {
+		return false, ""
+	}
+
+	return true, suspendedNode(wf)
+}
+
+func (a *workflowAction) Resume(ctx context.Context, target v1alpha1.Target, point string, outputs map[string]string) error {
+	return a.resumer.Resume(ctx, targetKey(target), point, outputs)
+}
+
+// unstructuredAction resumes the objects of a kind by updating a field of the object
+type unstructuredAction struct {
+	client   dynamic.Interface
+	resource schema.GroupVersionResource
+	backoff  wait.Backoff
+
+	// suspended returns whether the object is suspended
+	suspended func(obj *unstructured.Unstructured) bool
+	// resume modifies the object so that it is resumed
+	resume func(obj *unstructured.Unstructured) error
+}
+
+func (a *unstructuredAction) Get(ctx context.Context, target v1alpha1.Target) (runtime.Object, error) {
+	return a.client.Resource(a.resource).Namespace(target.Namespace).Get(target.Name, metav1.GetOptions{})
+}
+
+func (a *unstructuredAction) Suspended(obj runtime.Object) (bool, string) {
+	return a.suspended(obj.(*unstructured.Unstructured)), ""
+}
+
+func (a *unstructuredAction) Resume(ctx context.Context, target v1alpha1.Target, point string, outputs map[string]string) error {
+	objects := a.client.Resource(a.resource).Namespace(target.Namespace)
+
+	var lastErr error
+	err := wait.ExponentialBackoff(a.backoff, func() (bool, error) {
+		obj, err := objects.Get(target.Name, metav1.GetOptions{})
+		if err != nil {
+			lastErr = err
+			return false, retriableOrNil(err)
+		}
+
+		if !a.suspended(obj) {
+			return false, &ResumeSkipped{
+				Reason:  ReasonTargetNotSuspended,
+				Message: fmt.Sprintf("%s %s/%s is not suspended", target.Kind, target.Namespace, target.Name),
+			}
+		}
+		if err := a.resume(obj); err != nil {
+			return false, err
+		}
+
+		if _, err := objects.Update(obj, metav1.UpdateOptions{}); err != nil {
+			lastErr = err
+			return false, retriableOrNil(err)
+		}
+
+		return true, nil
+	})
+	if err == wait.ErrWaitTimeout {
+		return lastErr
+	}
+
+	return err
+}
+
+// targetKey returns the namespaced name of the target
+func targetKey(target v1alpha1.Target) types.NamespacedName {
+	return types.NamespacedName{Namespace: target.Namespace, Name: target.Name}
+}
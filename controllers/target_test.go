@@ -0,0 +1,125 @@
+/*
+Copyright 2019 Marek Cermak <macermak@redhat.com>.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"testing"
+
+	v1alpha1 "github.com/cermakm/argo-await-operator/api/v1alpha1"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/dynamic/fake"
+)
+
+func newObject(apiVersion, kind string, spec map[string]interface{}) *unstructured.Unstructured {
+	return &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": apiVersion,
+		"kind":       kind,
+		"metadata": map[string]interface{}{
+			"name":      "target",
+			"namespace": "default",
+		},
+		"spec": spec,
+	}}
+}
+
+func TestTargetActions(t *testing.T) {
+	tests := []struct {
+		name       string
+		obj        *unstructured.Unstructured
+		suspended  bool
+		wantErr    bool
+		wantField  []string
+		wantResult interface{}
+	}{
+		{
+			name:       "Paused Deployment",
+			obj:        newObject("apps/v1", v1alpha1.TargetDeployment, map[string]interface{}{"paused": true}),
+			suspended:  true,
+			wantField:  []string{"spec", "paused"},
+			wantResult: false,
+		},
+		{
+			name:       "Suspended Job",
+			obj:        newObject("batch/v1", v1alpha1.TargetJob, map[string]interface{}{"suspend": true}),
+			suspended:  true,
+			wantField:  []string{"spec", "suspend"},
+			wantResult: false,
+		},
+		{
+			name:       "Suspended CronWorkflow",
+			obj:        newObject("argoproj.io/v1alpha1", v1alpha1.TargetCronWorkflow, map[string]interface{}{"suspend": true}),
+			suspended:  true,
+			wantField:  []string{"spec", "suspend"},
+			wantResult: false,
+		},
+		{
+			name:       "Pending PipelineRun",
+			obj:        newObject("tekton.dev/v1beta1", v1alpha1.TargetPipelineRun, map[string]interface{}{"status": pipelineRunPending}),
+			suspended:  true,
+			wantField:  []string{"spec", "status"},
+			wantResult: nil,
+		},
+		{
+			name:      "Running Job",
+			obj:       newObject("batch/v1", v1alpha1.TargetJob, map[string]interface{}{}),
+			suspended: false,
+			wantErr:   true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := fake.NewSimpleDynamicClient(runtime.NewScheme(), tt.obj)
+			actions := NewTargetActions(nil, nil, client, testBackoff)
+
+			target := v1alpha1.Target{Kind: tt.obj.GetKind(), Name: tt.obj.GetName(), Namespace: tt.obj.GetNamespace()}
+			action := actions[target.Kind]
+
+			obj, err := action.Get(context.TODO(), target)
+			if err != nil {
+				t.Fatalf("Get() error = %v", err)
+			}
+			if suspended, _ := action.Suspended(obj); suspended != tt.suspended {
+				t.Errorf("Suspended() = %v, want %v", suspended, tt.suspended)
+			}
+
+			err = action.Resume(context.TODO(), target, "", nil)
+			if tt.wantErr {
+				if _, ok := err.(*ResumeSkipped); !ok {
+					t.Errorf("Resume() error = %v, want skipped", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Resume() error = %v", err)
+			}
+
+			resumed, err := client.Resource(action.(*unstructuredAction).resource).
+				Namespace(target.Namespace).Get(target.Name, metav1.GetOptions{})
+			if err != nil {
+				t.Fatal(err)
+			}
+			got, _, _ := unstructured.NestedFieldNoCopy(resumed.Object, tt.wantField...)
+			if got != tt.wantResult {
+				t.Errorf("Resume() %v = %v, want %v", tt.wantField, got, tt.wantResult)
+			}
+		})
+	}
+}
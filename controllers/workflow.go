@@ -0,0 +1,170 @@
+/*
+Copyright 2019 Marek Cermak <macermak@redhat.com>.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	workflowv1alpha1 "github.com/argoproj/argo/pkg/apis/workflow/v1alpha1"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/wait"
+)
+
+// ResumeSkipped is returned when the Workflow is no longer in the state
+// the Await was created for and must not be resumed
+type ResumeSkipped struct {
+	Reason  string
+	Message string
+}
+
+func (s *ResumeSkipped) Error() string {
+	return s.Message
+}
+
+// suspendedNode returns the ID of the running suspend node of the Workflow,
+// empty if the whole Workflow has been suspended
+func suspendedNode(wf *workflowv1alpha1.Workflow) string {
+	if wf.Spec.Suspend != nil && *wf.Spec.Suspend {
+		return ""
+	}
+
+	// Parallel suspend nodes are ordered to pick the same node every time
+	var nodes []string
+	for id, node := range wf.Status.Nodes {
+		if node.Type == workflowv1alpha1.NodeTypeSuspend && node.Phase == workflowv1alpha1.NodeRunning {
+			nodes = append(nodes, id)
+		}
+	}
+	if len(nodes) == 0 {
+		return ""
+	}
+	sort.Strings(nodes)
+
+	return nodes[0]
+}
+
+// checkResumable verifies that the Workflow is still suspended at the given node,
+// an empty node ID stands for the suspension of the whole Workflow
+func checkResumable(wf *workflowv1alpha1.Workflow, nodeID string) *ResumeSkipped {
+	if wf.Status.Completed() {
+		return &ResumeSkipped{
+			Reason:  ReasonWorkflowCompleted,
+			Message: fmt.Sprintf("Workflow %s/%s has already completed", wf.Namespace, wf.Name),
+		}
+	}
+
+	if nodeID == "" {
+		if wf.Spec.Suspend == nil || !*wf.Spec.Suspend {
+			return &ResumeSkipped{
+				Reason:  ReasonWorkflowNotSuspended,
+				Message: fmt.Sprintf("Workflow %s/%s is not suspended", wf.Namespace, wf.Name),
+			}
+		}
+		return nil
+	}
+
+	node, ok := wf.Status.Nodes[nodeID]
+	if ok && node.Type == workflowv1alpha1.NodeTypeSuspend && node.Phase == workflowv1alpha1.NodeRunning {
+		return nil
+	}
+
+	if current := suspendedNode(wf); current != "" || (wf.Spec.Suspend != nil && *wf.Spec.Suspend) {
+		return &ResumeSkipped{
+			Reason: ReasonSuspendNodeChanged,
+			Message: fmt.Sprintf("Workflow %s/%s is suspended at a different node than %s",
+				wf.Namespace, wf.Name, nodeID),
+		}
+	}
+
+	return &ResumeSkipped{
+		Reason:  ReasonWorkflowNotSuspended,
+		Message: fmt.Sprintf("Workflow %s/%s is no longer suspended at node %s", wf.Namespace, wf.Name, nodeID),
+	}
+}
+
+// resumeWorkflowNode resumes the Workflow suspended at the given node
+// and sets the outputs as its output parameters. The Workflow is re-fetched on every attempt and verified to still be suspended
+// at the node, otherwise *ResumeSkipped is returned. Transient errors are retried
+// with the given backoff, the last one is returned when the retries are exhausted.
+func resumeWorkflowNode(ctx context.Context, workflows WorkflowClient, key types.NamespacedName, nodeID string, outputs map[string]string, backoff wait.Backoff) error {
+	var lastErr error
+	err := wait.ExponentialBackoff(backoff, func() (bool, error) {
+		wf, err := workflows.Get(ctx, key)
+		if err != nil {
+			lastErr = err
+			return false, retriableOrNil(err)
+		}
+
+		if skipped := checkResumable(wf, nodeID); skipped != nil {
+			return false, skipped
+		}
+
+		if nodeID == "" {
+			wf.Spec.Suspend = nil
+		} else {
+			// To resume a suspended node, it is simply marked as successful
+			node := wf.Status.Nodes[nodeID]
+			node.Phase = workflowv1alpha1.NodeSucceeded
+			node.FinishedAt = metav1.Now()
+			if len(outputs) > 0 {
+				node.Outputs = outputParameters(outputs)
+			}
+			wf.Status.Nodes[nodeID] = node
+		}
+
+		if _, err := workflows.Update(ctx, wf); err != nil {
+			lastErr = err
+			return false, retriableOrNil(err)
+		}
+
+		return true, nil
+	})
+	if err == wait.ErrWaitTimeout {
+		return lastErr
+	}
+
+	return err
+}
+
+// outputParameters returns the outputs as the output parameters of a node
+func outputParameters(outputs map[string]string) *workflowv1alpha1.Outputs {
+	names := make([]string, 0, len(outputs))
+	for name := range outputs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	params := make([]workflowv1alpha1.Parameter, 0, len(names))
+	for _, name := range names {
+		value := outputs[name]
+		params = append(params, workflowv1alpha1.Parameter{Name: name, Value: &value})
+	}
+
+	return &workflowv1alpha1.Outputs{Parameters: params}
+}
+
+// retriableOrNil returns nil for the errors which should be retried
+func retriableOrNil(err error) error {
+	if isRetriable(err) {
+		return nil
+	}
+	return err
+}
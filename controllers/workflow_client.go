@@ -0,0 +1,85 @@
+/*
+Copyright 2019 Marek Cermak <macermak@redhat.com>.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+
+	workflowv1alpha1 "github.com/argoproj/argo/pkg/apis/workflow/v1alpha1"
+	argoprojv1alpha1 "github.com/argoproj/argo/pkg/client/clientset/versioned/typed/workflow/v1alpha1"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/rest"
+
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// WorkflowClient reads and updates the Argo Workflows
+type WorkflowClient interface {
+	// Get retrieves the Workflow with the given namespace and name
+	Get(ctx context.Context, key types.NamespacedName) (*workflowv1alpha1.Workflow, error)
+
+	// Update updates the given Workflow
+	Update(ctx context.Context, wf *workflowv1alpha1.Workflow) (*workflowv1alpha1.Workflow, error)
+}
+
+// workflowClient is a WorkflowClient talking directly to the API server
+type workflowClient struct {
+	clientset argoprojv1alpha1.ArgoprojV1alpha1Interface
+}
+
+// NewWorkflowClient creates a WorkflowClient talking directly to the API server
+func NewWorkflowClient(config *rest.Config) (WorkflowClient, error) {
+	clientset, err := argoprojv1alpha1.NewForConfig(config)
+	if err != nil {
+		return nil, err
+	}
+
+	return &workflowClient{clientset: clientset}, nil
+}
+
+func (c *workflowClient) Get(ctx context.Context, key types.NamespacedName) (*workflowv1alpha1.Workflow, error) {
+	return c.clientset.Workflows(key.Namespace).Get(key.Name, metav1.GetOptions{})
+}
+
+func (c *workflowClient) Update(ctx context.Context, wf *workflowv1alpha1.Workflow) (*workflowv1alpha1.Workflow, error) {
+	return c.clientset.Workflows(wf.Namespace).Update(wf)
+}
+
+// cachedWorkflowClient reads the Workflows from a cache
+// and updates them using the underlying WorkflowClient
+type cachedWorkflowClient struct {
+	WorkflowClient
+
+	reader client.Reader
+}
+
+// NewCachedWorkflowClient creates a WorkflowClient reading the Workflows from the given reader,
+// typically the cache of the manager, and updating them using the given WorkflowClient
+func NewCachedWorkflowClient(reader client.Reader, c WorkflowClient) WorkflowClient {
+	return &cachedWorkflowClient{WorkflowClient: c, reader: reader}
+}
+
+func (c *cachedWorkflowClient) Get(ctx context.Context, key types.NamespacedName) (*workflowv1alpha1.Workflow, error) {
+	wf := &workflowv1alpha1.Workflow{}
+	if err := c.reader.Get(ctx, key, wf); err != nil {
+		return nil, err
+	}
+
+	return wf, nil
+}
@@ -0,0 +1,249 @@
+/*
+Copyright 2019 Marek Cermak <macermak@redhat.com>.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	workflowv1alpha1 "github.com/argoproj/argo/pkg/apis/workflow/v1alpha1"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/wait"
+)
+
+var testBackoff = wait.Backoff{Steps: 3, Duration: time.Millisecond, Factor: 1.0}
+
+// fakeWorkflowClient stores a single Workflow, updates fail with the errors
+// returned by updateErr until it returns nil
+type fakeWorkflowClient struct {
+	workflow  *workflowv1alpha1.Workflow
+	updateErr func() error
+}
+
+func (f *fakeWorkflowClient) Get(ctx context.Context, key types.NamespacedName) (*workflowv1alpha1.Workflow, error) {
+	if f.workflow == nil || f.workflow.Namespace != key.Namespace || f.workflow.Name != key.Name {
+		return nil, apierrors.NewNotFound(workflowsResource, key.Name)
+	}
+	return f.workflow.DeepCopy(), nil
+}
+
+func (f *fakeWorkflowClient) Update(ctx context.Context, wf *workflowv1alpha1.Workflow) (*workflowv1alpha1.Workflow, error) {
+	if f.updateErr != nil {
+		if err := f.updateErr(); err != nil {
+			return nil, err
+		}
+	}
+	f.workflow = wf.DeepCopy()
+	return wf, nil
+}
+
+func namespacedName(wf *workflowv1alpha1.Workflow) types.NamespacedName {
+	return types.NamespacedName{Namespace: wf.Namespace, Name: wf.Name}
+}
+
+func newWorkflow(suspend bool, phase workflowv1alpha1.NodePhase, nodes map[string]workflowv1alpha1.NodePhase) *workflowv1alpha1.Workflow {
+	wf := &workflowv1alpha1.Workflow{
+		ObjectMeta: metav1.ObjectMeta{Name: "workflow", Namespace: "default"},
+		Status: workflowv1alpha1.WorkflowStatus{
+			Phase: phase,
+			Nodes: map[string]workflowv1alpha1.NodeStatus{},
+		},
+	}
+	if suspend {
+		wf.Spec.Suspend = &suspend
+	}
+	for id, nodePhase := range nodes {
+		wf.Status.Nodes[id] = workflowv1alpha1.NodeStatus{
+			ID:    id,
+			Type:  workflowv1alpha1.NodeTypeSuspend,
+			Phase: nodePhase,
+		}
+	}
+
+	return wf
+}
+
+func Test_suspendedNode(t *testing.T) {
+	tests := []struct {
+		name string
+		wf   *workflowv1alpha1.Workflow
+		want string
+	}{
+		{
+			name: "Suspended workflow",
+			wf:   newWorkflow(true, workflowv1alpha1.NodeRunning, nil),
+			want: "",
+		},
+		{
+			name: "Suspended node",
+			wf: newWorkflow(false, workflowv1alpha1.NodeRunning, map[string]workflowv1alpha1.NodePhase{
+				"first":  workflowv1alpha1.NodeSucceeded,
+				"second": workflowv1alpha1.NodeRunning,
+			}),
+			want: "second",
+		},
+		{
+			name: "Parallel suspended nodes",
+			wf: newWorkflow(false, workflowv1alpha1.NodeRunning, map[string]workflowv1alpha1.NodePhase{
+				"b": workflowv1alpha1.NodeRunning,
+				"a": workflowv1alpha1.NodeRunning,
+			}),
+			want: "a",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := suspendedNode(tt.wf); got != tt.want {
+				t.Errorf("suspendedNode() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_checkResumable(t *testing.T) {
+	tests := []struct {
+		name   string
+		wf     *workflowv1alpha1.Workflow
+		nodeID string
+		want   string
+	}{
+		{
+			name:   "Suspended workflow",
+			wf:     newWorkflow(true, workflowv1alpha1.NodeRunning, nil),
+			nodeID: "",
+			want:   "",
+		},
+		{
+			name:   "Suspended node",
+			wf:     newWorkflow(false, workflowv1alpha1.NodeRunning, map[string]workflowv1alpha1.NodePhase{"node": workflowv1alpha1.NodeRunning}),
+			nodeID: "node",
+			want:   "",
+		},
+		{
+			name:   "Completed workflow",
+			wf:     newWorkflow(false, workflowv1alpha1.NodeSucceeded, map[string]workflowv1alpha1.NodePhase{"node": workflowv1alpha1.NodeSucceeded}),
+			nodeID: "node",
+			want:   ReasonWorkflowCompleted,
+		},
+		{
+			name:   "Resumed workflow",
+			wf:     newWorkflow(false, workflowv1alpha1.NodeRunning, nil),
+			nodeID: "",
+			want:   ReasonWorkflowNotSuspended,
+		},
+		{
+			name:   "Resumed node",
+			wf:     newWorkflow(false, workflowv1alpha1.NodeRunning, map[string]workflowv1alpha1.NodePhase{"node": workflowv1alpha1.NodeSucceeded}),
+			nodeID: "node",
+			want:   ReasonWorkflowNotSuspended,
+		},
+		{
+			name: "Suspended at a later node",
+			wf: newWorkflow(false, workflowv1alpha1.NodeRunning, map[string]workflowv1alpha1.NodePhase{
+				"node":  workflowv1alpha1.NodeSucceeded,
+				"later": workflowv1alpha1.NodeRunning,
+			}),
+			nodeID: "node",
+			want:   ReasonSuspendNodeChanged,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := ""
+			if skipped := checkResumable(tt.wf, tt.nodeID); skipped != nil {
+				got = skipped.Reason
+			}
+			if got != tt.want {
+				t.Errorf("checkResumable() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_resumeWorkflowNode(t *testing.T) {
+	t.Run("Resumes the node after a conflict", func(t *testing.T) {
+		wf := newWorkflow(false, workflowv1alpha1.NodeRunning, map[string]workflowv1alpha1.NodePhase{
+			"node":     workflowv1alpha1.NodeRunning,
+			"parallel": workflowv1alpha1.NodeRunning,
+		})
+		conflicts := 1
+		workflows := &fakeWorkflowClient{workflow: wf, updateErr: func() error {
+			if conflicts > 0 {
+				conflicts--
+				return apierrors.NewConflict(workflowsResource, wf.Name, nil)
+			}
+			return nil
+		}}
+
+		if err := resumeWorkflowNode(context.TODO(), workflows, namespacedName(wf), "node", nil, testBackoff); err != nil {
+			t.Fatalf("resumeWorkflowNode() error = %v", err)
+		}
+
+		got := workflows.workflow
+		if phase := got.Status.Nodes["node"].Phase; phase != workflowv1alpha1.NodeSucceeded {
+			t.Errorf("node phase = %v, want %v", phase, workflowv1alpha1.NodeSucceeded)
+		}
+		if phase := got.Status.Nodes["parallel"].Phase; phase != workflowv1alpha1.NodeRunning {
+			t.Errorf("parallel node phase = %v, want %v", phase, workflowv1alpha1.NodeRunning)
+		}
+	})
+
+	t.Run("Sets the outputs of the node", func(t *testing.T) {
+		wf := newWorkflow(false, workflowv1alpha1.NodeRunning, map[string]workflowv1alpha1.NodePhase{
+			"node": workflowv1alpha1.NodeRunning,
+		})
+		workflows := &fakeWorkflowClient{workflow: wf}
+
+		outputs := map[string]string{"version": "1.2.3", "image": "app:1.2.3"}
+		if err := resumeWorkflowNode(context.TODO(), workflows, namespacedName(wf), "node", outputs, testBackoff); err != nil {
+			t.Fatalf("resumeWorkflowNode() error = %v", err)
+		}
+
+		params := workflows.workflow.Status.Nodes["node"].Outputs.Parameters
+		if len(params) != 2 || params[0].Name != "image" || *params[1].Value != "1.2.3" {
+			t.Errorf("node outputs = %v, want %v", params, outputs)
+		}
+	})
+
+	t.Run("Skips a completed workflow", func(t *testing.T) {
+		wf := newWorkflow(false, workflowv1alpha1.NodeFailed, map[string]workflowv1alpha1.NodePhase{
+			"node": workflowv1alpha1.NodeFailed,
+		})
+		workflows := &fakeWorkflowClient{workflow: wf}
+
+		err := resumeWorkflowNode(context.TODO(), workflows, namespacedName(wf), "node", nil, testBackoff)
+		if skipped, ok := err.(*ResumeSkipped); !ok || skipped.Reason != ReasonWorkflowCompleted {
+			t.Errorf("resumeWorkflowNode() error = %v, want %v", err, ReasonWorkflowCompleted)
+		}
+	})
+
+	t.Run("Returns the last transient error", func(t *testing.T) {
+		wf := newWorkflow(true, workflowv1alpha1.NodeRunning, nil)
+		workflows := &fakeWorkflowClient{workflow: wf, updateErr: func() error {
+			return apierrors.NewServiceUnavailable("unavailable")
+		}}
+
+		err := resumeWorkflowNode(context.TODO(), workflows, namespacedName(wf), "", nil, testBackoff)
+		if !apierrors.IsServiceUnavailable(err) {
+			t.Errorf("resumeWorkflowNode() error = %v, want service unavailable", err)
+		}
+	})
+}
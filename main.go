@@ -17,17 +17,33 @@ limitations under the License.
 package main
 
 import (
+	"context"
+	"crypto/tls"
 	"flag"
+	"net/http"
 	"os"
+	"time"
+
+	workflowv1alpha1 "github.com/argoproj/argo/pkg/apis/workflow/v1alpha1"
 
 	v1alpha1 "github.com/cermakm/argo-await-operator/api/v1alpha1"
+	v1beta1 "github.com/cermakm/argo-await-operator/api/v1beta1"
+	"github.com/cermakm/argo-await-operator/common"
 	"github.com/cermakm/argo-await-operator/controllers"
+	"github.com/cermakm/argo-await-operator/metrics"
+	"github.com/cermakm/argo-await-operator/observers"
+	"github.com/cermakm/argo-await-operator/observers/cloudevents"
+	"github.com/cermakm/argo-await-operator/observers/plugin"
+	"github.com/cermakm/argo-await-operator/tracing"
 
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/dynamic"
 	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
 	_ "k8s.io/client-go/plugin/pkg/client/auth/gcp"
 	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/log/zap"
+	crmetrics "sigs.k8s.io/controller-runtime/pkg/metrics"
 	// +kubebuilder:scaffold:imports
 )
 
@@ -41,19 +57,59 @@ func init() {
 
 	// Await API scheme
 	_ = v1alpha1.AddToScheme(scheme)
+	_ = v1beta1.AddToScheme(scheme)
+
+	// Argo Workflow API scheme
+	_ = workflowv1alpha1.AddToScheme(scheme)
 	// +kubebuilder:scaffold:scheme
 }
 
 func main() {
 	var metricsAddr string
 	var enableLeaderElection bool
+	var workflowEvents bool
+	var cacheWorkflows bool
+	var resumer, argoServerURL string
+	var argoServerInsecure bool
+	var tracingExporter, tracingEndpoint string
+	var eventsAddr, eventsAuth string
+	var pluginSocketDir, pluginConfigMap string
 	flag.StringVar(&metricsAddr, "metrics-addr", ":8080", "The address the metric endpoint binds to.")
 	flag.BoolVar(&enableLeaderElection, "enable-leader-election", false,
 		"Enable leader election for controller manager. Enabling this will ensure there is only one active controller manager.")
+	flag.BoolVar(&workflowEvents, "workflow-events", false,
+		"Record the Await events on the resumed Workflows as well.")
+	flag.BoolVar(&cacheWorkflows, "cache-workflows", false,
+		"Read the Workflows from the cache of the manager instead of the API server.")
+	flag.StringVar(&resumer, "resumer", controllers.ResumerPatch,
+		"The way the Workflows are resumed, one of 'patch' or 'argo-server'.")
+	flag.StringVar(&argoServerURL, "argo-server-url", "",
+		"The URL of the Argo Server used by the 'argo-server' resumer. The token is read from the ARGO_TOKEN env variable.")
+	flag.BoolVar(&argoServerInsecure, "argo-server-insecure-skip-verify", false,
+		"Skip the verification of the Argo Server certificate.")
+	flag.StringVar(&tracingExporter, "tracing-exporter", tracing.ExporterNone,
+		"The exporter of the traces, one of 'otlp' or 'stdout'. Tracing is disabled if not set.")
+	flag.StringVar(&tracingEndpoint, "tracing-endpoint", "",
+		"The address of the OTLP collector the traces are exported to.")
+	flag.StringVar(&eventsAddr, "events-addr", "",
+		"The address the CloudEvents receiver binds to. The Awaits of the events are not supported if not set.")
+	flag.StringVar(&eventsAuth, "events-auth", cloudevents.AuthToken,
+		"The way the senders of the CloudEvents authenticate, one of 'token', 'hmac' or 'none'. The secret is read from the EVENTS_SECRET env variable.")
+	flag.StringVar(&pluginSocketDir, "plugin-socket-dir", "",
+		"The directory with the sockets of the sidecar observer plugins, named <plugin>.sock.")
+	flag.StringVar(&pluginConfigMap, "plugin-configmap", "",
+		"The namespace/name of the ConfigMap listing the addresses of the observer plugins by their names.")
 	flag.Parse()
 
 	ctrl.SetLogger(zap.Logger(true))
 
+	shutdownTracing, err := tracing.Setup(tracingExporter, tracingEndpoint)
+	if err != nil {
+		setupLog.Error(err, "unable to set up tracing")
+		os.Exit(1)
+	}
+	defer shutdownTracing()
+
 	cfg := ctrl.GetConfigOrDie()
 	mgr, err := ctrl.NewManager(cfg, ctrl.Options{
 		Scheme:             scheme,
@@ -65,19 +121,134 @@ func main() {
 		os.Exit(1)
 	}
 
+	workflows, err := controllers.NewWorkflowClient(cfg)
+	if err != nil {
+		setupLog.Error(err, "unable to create workflow client")
+		os.Exit(1)
+	}
+	if cacheWorkflows {
+		workflows = controllers.NewCachedWorkflowClient(mgr.GetClient(), workflows)
+	}
+
+	var workflowResumer controllers.Resumer
+	switch resumer {
+	case controllers.ResumerPatch:
+		workflowResumer = controllers.NewPatchResumer(workflows, controllers.DefaultResumeBackoff)
+	case controllers.ResumerArgoServer:
+		if argoServerURL == "" {
+			setupLog.Error(nil, "the Argo Server URL must be set for the 'argo-server' resumer")
+			os.Exit(1)
+		}
+		httpClient := &http.Client{Transport: &http.Transport{
+			Proxy:           http.ProxyFromEnvironment,
+			TLSClientConfig: &tls.Config{InsecureSkipVerify: argoServerInsecure},
+		}}
+		workflowResumer = controllers.NewArgoServerResumer(
+			argoServerURL, os.Getenv(common.ArgoTokenEnvVar), httpClient, controllers.DefaultResumeBackoff)
+	default:
+		setupLog.Error(nil, "unknown resumer", "resumer", resumer)
+		os.Exit(1)
+	}
+
+	dynamicClient, err := dynamic.NewForConfig(cfg)
+	if err != nil {
+		setupLog.Error(err, "unable to create dynamic client")
+		os.Exit(1)
+	}
+	actions := controllers.NewTargetActions(workflows, workflowResumer, dynamicClient, controllers.DefaultResumeBackoff)
+
+	var events *cloudevents.Receiver
+	if eventsAddr != "" {
+		events, err = cloudevents.NewReceiver(eventsAddr, eventsAuth, os.Getenv(common.EventsSecretEnvVar))
+		if err != nil {
+			setupLog.Error(err, "unable to create the cloudevents receiver")
+			os.Exit(1)
+		}
+		if err := mgr.Add(events); err != nil {
+			setupLog.Error(err, "unable to add the cloudevents receiver")
+			os.Exit(1)
+		}
+	}
+
+	registry := observers.NewRegistry()
+	if err := loadPlugins(registry, mgr.GetAPIReader(), pluginSocketDir, pluginConfigMap); err != nil {
+		setupLog.Error(err, "unable to load the observer plugins")
+		os.Exit(1)
+	}
+
 	if err = (&controllers.AwaitReconciler{
-		Client: mgr.GetClient(),
-		Log:    ctrl.Log.WithName("controllers").WithName("Await"),
-		Config: cfg,
+		Client:         mgr.GetClient(),
+		Log:            ctrl.Log.WithName("controllers").WithName("Await"),
+		Config:         cfg,
+		Recorder:       mgr.GetEventRecorderFor("await-controller"),
+		Actions:        actions,
+		APIReader:      mgr.GetAPIReader(),
+		Events:         events,
+		Observers:      registry,
+		WorkflowEvents: workflowEvents,
 	}).SetupWithManager(mgr); err != nil {
 		setupLog.Error(err, "unable to create controller", "controller", "Await")
 		os.Exit(1)
 	}
+
+	if os.Getenv(common.EnableWebhooksEnvVar) != "false" {
+		if err = (&v1alpha1.Await{}).SetupWebhookWithManager(mgr); err != nil {
+			setupLog.Error(err, "unable to create webhook", "webhook", "Await")
+			os.Exit(1)
+		}
+		if err = (&v1beta1.Await{}).SetupWebhookWithManager(mgr); err != nil {
+			setupLog.Error(err, "unable to create webhook", "webhook", "Await")
+			os.Exit(1)
+		}
+		if err = v1alpha1.SetupApprovalWebhookWithManager(mgr); err != nil {
+			setupLog.Error(err, "unable to create webhook", "webhook", "Approval")
+			os.Exit(1)
+		}
+	}
 	// +kubebuilder:scaffold:builder
 
+	crmetrics.Registry.MustRegister(metrics.NewAwaitCollector(mgr.GetClient()))
+
 	setupLog.Info("starting manager")
 	if err := mgr.Start(ctrl.SetupSignalHandler()); err != nil {
 		setupLog.Error(err, "problem running manager")
 		os.Exit(1)
 	}
 }
+
+// pluginTimeout is the timeout of connecting to the observer plugins
+const pluginTimeout = time.Minute
+
+// loadPlugins registers the observer types of the plugins discovered
+// in the socket directory and the ConfigMap, if set
+func loadPlugins(registry *observers.Registry, reader client.Reader, socketDir, configMap string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), pluginTimeout)
+	defer cancel()
+
+	addresses := map[string]string{}
+	if socketDir != "" {
+		sockets, err := plugin.DiscoverSockets(socketDir)
+		if err != nil {
+			return err
+		}
+		for name, address := range sockets {
+			addresses[name] = address
+		}
+	}
+	if configMap != "" {
+		key, err := plugin.ParseConfigMapKey(configMap)
+		if err != nil {
+			return err
+		}
+		listed, err := plugin.DiscoverConfigMap(ctx, reader, key)
+		if err != nil {
+			return err
+		}
+		for name, address := range listed {
+			addresses[name] = address
+		}
+	}
+
+	_, err := plugin.Load(ctx, registry, addresses)
+	return err
+}
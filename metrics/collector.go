@@ -0,0 +1,77 @@
+/*
+Copyright 2019 Marek Cermak <macermak@redhat.com>.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package metrics
+
+import (
+	"context"
+
+	v1alpha1 "github.com/cermakm/argo-await-operator/api/v1alpha1"
+	"github.com/prometheus/client_golang/prometheus"
+
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+var log = logf.Log.WithName("metrics")
+
+var pendingAwaitsDesc = prometheus.NewDesc(
+	prometheus.BuildFQName(namespace, "", "pending"),
+	"Number of Awaits which have not been finished yet by the awaited resource kind and namespace.",
+	[]string{"kind", "namespace"}, nil,
+)
+
+// AwaitCollector collects the number of pending Awaits
+// from the Awaits known to the client at the time of the scrape
+type AwaitCollector struct {
+	client client.Reader
+}
+
+var _ prometheus.Collector = &AwaitCollector{}
+
+// NewAwaitCollector creates a new AwaitCollector listing the Awaits with the given client
+func NewAwaitCollector(c client.Reader) *AwaitCollector {
+	return &AwaitCollector{client: c}
+}
+
+// Describe implements prometheus.Collector
+func (c *AwaitCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- pendingAwaitsDesc
+}
+
+// Collect implements prometheus.Collector
+func (c *AwaitCollector) Collect(ch chan<- prometheus.Metric) {
+	awaits := &v1alpha1.AwaitList{}
+	if err := c.client.List(context.TODO(), awaits); err != nil {
+		log.Error(err, "unable to list awaits")
+		return
+	}
+
+	type key struct{ kind, namespace string }
+
+	pending := map[key]float64{}
+	for _, res := range awaits.Items {
+		switch res.Status.Phase {
+		case v1alpha1.AwaitResumed, v1alpha1.AwaitCompleted, v1alpha1.AwaitSkipped, v1alpha1.AwaitFailed:
+			continue
+		}
+		pending[key{res.Spec.ObservedKind(), res.Namespace}]++
+	}
+
+	for k, count := range pending {
+		ch <- prometheus.MustNewConstMetric(pendingAwaitsDesc, prometheus.GaugeValue, count, k.kind, k.namespace)
+	}
+}
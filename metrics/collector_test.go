@@ -0,0 +1,64 @@
+/*
+Copyright 2019 Marek Cermak <macermak@redhat.com>.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package metrics
+
+import (
+	"strings"
+	"testing"
+
+	v1alpha1 "github.com/cermakm/argo-await-operator/api/v1alpha1"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func newAwait(name, namespace, kind string, phase v1alpha1.AwaitPhase) *v1alpha1.Await {
+	return &v1alpha1.Await{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: namespace},
+		Spec: v1alpha1.AwaitSpec{
+			Resource: v1alpha1.Resource{Kind: kind},
+		},
+		Status: v1alpha1.AwaitStatus{Phase: phase},
+	}
+}
+
+func TestAwaitCollector(t *testing.T) {
+	scheme := runtime.NewScheme()
+	if err := v1alpha1.AddToScheme(scheme); err != nil {
+		t.Fatal(err)
+	}
+
+	c := fake.NewFakeClientWithScheme(scheme,
+		newAwait("pending", "default", "ConfigMap", v1alpha1.AwaitPending),
+		newAwait("waiting", "default", "ConfigMap", v1alpha1.AwaitWaiting),
+		newAwait("new", "argo", "ConfigMap", ""),
+		newAwait("resumed", "default", "ConfigMap", v1alpha1.AwaitResumed),
+		newAwait("failed", "default", "Pod", v1alpha1.AwaitFailed),
+	)
+
+	expected := `
+# HELP await_pending Number of Awaits which have not been finished yet by the awaited resource kind and namespace.
+# TYPE await_pending gauge
+await_pending{kind="ConfigMap",namespace="argo"} 1
+await_pending{kind="ConfigMap",namespace="default"} 2
+`
+	if err := testutil.CollectAndCompare(NewAwaitCollector(c), strings.NewReader(expected)); err != nil {
+		t.Errorf("unexpected collected metrics: %v", err)
+	}
+}
@@ -0,0 +1,89 @@
+/*
+Copyright 2019 Marek Cermak <macermak@redhat.com>.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package metrics contains the Prometheus metrics of the operator,
+// registered with the controller-runtime metrics registry
+package metrics
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
+)
+
+const namespace = "await"
+
+var (
+	// ActiveObservers is the number of running observers
+	ActiveObservers = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "active_observers",
+		Help:      "Number of running observers by the awaited resource kind and namespace.",
+	}, []string{"kind", "namespace"})
+
+	// AwaitDuration is the time between the start and the finish of an Await
+	AwaitDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "duration_seconds",
+		Help:      "Time between the start and the finish of an Await.",
+		Buckets:   prometheus.ExponentialBuckets(1, 4, 10),
+	}, []string{"kind", "namespace"})
+
+	// EventsProcessed is the number of watch events processed by the observers
+	EventsProcessed = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "events_processed_total",
+		Help:      "Number of watch events processed by the observers.",
+	}, []string{"kind", "namespace"})
+
+	// FilterEvaluations is the number of resources evaluated against the filters
+	FilterEvaluations = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "filter_evaluations_total",
+		Help:      "Number of resources evaluated against the Await filters.",
+	}, []string{"kind", "namespace"})
+
+	// FilterErrors is the number of filter evaluations which failed
+	FilterErrors = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "filter_errors_total",
+		Help:      "Number of Await filter evaluations which failed.",
+	}, []string{"kind", "namespace"})
+
+	// WorkflowResumes is the number of Workflow resume attempts by their result
+	WorkflowResumes = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "workflow_resumes_total",
+		Help:      "Number of Workflow resume attempts by their result.",
+	}, []string{"namespace", "result"})
+)
+
+// Results of the Workflow resume attempts
+const (
+	ResultSuccess = "success"
+	ResultFailure = "failure"
+)
+
+func init() {
+	metrics.Registry.MustRegister(
+		ActiveObservers,
+		AwaitDuration,
+		EventsProcessed,
+		FilterEvaluations,
+		FilterErrors,
+		WorkflowResumes,
+	)
+}
@@ -0,0 +1,94 @@
+package observers
+
+import (
+	"fmt"
+
+	v1alpha1 "github.com/cermakm/argo-await-operator/api/v1alpha1"
+	"github.com/cermakm/argo-await-operator/observers/cloudevents"
+	httpobserver "github.com/cermakm/argo-await-operator/observers/http"
+	podobserver "github.com/cermakm/argo-await-operator/observers/pod"
+	"github.com/cermakm/argo-await-operator/observers/resource"
+	workflowobserver "github.com/cermakm/argo-await-operator/observers/workflow"
+
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/kubernetes"
+)
+
+// registerBuiltins registers the Factories of the built-in observers
+func registerBuiltins(r *Registry) {
+	r.factories[v1alpha1.ObserverTypeResource] = newResourceObserver
+	r.factories[v1alpha1.ObserverTypeHTTP] = newHTTPObserver
+	r.factories[v1alpha1.ObserverTypeWorkflow] = newWorkflowObserver
+	r.factories[v1alpha1.ObserverTypePod] = newPodObserver
+	r.factories[v1alpha1.ObserverTypeCloudEvent] = newCloudEventObserver
+}
+
+func newResourceObserver(res *v1alpha1.Await, opts Options) (Observer, error) {
+	obs, err := resource.NewObserverForResource(opts.Config, &res.Spec.Resource, res.Spec.Filters, res.Spec.FilterMode, res.Spec.StableDuration(), res.Spec.Count)
+	if err != nil {
+		return nil, err
+	}
+
+	if count := res.Spec.Count; count != nil {
+		return FromAwaiter(obs, fmt.Sprintf("Counting %s %s, %s %d", res.Spec.Resource.Kind, res.Spec.Resource.Name, count.Operator, count.Value)), nil
+	}
+	return FromAwaiter(obs, fmt.Sprintf("Watching %s %s", res.Spec.Resource.Kind, res.Spec.Resource.Name)), nil
+}
+
+func newHTTPObserver(res *v1alpha1.Await, opts Options) (Observer, error) {
+	if res.Spec.HTTP == nil {
+		return nil, fmt.Errorf("the HTTP endpoint is not specified")
+	}
+	obs := httpobserver.NewObserver(res.Spec.HTTP, res.Spec.Filters, res.Spec.FilterMode, res.Spec.StableDuration(), res.Namespace, opts.HTTPClient)
+
+	return FromAwaiter(obs, fmt.Sprintf("Polling %s", res.Spec.HTTP.URL)), nil
+}
+
+func newWorkflowObserver(res *v1alpha1.Await, opts Options) (Observer, error) {
+	if res.Spec.Completion == nil {
+		return nil, fmt.Errorf("the workflow completion is not specified")
+	}
+	client, err := dynamic.NewForConfig(opts.Config)
+	if err != nil {
+		return nil, err
+	}
+	completion := res.Spec.Completion.DeepCopy()
+	if completion.Namespace == "" {
+		completion.Namespace = res.Namespace
+	}
+	obs := workflowobserver.NewObserver(client, completion, res.Spec.Filters)
+
+	return FromAwaiter(obs, fmt.Sprintf("Watching Workflows in %s for completion", completion.Namespace)), nil
+}
+
+func newPodObserver(res *v1alpha1.Await, opts Options) (Observer, error) {
+	if res.Spec.Pod == nil {
+		return nil, fmt.Errorf("the pod is not specified")
+	}
+	client, err := kubernetes.NewForConfig(opts.Config)
+	if err != nil {
+		return nil, err
+	}
+	match := res.Spec.Pod.DeepCopy()
+	if match.Namespace == "" {
+		match.Namespace = res.Namespace
+	}
+	obs, err := podobserver.NewObserver(client, match, res.Spec.Filters)
+	if err != nil {
+		return nil, err
+	}
+
+	return FromAwaiter(obs, fmt.Sprintf("Watching Pods in %s", match.Namespace)), nil
+}
+
+func newCloudEventObserver(res *v1alpha1.Await, opts Options) (Observer, error) {
+	if res.Spec.Event == nil {
+		return nil, fmt.Errorf("the event is not specified")
+	}
+	if opts.Events == nil {
+		return nil, fmt.Errorf("the CloudEvents receiver is not enabled")
+	}
+	obs := cloudevents.NewObserver(opts.Events, res.Spec.Event, res.Spec.Filters, res.Namespace)
+
+	return FromAwaiter(obs, "Waiting for a CloudEvent"), nil
+}
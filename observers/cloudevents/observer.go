@@ -0,0 +1,53 @@
+package cloudevents
+
+import (
+	"context"
+
+	v1alpha1 "github.com/cermakm/argo-await-operator/api/v1alpha1"
+	"github.com/cermakm/argo-await-operator/metrics"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+// Observer waits for an event received by the Receiver
+type Observer struct {
+	receiver *Receiver
+
+	namespace string
+	match     *v1alpha1.CloudEventMatch
+	filters   []string
+}
+
+// NewObserver creates a new Observer of the events received by the Receiver
+func NewObserver(receiver *Receiver, match *v1alpha1.CloudEventMatch, filters []string, namespace string) *Observer {
+	return &Observer{
+		receiver:  receiver,
+		namespace: namespace,
+		match:     match,
+		filters:   filters,
+	}
+}
+
+// Await waits for an event with the type and source passing the filters
+// and calls the callback with the event
+func (obs *Observer) Await(ctx context.Context, callback func(ctx context.Context, obj *unstructured.Unstructured) error) error {
+	sub := obs.receiver.subscribe(obs.namespace, obs.match, obs.filters)
+	defer obs.receiver.unsubscribe(sub)
+
+	log.Info("waiting for an event", "namespace", obs.namespace, "type", obs.match.Type, "source", obs.match.Source)
+
+	metrics.ActiveObservers.WithLabelValues(v1alpha1.ObservedKindCloudEvent, obs.namespace).Inc()
+	defer metrics.ActiveObservers.WithLabelValues(v1alpha1.ObservedKindCloudEvent, obs.namespace).Dec()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case res := <-sub.results:
+		if res.err != nil {
+			return res.err
+		}
+
+		log.Info("event received", "namespace", obs.namespace, "id", res.event["id"])
+		return callback(ctx, &unstructured.Unstructured{Object: res.event})
+	}
+}
@@ -0,0 +1,149 @@
+package cloudevents
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	v1alpha1 "github.com/cermakm/argo-await-operator/api/v1alpha1"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+// binaryEvent creates a request with an event in the binary content mode
+func binaryEvent(url, eventType, data string) *http.Request {
+	req, _ := http.NewRequest(http.MethodPost, url, strings.NewReader(data))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Ce-Specversion", "1.0")
+	req.Header.Set("Ce-Id", "1")
+	req.Header.Set("Ce-Source", "/ci")
+	req.Header.Set("Ce-Type", eventType)
+	req.Header.Set("Ce-Namespace", "default")
+	return req
+}
+
+// structuredEvent creates a request with an event in the structured content mode
+func structuredEvent(url, event string) *http.Request {
+	req, _ := http.NewRequest(http.MethodPost, url, strings.NewReader(event))
+	req.Header.Set("Content-Type", "application/cloudevents+json; charset=utf-8")
+	return req
+}
+
+func TestObserver_Await(t *testing.T) {
+	tests := []struct {
+		name      string
+		match     v1alpha1.CloudEventMatch
+		filters   []string
+		requests  func(url string) []*http.Request
+		wantCodes []int
+		wantID    string
+	}{
+		{
+			name:    "Binary event passing the filters",
+			match:   v1alpha1.CloudEventMatch{Type: "com.example.build"},
+			filters: []string{`data.status=="succeeded"`},
+			requests: func(url string) []*http.Request {
+				return []*http.Request{
+					binaryEvent(url, "com.example.deploy", `{"status": "succeeded"}`),
+					binaryEvent(url, "com.example.build", `{"status": "failed"}`),
+					binaryEvent(url, "com.example.build", `{"status": "succeeded"}`),
+				}
+			},
+			wantCodes: []int{http.StatusOK, http.StatusOK, http.StatusAccepted},
+			wantID:    "1",
+		},
+		{
+			name:  "Structured event",
+			match: v1alpha1.CloudEventMatch{Source: "/ci"},
+			requests: func(url string) []*http.Request {
+				return []*http.Request{
+					structuredEvent(url, `{"specversion": "1.0", "id": "2", "source": "/ci", "type": "com.example.build", "namespace": "default"}`),
+				}
+			},
+			wantCodes: []int{http.StatusAccepted},
+			wantID:    "2",
+		},
+		{
+			name:  "Events of other namespaces",
+			match: v1alpha1.CloudEventMatch{Type: "com.example.build"},
+			requests: func(url string) []*http.Request {
+				other := binaryEvent(url, "com.example.build", "")
+				other.Header.Set("Ce-Namespace", "other")
+				return []*http.Request{
+					other,
+					structuredEvent(url+"/namespaces/other", `{"specversion": "1.0", "id": "2", "source": "/ci", "type": "com.example.build"}`),
+					structuredEvent(url+"/namespaces/default", `{"specversion": "1.0", "id": "3", "source": "/ci", "type": "com.example.build"}`),
+				}
+			},
+			wantCodes: []int{http.StatusOK, http.StatusOK, http.StatusAccepted},
+			wantID:    "3",
+		},
+		{
+			name: "Invalid event",
+			requests: func(url string) []*http.Request {
+				return []*http.Request{
+					structuredEvent(url, `{"specversion": "1.0", "source": "/ci", "type": "com.example.build", "namespace": "default"}`),
+					binaryEvent(url, "com.example.build", ""),
+				}
+			},
+			wantCodes: []int{http.StatusBadRequest, http.StatusAccepted},
+			wantID:    "1",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			receiver, err := NewReceiver("", AuthNone, "")
+			if err != nil {
+				t.Fatal(err)
+			}
+			server := httptest.NewServer(receiver)
+			defer server.Close()
+
+			obs := NewObserver(receiver, &tt.match, tt.filters, "default")
+
+			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+
+			events := make(chan *unstructured.Unstructured, 1)
+			errs := make(chan error, 1)
+			go func() {
+				errs <- obs.Await(ctx, func(ctx context.Context, obj *unstructured.Unstructured) error {
+					events <- obj
+					return nil
+				})
+			}()
+
+			// Wait for the Observer to subscribe
+			for {
+				receiver.mu.Lock()
+				subscribed := len(receiver.subscriptions) > 0
+				receiver.mu.Unlock()
+				if subscribed {
+					break
+				}
+				time.Sleep(time.Millisecond)
+			}
+
+			for i, req := range tt.requests(server.URL) {
+				resp, err := server.Client().Do(req)
+				if err != nil {
+					t.Fatal(err)
+				}
+				resp.Body.Close()
+				if resp.StatusCode != tt.wantCodes[i] {
+					t.Errorf("request %d status = %v, want %v", i, resp.StatusCode, tt.wantCodes[i])
+				}
+			}
+
+			if err := <-errs; err != nil {
+				t.Fatalf("Await() error = %v", err)
+			}
+			if event := <-events; event.Object["id"] != tt.wantID {
+				t.Errorf("Await() event = %v, want id %v", event.Object, tt.wantID)
+			}
+		})
+	}
+}
@@ -0,0 +1,343 @@
+package cloudevents
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"mime"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	v1alpha1 "github.com/cermakm/argo-await-operator/api/v1alpha1"
+	"github.com/cermakm/argo-await-operator/metrics"
+	"github.com/cermakm/argo-await-operator/observers/filter"
+
+	logf "sigs.k8s.io/controller-runtime/pkg/runtime/log"
+)
+
+var log = logf.Log.WithName("cloudevents-receiver")
+
+// maxEventSize is the maximum size of the received events
+const maxEventSize = 1 << 20
+
+// headerPrefix is the prefix of the attribute headers of the events in the binary mode
+const headerPrefix = "Ce-"
+
+// requiredAttributes are the attributes every event has to have
+var requiredAttributes = []string{"specversion", "id", "source", "type"}
+
+// namespaceAttribute is the extension attribute with the namespace of the Awaits the event is for
+const namespaceAttribute = "namespace"
+
+// namespacesPath is the prefix of the paths the events for the Awaits of a namespace are sent to
+const namespacesPath = "/namespaces/"
+
+// SignatureHeader is the header with the HMAC-SHA256 signature of the request body,
+// the same way the callbacks of the Awaits are signed
+const SignatureHeader = "X-Await-Signature-256"
+
+// The ways the senders of the events authenticate
+const (
+	// AuthNone accepts the events of any sender
+	AuthNone = "none"
+	// AuthToken requires the shared secret as the bearer token
+	AuthToken = "token"
+	// AuthHMAC requires the body signed with HMAC-SHA256 using the shared secret
+	AuthHMAC = "hmac"
+)
+
+// Receiver receives the CloudEvents over HTTP in both the binary and the structured
+// content mode and delivers them to the Observers whose filters they pass.
+// The events are not persisted, the events no Observer is waiting for are dropped.
+// Every event is for the Awaits of a single namespace, given either by the path
+// /namespaces/<namespace> or by the namespace extension attribute.
+type Receiver struct {
+	addr string
+
+	// auth is the way the senders authenticate using the secret
+	auth   string
+	secret []byte
+
+	mu            sync.Mutex
+	subscriptions map[*subscription]struct{}
+}
+
+// subscription is an Observer waiting for an event
+type subscription struct {
+	namespace string
+	match     *v1alpha1.CloudEventMatch
+	filters   *filter.Filters
+
+	// results receives a single matching event or the filters error
+	results chan result
+}
+
+type result struct {
+	event map[string]interface{}
+	err   error
+}
+
+// NewReceiver creates a new Receiver listening on the address once started,
+// the senders authenticate using the secret the given way
+func NewReceiver(addr, auth, secret string) (*Receiver, error) {
+	switch auth {
+	case AuthNone:
+	case AuthToken, AuthHMAC:
+		if secret == "" {
+			return nil, fmt.Errorf("the secret has to be set for the %q authentication", auth)
+		}
+	default:
+		return nil, fmt.Errorf("unknown authentication %q", auth)
+	}
+
+	return &Receiver{
+		addr:          addr,
+		auth:          auth,
+		secret:        []byte(secret),
+		subscriptions: map[*subscription]struct{}{},
+	}, nil
+}
+
+// Start serves the events until the stop channel is closed
+func (r *Receiver) Start(stop <-chan struct{}) error {
+	listener, err := net.Listen("tcp", r.addr)
+	if err != nil {
+		return err
+	}
+
+	server := &http.Server{Handler: r}
+	go func() {
+		<-stop
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		_ = server.Shutdown(ctx)
+	}()
+
+	log.Info("receiving cloud events", "addr", r.addr)
+	if err := server.Serve(listener); err != nil && err != http.ErrServerClosed {
+		return err
+	}
+
+	return nil
+}
+
+// ServeHTTP receives a single event, it responds with 202 Accepted if the event
+// fulfilled any Await and 200 OK otherwise
+func (r *Receiver) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	namespace, ok := pathNamespace(req.URL.Path)
+	if !ok {
+		http.NotFound(w, req)
+		return
+	}
+
+	body, err := ioutil.ReadAll(io.LimitReader(req.Body, maxEventSize))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if !r.authenticate(req, body) {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	event, err := readEvent(req, body)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	namespace, err = eventNamespace(namespace, event)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	log.V(1).Info("event received", "namespace", namespace,
+		"id", event["id"], "type", event["type"], "source", event["source"])
+
+	if r.dispatch(namespace, event) > 0 {
+		w.WriteHeader(http.StatusAccepted)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+}
+
+// authenticate returns whether the sender of the request has the secret
+func (r *Receiver) authenticate(req *http.Request, body []byte) bool {
+	switch r.auth {
+	case AuthToken:
+		token := strings.TrimPrefix(req.Header.Get("Authorization"), "Bearer ")
+		return subtle.ConstantTimeCompare([]byte(token), r.secret) == 1
+	case AuthHMAC:
+		mac := hmac.New(sha256.New, r.secret)
+		mac.Write(body)
+		signature := "sha256=" + hex.EncodeToString(mac.Sum(nil))
+		return hmac.Equal([]byte(req.Header.Get(SignatureHeader)), []byte(signature))
+	}
+
+	return true
+}
+
+// pathNamespace returns the namespace given by the path, if any,
+// only the root and the namespaces paths are served
+func pathNamespace(path string) (string, bool) {
+	if path == "" || path == "/" {
+		return "", true
+	}
+	if !strings.HasPrefix(path, namespacesPath) {
+		return "", false
+	}
+
+	namespace := strings.TrimSuffix(strings.TrimPrefix(path, namespacesPath), "/")
+	if namespace == "" || strings.Contains(namespace, "/") {
+		return "", false
+	}
+	return namespace, true
+}
+
+// eventNamespace returns the namespace of the Awaits the event is for, the namespace
+// attribute has to match the namespace of the path if both are given
+func eventNamespace(pathNamespace string, event map[string]interface{}) (string, error) {
+	namespace, _ := event[namespaceAttribute].(string)
+	switch {
+	case pathNamespace == "" && namespace == "":
+		return "", fmt.Errorf("the event is missing the namespace, either the %q attribute or the %s<namespace> path has to be set",
+			namespaceAttribute, namespacesPath)
+	case pathNamespace == "":
+		return namespace, nil
+	case namespace != "" && namespace != pathNamespace:
+		return "", fmt.Errorf("the namespace attribute %q does not match the namespace %q of the path", namespace, pathNamespace)
+	}
+
+	return pathNamespace, nil
+}
+
+// subscribe registers an Observer waiting for an event
+func (r *Receiver) subscribe(namespace string, match *v1alpha1.CloudEventMatch, filters []string) *subscription {
+	sub := &subscription{
+		namespace: namespace,
+		match:     match,
+		filters:   filter.Compile(filters...),
+		results:   make(chan result, 1),
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.subscriptions[sub] = struct{}{}
+
+	return sub
+}
+
+// unsubscribe removes the Observer
+func (r *Receiver) unsubscribe(sub *subscription) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	delete(r.subscriptions, sub)
+}
+
+// dispatch delivers the event to the matching subscriptions of the namespace and returns
+// their count, each subscription receives a single result and is removed afterwards
+func (r *Receiver) dispatch(namespace string, event map[string]interface{}) int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	matched := 0
+	for sub := range r.subscriptions {
+		if sub.namespace != namespace {
+			continue
+		}
+		metrics.EventsProcessed.WithLabelValues(v1alpha1.ObservedKindCloudEvent, sub.namespace).Inc()
+		if !sub.matchesAttributes(event) {
+			continue
+		}
+
+		metrics.FilterEvaluations.WithLabelValues(v1alpha1.ObservedKindCloudEvent, sub.namespace).Inc()
+		ok, err := sub.filters.Pass(event)
+		if err != nil {
+			metrics.FilterErrors.WithLabelValues(v1alpha1.ObservedKindCloudEvent, sub.namespace).Inc()
+			sub.results <- result{err: filter.ErrInvalidFilters}
+			delete(r.subscriptions, sub)
+			continue
+		}
+		if !ok {
+			continue
+		}
+
+		sub.results <- result{event: event}
+		delete(r.subscriptions, sub)
+		matched++
+	}
+
+	return matched
+}
+
+// matchesAttributes returns whether the event has the awaited type and source
+func (s *subscription) matchesAttributes(event map[string]interface{}) bool {
+	if s.match.Type != "" && event["type"] != s.match.Type {
+		return false
+	}
+	if s.match.Source != "" && event["source"] != s.match.Source {
+		return false
+	}
+
+	return true
+}
+
+// readEvent reads the event from the request body in either content mode
+func readEvent(req *http.Request, body []byte) (map[string]interface{}, error) {
+	contentType := req.Header.Get("Content-Type")
+	mediaType, _, _ := mime.ParseMediaType(contentType)
+
+	event := map[string]interface{}{}
+	if mediaType == "application/cloudevents+json" {
+		if err := json.Unmarshal(body, &event); err != nil {
+			return nil, fmt.Errorf("invalid structured event: %v", err)
+		}
+	} else {
+		for name, values := range req.Header {
+			if strings.HasPrefix(name, headerPrefix) && len(values) > 0 {
+				event[strings.ToLower(strings.TrimPrefix(name, headerPrefix))] = values[0]
+			}
+		}
+		if contentType != "" {
+			event["datacontenttype"] = contentType
+		}
+		if len(body) > 0 {
+			event["data"] = decodeData(mediaType, body)
+		}
+	}
+
+	for _, attr := range requiredAttributes {
+		if value, ok := event[attr].(string); !ok || value == "" {
+			return nil, fmt.Errorf("the event is missing the required attribute %q", attr)
+		}
+	}
+
+	return event, nil
+}
+
+// decodeData decodes the JSON data so that the filters can match its fields,
+// any other data is kept as a string
+func decodeData(mediaType string, body []byte) interface{} {
+	if mediaType == "application/json" || strings.HasSuffix(mediaType, "+json") {
+		var data interface{}
+		if err := json.Unmarshal(body, &data); err == nil {
+			return data
+		}
+	}
+
+	return string(body)
+}
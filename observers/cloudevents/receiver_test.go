@@ -0,0 +1,136 @@
+package cloudevents
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// sign signs the body the way the HMAC authenticated senders do
+func sign(secret, body string) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(body))
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+func TestNewReceiver(t *testing.T) {
+	tests := []struct {
+		name    string
+		auth    string
+		secret  string
+		wantErr bool
+	}{
+		{name: "No authentication", auth: AuthNone},
+		{name: "Token", auth: AuthToken, secret: "secret"},
+		{name: "Token without the secret", auth: AuthToken, wantErr: true},
+		{name: "HMAC without the secret", auth: AuthHMAC, wantErr: true},
+		{name: "Unknown authentication", auth: "basic", secret: "secret", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := NewReceiver("", tt.auth, tt.secret); (err != nil) != tt.wantErr {
+				t.Errorf("NewReceiver() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestReceiver_ServeHTTP(t *testing.T) {
+	event := `{"specversion": "1.0", "id": "1", "source": "/ci", "type": "com.example.build", "namespace": "default"}`
+	tests := []struct {
+		name     string
+		auth     string
+		path     string
+		event    string
+		header   map[string]string
+		wantCode int
+	}{
+		{
+			name:     "Valid token",
+			auth:     AuthToken,
+			event:    event,
+			header:   map[string]string{"Authorization": "Bearer secret"},
+			wantCode: http.StatusOK,
+		},
+		{
+			name:     "Invalid token",
+			auth:     AuthToken,
+			event:    event,
+			header:   map[string]string{"Authorization": "Bearer other"},
+			wantCode: http.StatusUnauthorized,
+		},
+		{
+			name:     "Missing token",
+			auth:     AuthToken,
+			event:    event,
+			wantCode: http.StatusUnauthorized,
+		},
+		{
+			name:     "Valid signature",
+			auth:     AuthHMAC,
+			event:    event,
+			header:   map[string]string{SignatureHeader: sign("secret", event)},
+			wantCode: http.StatusOK,
+		},
+		{
+			name:     "Signature of another body",
+			auth:     AuthHMAC,
+			event:    event,
+			header:   map[string]string{SignatureHeader: sign("secret", "{}")},
+			wantCode: http.StatusUnauthorized,
+		},
+		{
+			name:     "Namespace of the path",
+			auth:     AuthNone,
+			path:     "/namespaces/default",
+			event:    `{"specversion": "1.0", "id": "1", "source": "/ci", "type": "com.example.build"}`,
+			wantCode: http.StatusOK,
+		},
+		{
+			name:     "Namespace not matching the path",
+			auth:     AuthNone,
+			path:     "/namespaces/other",
+			event:    event,
+			wantCode: http.StatusBadRequest,
+		},
+		{
+			name:     "Missing namespace",
+			auth:     AuthNone,
+			event:    `{"specversion": "1.0", "id": "1", "source": "/ci", "type": "com.example.build"}`,
+			wantCode: http.StatusBadRequest,
+		},
+		{
+			name:     "Unknown path",
+			auth:     AuthNone,
+			path:     "/events",
+			event:    event,
+			wantCode: http.StatusNotFound,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			secret := ""
+			if tt.auth != AuthNone {
+				secret = "secret"
+			}
+			receiver, err := NewReceiver("", tt.auth, secret)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			req := structuredEvent("http://receiver"+tt.path, tt.event)
+			for name, value := range tt.header {
+				req.Header.Set(name, value)
+			}
+			recorder := httptest.NewRecorder()
+			receiver.ServeHTTP(recorder, req)
+
+			if recorder.Code != tt.wantCode {
+				t.Errorf("ServeHTTP() status = %v, want %v: %s", recorder.Code, tt.wantCode, recorder.Body)
+			}
+		})
+	}
+}
@@ -0,0 +1,368 @@
+package filter
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"unicode/utf8"
+
+	gjson "github.com/tidwall/gjson"
+)
+
+// Filters are the filters compiled once to be evaluated against many objects.
+// The simple comparisons of a path to a value, e.g. status.phase==Running,
+// are evaluated directly against the object, the other filters are evaluated
+// by the filter engine against the object formatted as JSON.
+//
+// The results are cached by the UID of the objects, the object of the same
+// resource version is not evaluated again, e.g. once it is relisted.
+type Filters struct {
+	filters []compiled
+
+	mu sync.Mutex
+	// results are the last results of the objects, keyed by their UIDs
+	results map[string]result
+}
+
+// result is the cached result of an object version
+type result struct {
+	version string
+	passed  bool
+}
+
+// compiled is a single compiled filter
+type compiled struct {
+	filter string
+
+	// direct is whether the filter is evaluated directly
+	direct bool
+	path   []string
+	op     string
+	value  string
+
+	// query is the filter wrapped for the filter engine
+	query string
+}
+
+// Compile compiles the filters, the filters which are not well-formed
+// are evaluated by the filter engine just like by Pass
+func Compile(filters ...string) *Filters {
+	f := &Filters{filters: make([]compiled, len(filters)), results: map[string]result{}}
+	for i, filter := range filters {
+		f.filters[i] = compile(filter)
+	}
+
+	return f
+}
+
+// Pass checks whether the object passes all the filters
+func (f *Filters) Pass(object map[string]interface{}) (bool, error) {
+	uid, version := objectVersion(object)
+	return f.cached(uid, version, object)
+}
+
+// cached returns the cached result of the object version, if any,
+// otherwise it evaluates the filters and caches the result
+func (f *Filters) cached(uid, version string, object map[string]interface{}) (bool, error) {
+	if uid == "" || version == "" {
+		return f.pass(object)
+	}
+
+	f.mu.Lock()
+	r, ok := f.results[uid]
+	f.mu.Unlock()
+	if ok && r.version == version {
+		return r.passed, nil
+	}
+
+	passed, err := f.pass(object)
+	if err != nil {
+		return false, err
+	}
+
+	f.mu.Lock()
+	f.results[uid] = result{version: version, passed: passed}
+	f.mu.Unlock()
+
+	return passed, nil
+}
+
+// Forget removes the cached result of the object, e.g. once it has been deleted
+func (f *Filters) Forget(uid string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	delete(f.results, uid)
+}
+
+// pass evaluates the filters against the object
+func (f *Filters) pass(object map[string]interface{}) (bool, error) {
+	// The object is formatted as JSON only if a filter requires it
+	var resourceJSON string
+	for i := range f.filters {
+		filter := &f.filters[i]
+		log.V(1).Info("applying filter", "filter", filter.filter)
+
+		if filter.direct {
+			if ok, supported := filter.match(object); supported {
+				if !ok {
+					return false, nil
+				}
+				continue
+			}
+		}
+
+		if resourceJSON == "" {
+			resourceJSON = unstructuredToJSON([]interface{}{object})
+			if !gjson.Valid(resourceJSON) {
+				return false, fmt.Errorf("failed to parse the resource: invalid json")
+			}
+		}
+		if !gjson.Get(resourceJSON, filter.query).Exists() {
+			return false, nil
+		}
+	}
+
+	return true, nil
+}
+
+// PassTransition checks whether the transition of the object from the old state
+// passes all the filters, there is no old state if it is nil
+func (f *Filters) PassTransition(old, object map[string]interface{}) (bool, error) {
+	if old == nil {
+		return f.Pass(object)
+	}
+
+	transition := make(map[string]interface{}, len(object)+1)
+	for k, v := range object {
+		transition[k] = v
+	}
+	transition[OldKey] = old
+
+	// The result depends on both the versions
+	uid, version := objectVersion(object)
+	if _, oldVersion := objectVersion(old); oldVersion != "" && version != "" {
+		return f.cached(uid, version+"/"+oldVersion, transition)
+	}
+	return f.pass(transition)
+}
+
+// objectVersion returns the UID and the resource version of the object,
+// empty unless it is a Kubernetes object
+func objectVersion(object map[string]interface{}) (uid, version string) {
+	metadata, ok := object["metadata"].(map[string]interface{})
+	if !ok {
+		return "", ""
+	}
+	uid, _ = metadata["uid"].(string)
+	version, _ = metadata["resourceVersion"].(string)
+	return uid, version
+}
+
+// compile parses the filter the way the filter engine does and
+// falls back to the engine unless it is a simple comparison
+func compile(filter string) compiled {
+	// filter needs to wrapped in order to use comparison operator
+	c := compiled{filter: filter, query: fmt.Sprintf("#(%s)", filter)}
+
+	// The start of the operator
+	j := strings.IndexAny(filter, "!=<>%")
+	if j < 0 {
+		j = len(filter)
+	}
+	path, value := trim(filter[:j]), trim(filter[j:])
+
+	if path == "" || strings.ContainsAny(path, "\\\"'#*?|@()[]{}!=<>%,:") || strings.IndexFunc(path, isSpace) >= 0 {
+		return c
+	}
+	c.path = strings.Split(path, ".")
+	for _, key := range c.path {
+		if key == "" {
+			return c
+		}
+	}
+
+	if value != "" {
+		var opsz int
+		switch {
+		case value == "=", value == "<", value == ">":
+			opsz = 1
+		case strings.HasPrefix(value, "=="):
+			value = value[1:]
+			opsz = 1
+		case strings.HasPrefix(value, "!="), strings.HasPrefix(value, "<="), strings.HasPrefix(value, ">="):
+			opsz = 2
+		case value[0] == '<', value[0] == '>', value[0] == '=':
+			opsz = 1
+		default:
+			// The pattern matching and the malformed operators
+			return c
+		}
+		c.op, value = value[:opsz], trim(value[opsz:])
+
+		if strings.ContainsAny(value, "\\()[]{}") || !quoted(value) {
+			return c
+		}
+		if len(value) > 2 && value[0] == '"' && value[len(value)-1] == '"' {
+			value = value[1 : len(value)-1]
+		}
+		c.value = value
+	}
+
+	c.direct = true
+	return c
+}
+
+// trim trims the spaces and the control characters the way the filter engine does
+func trim(s string) string {
+	for len(s) > 0 && s[0] <= ' ' {
+		s = s[1:]
+	}
+	for len(s) > 0 && s[len(s)-1] <= ' ' {
+		s = s[:len(s)-1]
+	}
+	return s
+}
+
+func isSpace(r rune) bool {
+	return r <= ' '
+}
+
+// quoted returns whether the value is either quoted as a whole or not at all
+func quoted(value string) bool {
+	n := strings.Count(value, `"`)
+	return n == 0 || (n == 2 && len(value) > 2 && value[0] == '"' && value[len(value)-1] == '"')
+}
+
+// match evaluates the filter against the object with the semantics of the filter
+// engine, it is not supported if the object contains values which are formatted
+// as JSON in a way the filter can not be evaluated against directly
+func (c *compiled) match(object map[string]interface{}) (ok bool, supported bool) {
+	var value interface{} = object
+	for _, key := range c.path {
+		switch v := value.(type) {
+		case map[string]interface{}:
+			var exists bool
+			if value, exists = v[key]; !exists {
+				return false, true
+			}
+		case []interface{}:
+			if strings.TrimLeft(key, "0123456789") != "" {
+				// Only the indexes select the elements of arrays
+				return false, true
+			}
+			i, err := strconv.ParseUint(key, 10, 0)
+			if err != nil {
+				return false, false
+			}
+			if i >= uint64(len(v)) {
+				return false, true
+			}
+			value = v[i]
+		case string, bool, nil, float64, int64, int, int32, uint64, uint32, json.Number:
+			// The scalars have no fields
+			return false, true
+		default:
+			return false, false
+		}
+	}
+
+	switch v := value.(type) {
+	case nil, map[string]interface{}, []interface{}:
+		// Only the existence of null, objects and arrays can be checked
+		return c.op == "", true
+	case string:
+		if !utf8.ValidString(v) {
+			return false, false
+		}
+		return c.op == "" || compareStrings(v, c.op, c.value), true
+	case bool:
+		return c.op == "" || compareBools(v, c.op, c.value), true
+	case float64:
+		return c.op == "" || compareNumbers(v, c.op, c.value), true
+	case int64:
+		return c.op == "" || compareNumbers(float64(v), c.op, c.value), true
+	case int:
+		return c.op == "" || compareNumbers(float64(v), c.op, c.value), true
+	case int32:
+		return c.op == "" || compareNumbers(float64(v), c.op, c.value), true
+	case uint64:
+		return c.op == "" || compareNumbers(float64(v), c.op, c.value), true
+	case uint32:
+		return c.op == "" || compareNumbers(float64(v), c.op, c.value), true
+	case json.Number:
+		n, err := strconv.ParseFloat(string(v), 64)
+		if err != nil {
+			return false, false
+		}
+		return c.op == "" || compareNumbers(n, c.op, c.value), true
+	}
+
+	return false, false
+}
+
+func compareStrings(s, op, value string) bool {
+	switch op {
+	case "=":
+		return s == value
+	case "!=":
+		return s != value
+	case "<":
+		return s < value
+	case "<=":
+		return s <= value
+	case ">":
+		return s > value
+	case ">=":
+		return s >= value
+	}
+	return false
+}
+
+func compareNumbers(n float64, op, value string) bool {
+	v, _ := strconv.ParseFloat(value, 64)
+	switch op {
+	case "=":
+		return n == v
+	case "!=":
+		return n != v
+	case "<":
+		return n < v
+	case "<=":
+		return n <= v
+	case ">":
+		return n > v
+	case ">=":
+		return n >= v
+	}
+	return false
+}
+
+func compareBools(b bool, op, value string) bool {
+	if b {
+		switch op {
+		case "=":
+			return value == "true"
+		case "!=":
+			return value != "true"
+		case ">":
+			return value == "false"
+		case ">=":
+			return true
+		}
+		return false
+	}
+
+	switch op {
+	case "=":
+		return value == "false"
+	case "!=":
+		return value != "false"
+	case "<":
+		return value == "true"
+	case "<=":
+		return true
+	}
+	return false
+}
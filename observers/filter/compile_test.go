@@ -0,0 +1,267 @@
+package filter
+
+import (
+	"fmt"
+	"testing"
+
+	gjson "github.com/tidwall/gjson"
+)
+
+// passJSON is the evaluation of the filters against the object formatted
+// as JSON, the compiled filters have to evaluate the same way
+func passJSON(object map[string]interface{}, filters ...string) (bool, error) {
+	resourceJSON := unstructuredToJSON([]interface{}{object})
+
+	if !gjson.Valid(resourceJSON) {
+		return false, fmt.Errorf("failed to parse the resource: invalid json")
+	}
+
+	for _, filter := range filters {
+		if !gjson.Get(resourceJSON, fmt.Sprintf("#(%s)", filter)).Exists() {
+			return false, nil
+		}
+	}
+
+	return true, nil
+}
+
+func newPod() map[string]interface{} {
+	return map[string]interface{}{
+		"apiVersion": "v1",
+		"kind":       "Pod",
+		"metadata": map[string]interface{}{
+			"name":              "etl-7d9f",
+			"namespace":         "default",
+			"generation":        int64(3),
+			"deletionTimestamp": nil,
+			"labels":            map[string]interface{}{"app": "etl", "tier": "<backend>"},
+		},
+		"spec": map[string]interface{}{
+			"replicas": 3,
+			"paused":   false,
+			"containers": []interface{}{
+				map[string]interface{}{"name": "etl", "image": "etl:1.2"},
+			},
+		},
+		"status": map[string]interface{}{
+			"phase":    "Running",
+			"ready":    true,
+			"restarts": float64(1.5),
+			"conditions": []interface{}{
+				map[string]interface{}{"type": "Initialized", "status": "True"},
+				map[string]interface{}{"type": "Ready", "status": "False"},
+			},
+		},
+	}
+}
+
+func TestCompile(t *testing.T) {
+	tests := []struct {
+		filter     string
+		wantDirect bool
+	}{
+		{filter: "kind==Pod", wantDirect: true},
+		{filter: `kind=="Pod"`, wantDirect: true},
+		{filter: ` status.phase == "Running" `, wantDirect: true},
+		{filter: `status.phase!="Running"`, wantDirect: true},
+		{filter: `status.phase="Running"`, wantDirect: true},
+		{filter: `status.phase<"Succeeded"`, wantDirect: true},
+		{filter: `status.phase>=Running`, wantDirect: true},
+		{filter: `status.phase==""`, wantDirect: false},
+		{filter: `status.phase%"Run*"`, wantDirect: false},
+		{filter: `status.phase!%"Run*"`, wantDirect: false},
+		{filter: `status.phase!Running`, wantDirect: false},
+		{filter: "status.ready==true", wantDirect: true},
+		{filter: "status.ready!=false", wantDirect: true},
+		{filter: "status.ready>false", wantDirect: true},
+		{filter: "status.ready<true", wantDirect: true},
+		{filter: "spec.paused<=true", wantDirect: true},
+		{filter: "spec.paused==true", wantDirect: true},
+		{filter: "spec.replicas>=3", wantDirect: true},
+		{filter: "spec.replicas<3", wantDirect: true},
+		{filter: "spec.replicas==three", wantDirect: true},
+		{filter: "metadata.generation==3", wantDirect: true},
+		{filter: "status.restarts>1", wantDirect: true},
+		{filter: "status.restarts==1.5", wantDirect: true},
+		{filter: "metadata.labels.tier==<backend>", wantDirect: true},
+		{filter: "metadata.labels.app", wantDirect: true},
+		{filter: "metadata.labels.missing", wantDirect: true},
+		{filter: "metadata.deletionTimestamp", wantDirect: true},
+		{filter: "metadata.deletionTimestamp==null", wantDirect: true},
+		{filter: "metadata.labels==etl", wantDirect: true},
+		{filter: "metadata", wantDirect: true},
+		{filter: "spec.containers.0.image==etl:1.2", wantDirect: true},
+		{filter: "spec.containers.1.image==etl:1.2", wantDirect: true},
+		{filter: "spec.containers.name==etl", wantDirect: true},
+		{filter: "metadata.name.first==etl", wantDirect: true},
+		{filter: "spec.containers.#==1", wantDirect: false},
+		{filter: `status.conditions.#(type=="Ready").status=="True"`, wantDirect: false},
+		{filter: `status.conditions.#(type=="Ready").status=="False"`, wantDirect: false},
+		{filter: `metadata.labels.a*==etl`, wantDirect: false},
+		{filter: `metadata.name=="fake)"`, wantDirect: false},
+		{filter: `metadata..name==etl-7d9f`, wantDirect: false},
+		{filter: `==Pod`, wantDirect: false},
+		{filter: `status.phase=`, wantDirect: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.filter, func(t *testing.T) {
+			c := compile(tt.filter)
+			if c.direct != tt.wantDirect {
+				t.Errorf("compile() direct = %v, want %v", c.direct, tt.wantDirect)
+			}
+
+			object := newPod()
+			want, wantErr := passJSON(object, tt.filter)
+			got, err := Compile(tt.filter).Pass(object)
+			if (err != nil) != (wantErr != nil) {
+				t.Fatalf("Pass() error = %v, want %v", err, wantErr)
+			}
+			if got != want {
+				t.Errorf("Pass() = %v, want %v as evaluated against JSON", got, want)
+			}
+		})
+	}
+}
+
+func TestFilters_PassUnsupported(t *testing.T) {
+	object := newPod()
+	object["status"].(map[string]interface{})["phase"] = []string{"Running"}
+	object["spec"].(map[string]interface{})["replicas"] = float32(0.1)
+
+	for _, filter := range []string{
+		"status.phase",
+		"status.phase.0==Running",
+		"spec.replicas==0.1",
+	} {
+		want, _ := passJSON(object, filter)
+		got, err := Compile(filter).Pass(object)
+		if err != nil {
+			t.Fatalf("Pass(%q) error = %v", filter, err)
+		}
+		if got != want {
+			t.Errorf("Pass(%q) = %v, want %v as evaluated against JSON", filter, got, want)
+		}
+	}
+}
+
+func TestFilters_PassCached(t *testing.T) {
+	filters := Compile(`status.phase=="Running"`)
+	object := newPod()
+	metadata := object["metadata"].(map[string]interface{})
+	metadata["uid"], metadata["resourceVersion"] = "uid", "1"
+	status := object["status"].(map[string]interface{})
+
+	// The object of the same version is not evaluated again, even though it
+	// has been changed here, the cached result is returned
+	steps := []struct {
+		version string
+		phase   string
+		want    bool
+	}{
+		{version: "1", phase: "Running", want: true},
+		{version: "1", phase: "Failed", want: true},
+		{version: "2", phase: "Failed", want: false},
+		{version: "2", phase: "Running", want: false},
+	}
+	for i, s := range steps {
+		metadata["resourceVersion"], status["phase"] = s.version, s.phase
+		got, err := filters.Pass(object)
+		if err != nil {
+			t.Fatalf("Pass() step %d error = %v", i, err)
+		}
+		if got != s.want {
+			t.Errorf("Pass() step %d = %v, want %v", i, got, s.want)
+		}
+	}
+
+	filters.Forget("uid")
+	if got, _ := filters.Pass(object); !got {
+		t.Error("Pass() = false after Forget(), want the object evaluated again")
+	}
+
+	// The transitions of the same version are cached by the version of the old state
+	transitions := Compile(`_old.status.phase=="Pending"`)
+	old := newPod()
+	old["metadata"].(map[string]interface{})["resourceVersion"] = "1"
+	old["status"].(map[string]interface{})["phase"] = "Pending"
+	if got, _ := transitions.PassTransition(old, object); !got {
+		t.Error("PassTransition() = false, want true")
+	}
+	old["metadata"].(map[string]interface{})["resourceVersion"] = "0"
+	old["status"].(map[string]interface{})["phase"] = "Running"
+	if got, _ := transitions.PassTransition(old, object); got {
+		t.Error("PassTransition() = true for another old version, want it evaluated again")
+	}
+}
+
+// newLargeObject returns a Pod with many annotations, e.g. the last applied configuration
+func newLargeObject() map[string]interface{} {
+	object := newPod()
+	annotations := map[string]interface{}{}
+	for i := 0; i < 200; i++ {
+		annotations[fmt.Sprintf("example.com/annotation-%d", i)] = fmt.Sprintf("value of the annotation %d", i)
+	}
+	object["metadata"].(map[string]interface{})["annotations"] = annotations
+
+	return object
+}
+
+var benchmarkFilters = []string{
+	`kind=="Pod"`,
+	`status.phase=="Running"`,
+	`metadata.labels.app==etl`,
+	`spec.replicas>=3`,
+}
+
+func BenchmarkPass_JSON(b *testing.B) {
+	object := newLargeObject()
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		if ok, _ := passJSON(object, benchmarkFilters...); !ok {
+			b.Fatal("the object did not pass the filters")
+		}
+	}
+}
+
+func BenchmarkFilters_Pass(b *testing.B) {
+	object := newLargeObject()
+	filters := Compile(benchmarkFilters...)
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		if ok, _ := filters.Pass(object); !ok {
+			b.Fatal("the object did not pass the filters")
+		}
+	}
+}
+
+func BenchmarkFilters_PassQuery(b *testing.B) {
+	object := newLargeObject()
+	filters := Compile(append(benchmarkFilters, `status.conditions.#(type=="Ready").status=="False"`)...)
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		if ok, _ := filters.Pass(object); !ok {
+			b.Fatal("the object did not pass the filters")
+		}
+	}
+}
+
+func BenchmarkFilters_PassCached(b *testing.B) {
+	object := newLargeObject()
+	metadata := object["metadata"].(map[string]interface{})
+	metadata["uid"], metadata["resourceVersion"] = "uid", "1"
+	filters := Compile(benchmarkFilters...)
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		if ok, _ := filters.Pass(object); !ok {
+			b.Fatal("the object did not pass the filters")
+		}
+	}
+}
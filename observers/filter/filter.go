@@ -0,0 +1,88 @@
+package filter
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	logf "sigs.k8s.io/controller-runtime/pkg/runtime/log"
+)
+
+var log = logf.Log.WithName("filter")
+
+// unstructuredToJSON formats an object and returns JSON string
+func unstructuredToJSON(obj interface{}) string {
+	jsonified, err := json.Marshal(obj)
+	if err != nil {
+		panic(err)
+	}
+
+	return string(jsonified)
+}
+
+// ErrInvalidFilters is returned by the observers when the filters can not be evaluated
+var ErrInvalidFilters = fmt.Errorf("Unable to parse resource filters")
+
+// Pass checks whether the object passes all the given filters,
+// the filters evaluated against many objects should be compiled once instead
+func Pass(object map[string]interface{}, filters ...string) (bool, error) {
+	return Compile(filters...).Pass(object)
+}
+
+// Validate checks that the filter is well-formed by dry-running it
+// against an empty object
+func Validate(filter string) (err error) {
+	if strings.TrimSpace(filter) == "" {
+		return fmt.Errorf("filter must not be empty")
+	}
+
+	if err := checkBrackets(filter); err != nil {
+		return err
+	}
+
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("unable to evaluate filter %q: %v", filter, r)
+		}
+	}()
+
+	_, err = Pass(map[string]interface{}{}, filter)
+	return err
+}
+
+// checkBrackets checks that quotes are terminated and brackets are balanced,
+// since the filter engine silently ignores such errors
+func checkBrackets(filter string) error {
+	pairs := map[rune]rune{')': '(', ']': '[', '}': '{'}
+
+	var stack []rune
+	var quoted, escaped bool
+	for _, c := range filter {
+		switch {
+		case escaped:
+			escaped = false
+		case c == '\\':
+			escaped = true
+		case c == '"':
+			quoted = !quoted
+		case quoted:
+			continue
+		case c == '(' || c == '[' || c == '{':
+			stack = append(stack, c)
+		case pairs[c] != 0:
+			if len(stack) == 0 || stack[len(stack)-1] != pairs[c] {
+				return fmt.Errorf("filter %q has unbalanced %q", filter, c)
+			}
+			stack = stack[:len(stack)-1]
+		}
+	}
+
+	if quoted {
+		return fmt.Errorf("filter %q has an unterminated quote", filter)
+	}
+	if len(stack) > 0 {
+		return fmt.Errorf("filter %q has unbalanced %q", filter, stack[len(stack)-1])
+	}
+
+	return nil
+}
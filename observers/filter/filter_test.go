@@ -0,0 +1,56 @@
+package filter
+
+import (
+	"testing"
+)
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		filter  string
+		wantErr bool
+	}{
+		{
+			name:    "simple comparison",
+			filter:  "kind==Fake",
+			wantErr: false,
+		},
+		{
+			name:    "nested query",
+			filter:  `status.conditions.#(type=="Ready").status=="True"`,
+			wantErr: false,
+		},
+		{
+			name:    "quoted bracket",
+			filter:  `metadata.name=="fake)"`,
+			wantErr: false,
+		},
+		{
+			name:    "empty filter",
+			filter:  " ",
+			wantErr: true,
+		},
+		{
+			name:    "unbalanced parenthesis",
+			filter:  `status.conditions.#(type=="Ready"`,
+			wantErr: true,
+		},
+		{
+			name:    "mismatched brackets",
+			filter:  `status.conditions.#(type=="Ready"]`,
+			wantErr: true,
+		},
+		{
+			name:    "unterminated quote",
+			filter:  `metadata.name=="fake`,
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := Validate(tt.filter); (err != nil) != tt.wantErr {
+				t.Errorf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,61 @@
+package filter
+
+// OldKey is the key the filters reference the previous state of the object with,
+// e.g. _old.status.phase==Pending
+const OldKey = "_old"
+
+// PassTransition checks whether the transition of the object from the old state
+// passes all the given filters, there is no old state if it is nil
+func PassTransition(old, object map[string]interface{}, filters ...string) (bool, error) {
+	return Compile(filters...).PassTransition(old, object)
+}
+
+// Tracker evaluates the filters against the transitions of the objects,
+// it keeps the previous state of each object by its key, e.g. the UID
+type Tracker struct {
+	filters *Filters
+	changed bool
+
+	states map[string]state
+}
+
+// state is the previous state of an object
+type state struct {
+	object map[string]interface{}
+	passed bool
+}
+
+// NewTracker creates a new Tracker of the filters, in the changed mode
+// the objects pass only when they start to match the filters
+func NewTracker(changed bool, filters ...string) *Tracker {
+	return &Tracker{
+		filters: Compile(filters...),
+		changed: changed,
+		states:  map[string]state{},
+	}
+}
+
+// Pass evaluates the filters against the object and its previous state and
+// records the object as its state. In the changed mode, the object passes only
+// if its previous state has not passed and the objects seen for the first time
+// never pass, since their transition is not known.
+func (t *Tracker) Pass(key string, object map[string]interface{}) (bool, error) {
+	prev, seen := t.states[key]
+
+	ok, err := t.filters.PassTransition(prev.object, object)
+	if err != nil {
+		return false, err
+	}
+	t.states[key] = state{object: object, passed: ok}
+
+	if t.changed {
+		return ok && seen && !prev.passed, nil
+	}
+	return ok, nil
+}
+
+// Forget removes the state of the object, e.g. once it has been deleted
+func (t *Tracker) Forget(key string) {
+	delete(t.states, key)
+	t.filters.Forget(key)
+}
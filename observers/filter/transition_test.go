@@ -0,0 +1,84 @@
+package filter
+
+import (
+	"testing"
+)
+
+func pod(phase string) map[string]interface{} {
+	return map[string]interface{}{
+		"kind":   "Pod",
+		"status": map[string]interface{}{"phase": phase},
+	}
+}
+
+func TestTracker_Pass(t *testing.T) {
+	type step struct {
+		key    string
+		object map[string]interface{}
+		want   bool
+	}
+
+	tests := []struct {
+		name    string
+		changed bool
+		filters []string
+		steps   []step
+	}{
+		{
+			name:    "level mode passes whenever matched",
+			filters: []string{"status.phase==Running"},
+			steps: []step{
+				{key: "a", object: pod("Running"), want: true},
+				{key: "a", object: pod("Running"), want: true},
+			},
+		},
+		{
+			name:    "changed mode passes only on transitions",
+			changed: true,
+			filters: []string{"status.phase==Running"},
+			steps: []step{
+				{key: "a", object: pod("Running"), want: false},
+				{key: "a", object: pod("Running"), want: false},
+				{key: "a", object: pod("Pending"), want: false},
+				{key: "a", object: pod("Running"), want: true},
+			},
+		},
+		{
+			name:    "filters reference the previous state",
+			filters: []string{"_old.status.phase==Pending", "status.phase==Running"},
+			steps: []step{
+				{key: "a", object: pod("Running"), want: false},
+				{key: "b", object: pod("Pending"), want: false},
+				{key: "a", object: pod("Running"), want: false},
+				{key: "b", object: pod("Running"), want: true},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tracker := NewTracker(tt.changed, tt.filters...)
+			for i, s := range tt.steps {
+				got, err := tracker.Pass(s.key, s.object)
+				if err != nil {
+					t.Fatalf("Tracker.Pass() step %d error = %v", i, err)
+				}
+				if got != s.want {
+					t.Errorf("Tracker.Pass() step %d = %v, want %v", i, got, s.want)
+				}
+			}
+		})
+	}
+}
+
+func TestTracker_Forget(t *testing.T) {
+	tracker := NewTracker(true, "status.phase==Running")
+	if _, err := tracker.Pass("a", pod("Pending")); err != nil {
+		t.Fatal(err)
+	}
+	tracker.Forget("a")
+
+	// the object is seen for the first time again
+	if got, _ := tracker.Pass("a", pod("Running")); got {
+		t.Errorf("Tracker.Pass() = %v after Forget(), want false", got)
+	}
+}
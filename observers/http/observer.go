@@ -0,0 +1,181 @@
+package http
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	nethttp "net/http"
+	"time"
+
+	v1alpha1 "github.com/cermakm/argo-await-operator/api/v1alpha1"
+	"github.com/cermakm/argo-await-operator/metrics"
+	"github.com/cermakm/argo-await-operator/observers/filter"
+	"github.com/cermakm/argo-await-operator/observers/stability"
+	"github.com/cermakm/argo-await-operator/tracing"
+	"go.opentelemetry.io/otel/label"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/util/clock"
+
+	logf "sigs.k8s.io/controller-runtime/pkg/runtime/log"
+)
+
+var log = logf.Log.WithName("http-observer")
+
+// maxBodySize is the maximum size of the response body read by the Observer
+const maxBodySize = 1 << 20
+
+// Observer polls an HTTP endpoint
+type Observer struct {
+	client *nethttp.Client
+
+	namespace string
+	endpoint  *v1alpha1.HTTPEndpoint
+	filters   []string
+
+	// tracker keeps the previous response body to evaluate the filters against
+	tracker *filter.Tracker
+	// window holds the response body passing the filters until it is stable
+	window *stability.Window
+	clock  clock.Clock
+}
+
+// NewObserver creates a new Observer polling the endpoint using the given client,
+// the endpoint is fulfilled once the response body passes the filters for stableFor
+func NewObserver(endpoint *v1alpha1.HTTPEndpoint, filters []string, mode v1alpha1.FilterMode, stableFor time.Duration, namespace string, client *nethttp.Client) *Observer {
+	return newObserver(endpoint, filters, mode, stableFor, namespace, client, clock.RealClock{})
+}
+
+// newObserver creates a new Observer polling the endpoint at the intervals of the clock
+func newObserver(endpoint *v1alpha1.HTTPEndpoint, filters []string, mode v1alpha1.FilterMode, stableFor time.Duration,
+	namespace string, client *nethttp.Client, clk clock.Clock) *Observer {
+	if client == nil {
+		client = nethttp.DefaultClient
+	}
+
+	return &Observer{
+		client:    client,
+		namespace: namespace,
+		endpoint:  endpoint,
+		filters:   filters,
+		tracker:   filter.NewTracker(mode == v1alpha1.FilterModeChanged, filters...),
+		window:    stability.NewWindow(stableFor, clk),
+		clock:     clk,
+	}
+}
+
+// Await polls the endpoint until it responds with the expected status code
+// and a JSON body passing the filters and calls the callback with the body
+func (obs *Observer) Await(ctx context.Context, callback func(ctx context.Context, obj *unstructured.Unstructured) error) error {
+	interval := v1alpha1.DefaultHTTPInterval
+	if obs.endpoint.Interval != nil && obs.endpoint.Interval.Duration > 0 {
+		interval = obs.endpoint.Interval.Duration
+	}
+
+	log := log.WithValues("url", obs.endpoint.URL, "interval", interval)
+	log.Info("polling the endpoint")
+
+	metrics.ActiveObservers.WithLabelValues(v1alpha1.ObservedKindHTTP, obs.namespace).Inc()
+	defer metrics.ActiveObservers.WithLabelValues(v1alpha1.ObservedKindHTTP, obs.namespace).Dec()
+
+	for {
+		object, err := obs.observe(ctx)
+		switch {
+		case err == filter.ErrInvalidFilters:
+			return err
+		case err != nil:
+			log.Info("endpoint is not ready", "reason", err.Error())
+		case object != nil:
+			log.Info("endpoint fulfilled")
+
+			// Execute the callback function and return
+			return callback(ctx, &unstructured.Unstructured{Object: object})
+		}
+
+		// The next poll is an interval after the previous one has finished
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-obs.clock.After(interval):
+		}
+	}
+}
+
+// observe polls the endpoint once and returns the response body
+// if it has been passing the filters for the stable window, nil otherwise
+func (obs *Observer) observe(ctx context.Context) (map[string]interface{}, error) {
+	metrics.EventsProcessed.WithLabelValues(v1alpha1.ObservedKindHTTP, obs.namespace).Inc()
+
+	object, err := obs.poll(ctx)
+	if err == filter.ErrInvalidFilters {
+		return nil, err
+	}
+	// An unavailable endpoint resets the window as well
+	obs.window.Observe(obs.endpoint.URL, object, err == nil && object != nil)
+
+	if object, ok := obs.window.Stable(); ok {
+		return object, nil
+	}
+	return nil, err
+}
+
+// poll sends a single request to the endpoint and returns the response body
+// if it fulfilled the endpoint, nil otherwise
+func (obs *Observer) poll(ctx context.Context) (map[string]interface{}, error) {
+	ctx, span := tracing.StartSpan(ctx, "Poll",
+		label.String("http.url", obs.endpoint.URL),
+	)
+	defer span.End()
+
+	req, err := nethttp.NewRequest(nethttp.MethodGet, obs.endpoint.URL, nil)
+	if err != nil {
+		return nil, err
+	}
+	req = req.WithContext(ctx)
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := obs.client.Do(req)
+	if err != nil {
+		tracing.RecordError(ctx, span, err)
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	span.SetAttributes(label.Int("http.status_code", resp.StatusCode))
+	if !obs.statusMatches(resp.StatusCode) {
+		_, _ = io.Copy(ioutil.Discard, resp.Body)
+		log.V(1).Info("status code does not match", "statusCode", resp.StatusCode)
+		return nil, nil
+	}
+
+	object := map[string]interface{}{}
+	if err := json.NewDecoder(io.LimitReader(resp.Body, maxBodySize)).Decode(&object); err != nil && len(obs.filters) > 0 {
+		// The body is required to evaluate the filters
+		return nil, err
+	}
+
+	metrics.FilterEvaluations.WithLabelValues(v1alpha1.ObservedKindHTTP, obs.namespace).Inc()
+	ok, err := obs.tracker.Pass(obs.endpoint.URL, object)
+	span.SetAttributes(label.Bool("filters.passed", ok))
+	if err != nil {
+		tracing.RecordError(ctx, span, err)
+		metrics.FilterErrors.WithLabelValues(v1alpha1.ObservedKindHTTP, obs.namespace).Inc()
+		return nil, filter.ErrInvalidFilters
+	}
+	if !ok {
+		log.V(1).Info("response did not pass the filters")
+		return nil, nil
+	}
+
+	return object, nil
+}
+
+// statusMatches returns whether the status code is the expected one
+func (obs *Observer) statusMatches(code int) bool {
+	if obs.endpoint.StatusCode == 0 {
+		return code >= nethttp.StatusOK && code < nethttp.StatusMultipleChoices
+	}
+
+	return code == int(obs.endpoint.StatusCode)
+}
@@ -0,0 +1,240 @@
+package http
+
+import (
+	"context"
+	"encoding/json"
+	nethttp "net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	v1alpha1 "github.com/cermakm/argo-await-operator/api/v1alpha1"
+	"github.com/cermakm/argo-await-operator/observers/filter"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/util/clock"
+)
+
+// statusServer responds with the next of the responses on each request
+// and keeps responding with the last one
+type statusServer struct {
+	mu        sync.Mutex
+	responses []response
+	requests  int
+}
+
+type response struct {
+	code int
+	body map[string]interface{}
+}
+
+func (s *statusServer) ServeHTTP(w nethttp.ResponseWriter, req *nethttp.Request) {
+	s.mu.Lock()
+	resp := s.responses[len(s.responses)-1]
+	if s.requests < len(s.responses) {
+		resp = s.responses[s.requests]
+	}
+	s.requests++
+	s.mu.Unlock()
+
+	w.WriteHeader(resp.code)
+	_ = json.NewEncoder(w).Encode(resp.body)
+}
+
+func TestObserver_Await(t *testing.T) {
+	ready := map[string]interface{}{"status": "ready"}
+	starting := map[string]interface{}{"status": "starting"}
+
+	tests := []struct {
+		name         string
+		responses    []response
+		statusCode   int32
+		filters      []string
+		mode         v1alpha1.FilterMode
+		wantRequests int
+	}{
+		{
+			name:         "Any successful response",
+			responses:    []response{{nethttp.StatusServiceUnavailable, nil}, {nethttp.StatusOK, ready}},
+			wantRequests: 2,
+		},
+		{
+			name:         "Expected status code",
+			responses:    []response{{nethttp.StatusOK, ready}, {nethttp.StatusNoContent, nil}},
+			statusCode:   nethttp.StatusNoContent,
+			wantRequests: 2,
+		},
+		{
+			name:         "Body passing the filters",
+			responses:    []response{{nethttp.StatusOK, starting}, {nethttp.StatusOK, starting}, {nethttp.StatusOK, ready}},
+			filters:      []string{`status=="ready"`},
+			wantRequests: 3,
+		},
+		{
+			name:         "Body changed to pass the filters",
+			responses:    []response{{nethttp.StatusOK, ready}, {nethttp.StatusOK, starting}, {nethttp.StatusOK, ready}},
+			filters:      []string{`status=="ready"`},
+			mode:         v1alpha1.FilterModeChanged,
+			wantRequests: 3,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := &statusServer{responses: tt.responses}
+			ts := httptest.NewServer(server)
+			defer ts.Close()
+
+			endpoint := &v1alpha1.HTTPEndpoint{
+				URL:        ts.URL,
+				Interval:   &metav1.Duration{Duration: time.Millisecond},
+				StatusCode: tt.statusCode,
+			}
+			obs := NewObserver(endpoint, tt.filters, tt.mode, 0, "default", ts.Client())
+
+			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+
+			var called bool
+			err := obs.Await(ctx, func(ctx context.Context, obj *unstructured.Unstructured) error {
+				called = true
+				return nil
+			})
+			if err != nil {
+				t.Fatalf("Await() error = %v", err)
+			}
+			if !called {
+				t.Errorf("Await() callback not called")
+			}
+			if server.requests != tt.wantRequests {
+				t.Errorf("Await() requests = %v, want %v", server.requests, tt.wantRequests)
+			}
+		})
+	}
+}
+
+func TestObserver_AwaitCancelled(t *testing.T) {
+	ts := httptest.NewServer(&statusServer{responses: []response{{nethttp.StatusServiceUnavailable, nil}}})
+	defer ts.Close()
+
+	endpoint := &v1alpha1.HTTPEndpoint{URL: ts.URL, Interval: &metav1.Duration{Duration: time.Millisecond}}
+	obs := NewObserver(endpoint, nil, "", 0, "default", ts.Client())
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	err := obs.Await(ctx, func(ctx context.Context, obj *unstructured.Unstructured) error {
+		t.Error("Await() callback called for an unavailable endpoint")
+		return nil
+	})
+	if err != context.DeadlineExceeded {
+		t.Errorf("Await() error = %v, want %v", err, context.DeadlineExceeded)
+	}
+}
+
+func TestObserver_observeStable(t *testing.T) {
+	ready := map[string]interface{}{"status": "ready"}
+	starting := map[string]interface{}{"status": "starting"}
+
+	server := &statusServer{responses: []response{
+		{nethttp.StatusOK, ready},
+		{nethttp.StatusOK, starting},
+		{nethttp.StatusOK, ready},
+		{nethttp.StatusServiceUnavailable, nil},
+		{nethttp.StatusOK, ready},
+		{nethttp.StatusOK, ready},
+		{nethttp.StatusOK, ready},
+	}}
+	ts := httptest.NewServer(server)
+	defer ts.Close()
+
+	endpoint := &v1alpha1.HTTPEndpoint{URL: ts.URL}
+	fakeClock := clock.NewFakeClock(time.Date(2019, 9, 1, 12, 0, 0, 0, time.UTC))
+	obs := newObserver(endpoint, []string{`status=="ready"`}, "", 2*time.Second, "default", ts.Client(), fakeClock)
+
+	// The body passes the filters from the fifth response on, stable two seconds later
+	wantStable := []bool{false, false, false, false, false, false, true}
+	for i, want := range wantStable {
+		if i > 0 {
+			fakeClock.Step(time.Second)
+		}
+
+		object, err := obs.observe(context.Background())
+		if err == filter.ErrInvalidFilters {
+			t.Fatalf("observe() error = %v", err)
+		}
+		if got := object != nil; got != want {
+			t.Errorf("observe() at %d fulfilled = %v, want %v", i, got, want)
+		}
+	}
+}
+
+// polled returns the number of the requests served
+func (s *statusServer) polled() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.requests
+}
+
+func TestObserver_AwaitStable(t *testing.T) {
+	ready := map[string]interface{}{"status": "ready"}
+	starting := map[string]interface{}{"status": "starting"}
+
+	tests := []struct {
+		name      string
+		responses []response
+		// wantRequests is the number of the polls until the body is stable
+		wantRequests int
+	}{
+		{
+			name:         "Passing for the stable window",
+			responses:    []response{{nethttp.StatusOK, ready}},
+			wantRequests: 3,
+		},
+		{
+			name:         "Not passing before the end of the stable window",
+			responses:    []response{{nethttp.StatusOK, ready}, {nethttp.StatusOK, starting}, {nethttp.StatusOK, ready}},
+			wantRequests: 5,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := &statusServer{responses: tt.responses}
+			ts := httptest.NewServer(server)
+			defer ts.Close()
+
+			endpoint := &v1alpha1.HTTPEndpoint{URL: ts.URL, Interval: &metav1.Duration{Duration: time.Second}}
+			fakeClock := clock.NewFakeClock(time.Date(2019, 9, 1, 12, 0, 0, 0, time.UTC))
+			obs := newObserver(endpoint, []string{`status=="ready"`}, "", 2*time.Second, "default", ts.Client(), fakeClock)
+
+			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+
+			errs := make(chan error, 1)
+			go func() {
+				errs <- obs.Await(ctx, func(ctx context.Context, obj *unstructured.Unstructured) error {
+					return nil
+				})
+			}()
+
+			// Every interval is stepped once the Observer waits for the next poll
+			for polled := 1; polled < tt.wantRequests; polled++ {
+				for !fakeClock.HasWaiters() {
+					if ctx.Err() != nil {
+						t.Fatalf("the endpoint has not been polled after %d requests", server.polled())
+					}
+					time.Sleep(time.Millisecond)
+				}
+				fakeClock.Step(time.Second)
+			}
+
+			if err := <-errs; err != nil {
+				t.Fatalf("Await() error = %v", err)
+			}
+			if got := server.polled(); got != tt.wantRequests {
+				t.Errorf("Await() requests = %v, want %v", got, tt.wantRequests)
+			}
+		})
+	}
+}
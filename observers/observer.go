@@ -0,0 +1,90 @@
+package observers
+
+import (
+	"context"
+	"sync"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+// Result is the outcome of an Observer, either the matched object or the error
+// the observing failed with
+type Result struct {
+	Object *unstructured.Unstructured
+	Err    error
+}
+
+// Failure is the error of an Observer when the Await can never be fulfilled,
+// the Await fails with the reason and the message
+type Failure struct {
+	Reason  string
+	Message string
+}
+
+func (f *Failure) Error() string {
+	return f.Message
+}
+
+// Observer observes whatever an Await is waiting for
+type Observer interface {
+	// Start starts observing in the background until the Result is sent
+	// or the context is cancelled
+	Start(ctx context.Context)
+	// Result returns the channel the single Result of the Observer is sent to
+	Result() <-chan Result
+	// Describe returns a human readable description of what is observed
+	Describe() string
+}
+
+// Awaiter awaits an object and calls the callback with it, the built-in observers are Awaiters
+type Awaiter interface {
+	Await(ctx context.Context, callback func(ctx context.Context, obj *unstructured.Unstructured) error) error
+}
+
+// awaiterObserver adapts an Awaiter to the Observer interface
+type awaiterObserver struct {
+	awaiter     Awaiter
+	description string
+
+	once   sync.Once
+	result chan Result
+}
+
+// FromAwaiter creates an Observer sending the object awaited by the Awaiter as its Result
+func FromAwaiter(awaiter Awaiter, description string) Observer {
+	return &awaiterObserver{
+		awaiter:     awaiter,
+		description: description,
+		result:      make(chan Result, 1),
+	}
+}
+
+// Start implements Observer
+func (o *awaiterObserver) Start(ctx context.Context) {
+	go func() {
+		err := o.awaiter.Await(ctx, func(ctx context.Context, obj *unstructured.Unstructured) error {
+			o.send(Result{Object: obj})
+			return nil
+		})
+		if err != nil {
+			o.send(Result{Err: err})
+		}
+	}()
+}
+
+// send sends the Result unless one has already been sent
+func (o *awaiterObserver) send(result Result) {
+	o.once.Do(func() {
+		o.result <- result
+	})
+}
+
+// Result implements Observer
+func (o *awaiterObserver) Result() <-chan Result {
+	return o.result
+}
+
+// Describe implements Observer
+func (o *awaiterObserver) Describe() string {
+	return o.description
+}
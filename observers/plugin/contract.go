@@ -0,0 +1,268 @@
+// Package plugin implements the observers served by the out-of-process plugins.
+//
+// The plugins implement the ObserverPlugin gRPC service. The messages are encoded
+// as JSON (the "application/grpc+json" content type) so that the plugins can be
+// implemented in any language without a shared protobuf definition:
+//
+//   - Register returns the observer types the plugin provides, the types are
+//     awaited by setting spec.observer.type of the Awaits
+//   - Watch starts observing for an Await, repeated calls with the same ID
+//     must not start another observation
+//   - Result streams the progress of the observation of the ID, followed by
+//     a single message with either the matched object or the error. The stream
+//     fails with the NotFound code if the ID is not being observed, e.g. after
+//     the plugin has been restarted, which makes the operator call Watch again.
+//   - Cancel stops observing for the ID once the Await is deleted or finished,
+//     the plugins not implementing it keep the observation until its result
+package plugin
+
+import (
+	"context"
+	"encoding/json"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/encoding"
+)
+
+// ServiceName is the full name of the gRPC service of the plugins
+const ServiceName = "await.observer.v1.ObserverPlugin"
+
+// ProtocolVersion is the version of the contract sent to the plugins on registration
+const ProtocolVersion = "v1"
+
+// RegisterRequest is sent by the operator to discover the plugin
+type RegisterRequest struct {
+	ProtocolVersion string `json:"protocolVersion"`
+}
+
+// RegisterResponse lists the observer types of the plugin
+type RegisterResponse struct {
+	Types []ObserverType `json:"types"`
+}
+
+// ObserverType is an observer type provided by the plugin
+type ObserverType struct {
+	Name        string `json:"name"`
+	Description string `json:"description,omitempty"`
+}
+
+// AwaitRef identifies the observed Await
+type AwaitRef struct {
+	Name      string `json:"name"`
+	Namespace string `json:"namespace"`
+	UID       string `json:"uid"`
+}
+
+// WatchRequest starts observing for an Await
+type WatchRequest struct {
+	// ID identifies the observation, it is the UID of the Await
+	ID         string            `json:"id"`
+	Type       string            `json:"type"`
+	Await      AwaitRef          `json:"await"`
+	Parameters map[string]string `json:"parameters,omitempty"`
+	// Filters are to be evaluated by the plugin against the matched object
+	Filters []string `json:"filters,omitempty"`
+	// FilterMode is the mode the filters are evaluated in, either Level or Changed
+	FilterMode string `json:"filterMode,omitempty"`
+	// StableFor is the duration the filters have to be passed for, e.g. "30s",
+	// the plugin is expected to send the Result only once the object is stable
+	StableFor string `json:"stableFor,omitempty"`
+}
+
+// WatchResponse acknowledges the WatchRequest
+type WatchResponse struct{}
+
+// ResultRequest subscribes to the Result of the observation
+type ResultRequest struct {
+	ID string `json:"id"`
+}
+
+// CancelRequest stops the observation
+type CancelRequest struct {
+	ID string `json:"id"`
+}
+
+// CancelResponse acknowledges the CancelRequest
+type CancelResponse struct{}
+
+// ResultResponse is either a progress of the observation or its final result,
+// the result is final if either the Object or the Error is set
+type ResultResponse struct {
+	Progress string                 `json:"progress,omitempty"`
+	Object   map[string]interface{} `json:"object,omitempty"`
+	Error    string                 `json:"error,omitempty"`
+	// Reason is the reason of the Error the Await fails with, PluginFailed if not set
+	Reason string `json:"reason,omitempty"`
+}
+
+// codecName is the content subtype of the messages
+const codecName = "json"
+
+// codec encodes the messages as JSON
+type codec struct{}
+
+func (codec) Marshal(v interface{}) ([]byte, error) {
+	return json.Marshal(v)
+}
+
+func (codec) Unmarshal(data []byte, v interface{}) error {
+	return json.Unmarshal(data, v)
+}
+
+func (codec) Name() string {
+	return codecName
+}
+
+func init() {
+	encoding.RegisterCodec(codec{})
+}
+
+// ObserverPluginServer is the server API of the plugins
+type ObserverPluginServer interface {
+	Register(context.Context, *RegisterRequest) (*RegisterResponse, error)
+	Watch(context.Context, *WatchRequest) (*WatchResponse, error)
+	Result(*ResultRequest, ResultServer) error
+	Cancel(context.Context, *CancelRequest) (*CancelResponse, error)
+}
+
+// ResultServer is the server side of the Result stream
+type ResultServer interface {
+	Send(*ResultResponse) error
+	grpc.ServerStream
+}
+
+type resultServer struct {
+	grpc.ServerStream
+}
+
+func (s *resultServer) Send(m *ResultResponse) error {
+	return s.ServerStream.SendMsg(m)
+}
+
+// RegisterObserverPluginServer registers the plugin with the gRPC server
+func RegisterObserverPluginServer(s *grpc.Server, srv ObserverPluginServer) {
+	s.RegisterService(&serviceDesc, srv)
+}
+
+func registerHandler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(RegisterRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ObserverPluginServer).Register(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{Server: srv, FullMethod: "/" + ServiceName + "/Register"}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ObserverPluginServer).Register(ctx, req.(*RegisterRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func watchHandler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(WatchRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ObserverPluginServer).Watch(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{Server: srv, FullMethod: "/" + ServiceName + "/Watch"}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ObserverPluginServer).Watch(ctx, req.(*WatchRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func cancelHandler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(CancelRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ObserverPluginServer).Cancel(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{Server: srv, FullMethod: "/" + ServiceName + "/Cancel"}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ObserverPluginServer).Cancel(ctx, req.(*CancelRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func resultHandler(srv interface{}, stream grpc.ServerStream) error {
+	in := new(ResultRequest)
+	if err := stream.RecvMsg(in); err != nil {
+		return err
+	}
+	return srv.(ObserverPluginServer).Result(in, &resultServer{stream})
+}
+
+var serviceDesc = grpc.ServiceDesc{
+	ServiceName: ServiceName,
+	HandlerType: (*ObserverPluginServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{MethodName: "Register", Handler: registerHandler},
+		{MethodName: "Watch", Handler: watchHandler},
+		{MethodName: "Cancel", Handler: cancelHandler},
+	},
+	Streams: []grpc.StreamDesc{
+		{StreamName: "Result", Handler: resultHandler, ServerStreams: true},
+	},
+}
+
+// pluginClient is the client API of the plugins
+type pluginClient struct {
+	conn *grpc.ClientConn
+}
+
+func (c *pluginClient) Register(ctx context.Context, in *RegisterRequest, opts ...grpc.CallOption) (*RegisterResponse, error) {
+	out := new(RegisterResponse)
+	if err := c.conn.Invoke(ctx, "/"+ServiceName+"/Register", in, out, opts...); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *pluginClient) Watch(ctx context.Context, in *WatchRequest) (*WatchResponse, error) {
+	out := new(WatchResponse)
+	if err := c.conn.Invoke(ctx, "/"+ServiceName+"/Watch", in, out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *pluginClient) Cancel(ctx context.Context, in *CancelRequest) (*CancelResponse, error) {
+	out := new(CancelResponse)
+	if err := c.conn.Invoke(ctx, "/"+ServiceName+"/Cancel", in, out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// resultClient is the client side of the Result stream
+type resultClient struct {
+	grpc.ClientStream
+}
+
+func (c *resultClient) Recv() (*ResultResponse, error) {
+	m := new(ResultResponse)
+	if err := c.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func (c *pluginClient) Result(ctx context.Context, in *ResultRequest) (*resultClient, error) {
+	stream, err := c.conn.NewStream(ctx, &serviceDesc.Streams[0], "/"+ServiceName+"/Result")
+	if err != nil {
+		return nil, err
+	}
+	x := &resultClient{stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
@@ -0,0 +1,101 @@
+package plugin
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/cermakm/argo-await-operator/observers"
+
+	"google.golang.org/grpc"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// SocketSuffix is the suffix of the sockets of the sidecar plugins
+const SocketSuffix = ".sock"
+
+// DiscoverSockets returns the addresses of the plugins listening on the sockets in the
+// directory keyed by the names of the plugins, which are the names of the sockets
+func DiscoverSockets(dir string) (map[string]string, error) {
+	files, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	addresses := map[string]string{}
+	for _, f := range files {
+		if f.Mode()&os.ModeSocket == 0 || !strings.HasSuffix(f.Name(), SocketSuffix) {
+			continue
+		}
+		name := strings.TrimSuffix(f.Name(), SocketSuffix)
+		addresses[name] = "unix://" + filepath.Join(dir, f.Name())
+	}
+
+	return addresses, nil
+}
+
+// DiscoverConfigMap returns the addresses of the plugins listed in the ConfigMap,
+// the keys of the data are the names of the plugins and the values their addresses
+func DiscoverConfigMap(ctx context.Context, reader client.Reader, key types.NamespacedName) (map[string]string, error) {
+	cm := &corev1.ConfigMap{}
+	if err := reader.Get(ctx, key, cm); err != nil {
+		return nil, err
+	}
+
+	addresses := map[string]string{}
+	for name, address := range cm.Data {
+		addresses[name] = strings.TrimSpace(address)
+	}
+
+	return addresses, nil
+}
+
+// Load dials the plugins at the addresses keyed by their names and registers
+// their observer types with the Registry. The plugins which can not be reached
+// are skipped so that a single plugin can not keep the operator from starting,
+// the Awaits of their types fail as of an unknown observer type.
+func Load(ctx context.Context, registry *observers.Registry, addresses map[string]string, opts ...grpc.DialOption) ([]*Plugin, error) {
+	names := make([]string, 0, len(addresses))
+	for name := range addresses {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var plugins []*Plugin
+	for _, name := range names {
+		p, err := Dial(ctx, name, addresses[name], opts...)
+		if err != nil {
+			log.Error(err, "plugin skipped", "plugin", name, "address", addresses[name])
+			continue
+		}
+		if err := p.RegisterWith(registry); err != nil {
+			p.Close()
+			for _, p := range plugins {
+				p.Close()
+			}
+			return nil, err
+		}
+
+		log.Info("plugin registered", "plugin", name, "address", addresses[name], "types", p.Types())
+		plugins = append(plugins, p)
+	}
+
+	return plugins, nil
+}
+
+// ParseConfigMapKey parses the namespace/name of the ConfigMap listing the plugins
+func ParseConfigMapKey(s string) (types.NamespacedName, error) {
+	parts := strings.Split(s, "/")
+	if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+		return types.NamespacedName{}, fmt.Errorf("invalid configmap %q, expected namespace/name", s)
+	}
+
+	return types.NamespacedName{Namespace: parts[0], Name: parts[1]}, nil
+}
@@ -0,0 +1,279 @@
+package plugin
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strings"
+	"time"
+
+	v1alpha1 "github.com/cermakm/argo-await-operator/api/v1alpha1"
+	"github.com/cermakm/argo-await-operator/metrics"
+	"github.com/cermakm/argo-await-operator/observers"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/util/wait"
+
+	logf "sigs.k8s.io/controller-runtime/pkg/runtime/log"
+)
+
+var log = logf.Log.WithName("plugin-observer")
+
+// ReasonPluginFailed is the reason of the failures reported by the plugins without one
+const ReasonPluginFailed = "PluginFailed"
+
+// DefaultBackoff is the backoff of reconnecting the broken Result streams
+var DefaultBackoff = wait.Backoff{
+	Duration: 500 * time.Millisecond,
+	Factor:   2.0,
+	Jitter:   0.1,
+	Steps:    10,
+	Cap:      30 * time.Second,
+}
+
+// registerTimeout is the timeout of registering a plugin, the plugins
+// which can not be reached meanwhile are not registered
+var registerTimeout = 10 * time.Second
+
+// cancelTimeout is the timeout of cancelling an observation
+const cancelTimeout = 5 * time.Second
+
+// errStreamClosed is returned when the Result stream is closed without the final result
+var errStreamClosed = errors.New("result stream closed without a result")
+
+// Plugin is a connection to a registered plugin
+type Plugin struct {
+	Name string
+
+	conn   *grpc.ClientConn
+	client *pluginClient
+	types  []ObserverType
+
+	// backoff is the backoff of reconnecting the Result streams of the Observers
+	backoff wait.Backoff
+}
+
+// Dial connects to the plugin at the address and registers it, the address
+// is either a gRPC target or unix:// followed by the path of the socket.
+// The connection is not secured, the plugins are expected to run as sidecars
+// or within the cluster network. The plugin is waited for until the register
+// timeout, the connection is then kept up in the background.
+func Dial(ctx context.Context, name, address string, opts ...grpc.DialOption) (*Plugin, error) {
+	opts = append([]grpc.DialOption{
+		grpc.WithInsecure(),
+		grpc.WithContextDialer(dialContext),
+		grpc.WithDefaultCallOptions(grpc.CallContentSubtype(codecName)),
+	}, opts...)
+
+	conn, err := grpc.DialContext(ctx, address, opts...)
+	if err != nil {
+		return nil, fmt.Errorf("dial plugin %s at %s: %v", name, address, err)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, registerTimeout)
+	defer cancel()
+
+	p := &Plugin{Name: name, conn: conn, client: &pluginClient{conn: conn}, backoff: DefaultBackoff}
+	resp, err := p.client.Register(ctx, &RegisterRequest{ProtocolVersion: ProtocolVersion}, grpc.WaitForReady(true))
+	if err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("register plugin %s: %v", name, err)
+	}
+	p.types = resp.Types
+
+	return p, nil
+}
+
+// dialContext dials the unix sockets given as unix://<path>, the other addresses over TCP
+func dialContext(ctx context.Context, address string) (net.Conn, error) {
+	network := "tcp"
+	if strings.HasPrefix(address, "unix://") {
+		network, address = "unix", strings.TrimPrefix(address, "unix://")
+	}
+
+	var d net.Dialer
+	return d.DialContext(ctx, network, address)
+}
+
+// Types returns the observer types provided by the plugin
+func (p *Plugin) Types() []ObserverType {
+	return p.types
+}
+
+// Close closes the connection to the plugin
+func (p *Plugin) Close() error {
+	return p.conn.Close()
+}
+
+// RegisterWith registers the observer types of the plugin with the Registry
+func (p *Plugin) RegisterWith(registry *observers.Registry) error {
+	for _, t := range p.types {
+		observerType := t.Name
+		err := registry.Register(observerType, func(res *v1alpha1.Await, opts observers.Options) (observers.Observer, error) {
+			return p.NewObserver(observerType, res), nil
+		})
+		if err != nil {
+			return fmt.Errorf("plugin %s: %v", p.Name, err)
+		}
+	}
+
+	return nil
+}
+
+// Observer observes an Await by a plugin
+type Observer struct {
+	plugin  *Plugin
+	request *WatchRequest
+
+	result chan observers.Result
+}
+
+// NewObserver creates a new Observer of the Await by the observer type of the plugin
+func (p *Plugin) NewObserver(observerType string, res *v1alpha1.Await) *Observer {
+	req := &WatchRequest{
+		ID:   string(res.UID),
+		Type: observerType,
+		Await: AwaitRef{
+			Name:      res.Name,
+			Namespace: res.Namespace,
+			UID:       string(res.UID),
+		},
+		Filters:    res.Spec.Filters,
+		FilterMode: string(res.Spec.FilterMode),
+	}
+	if res.Spec.StableFor != nil {
+		req.StableFor = res.Spec.StableFor.Duration.String()
+	}
+	if res.Spec.Observer != nil {
+		req.Parameters = res.Spec.Observer.Parameters
+	}
+
+	return &Observer{
+		plugin:  p,
+		request: req,
+		result:  make(chan observers.Result, 1),
+	}
+}
+
+// Start implements observers.Observer, the broken Result streams are
+// reconnected and the observation is started again if the plugin has lost it.
+// The observation is cancelled in the plugin once the context is cancelled.
+func (obs *Observer) Start(ctx context.Context) {
+	go func() {
+		metrics.ActiveObservers.WithLabelValues(obs.request.Type, obs.request.Await.Namespace).Inc()
+		defer metrics.ActiveObservers.WithLabelValues(obs.request.Type, obs.request.Await.Namespace).Dec()
+
+		backoff := obs.plugin.backoff
+		for {
+			result, streamed, err := obs.watch(ctx)
+			if ctx.Err() != nil {
+				obs.cancel()
+				obs.result <- observers.Result{Err: ctx.Err()}
+				return
+			}
+			if err == nil {
+				obs.result <- result
+				return
+			}
+			if !retriable(err) {
+				obs.result <- observers.Result{Err: fmt.Errorf("plugin %s: %v", obs.plugin.Name, err)}
+				return
+			}
+
+			if streamed {
+				// The stream has been working, the reconnecting starts over
+				backoff = obs.plugin.backoff
+			}
+			delay := backoff.Step()
+			log.Info("result stream broken, reconnecting", "plugin", obs.plugin.Name, "id", obs.request.ID, "error", err.Error(), "delay", delay)
+			select {
+			case <-ctx.Done():
+				obs.cancel()
+				obs.result <- observers.Result{Err: ctx.Err()}
+				return
+			case <-time.After(delay):
+			}
+		}
+	}()
+}
+
+// watch starts the observation and waits for its final result, streamed
+// is whether any message has been received from the Result stream
+func (obs *Observer) watch(ctx context.Context) (result observers.Result, streamed bool, err error) {
+	if _, err := obs.plugin.client.Watch(ctx, obs.request); err != nil {
+		return observers.Result{}, false, err
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	stream, err := obs.plugin.client.Result(ctx, &ResultRequest{ID: obs.request.ID})
+	if err != nil {
+		return observers.Result{}, false, err
+	}
+
+	for {
+		resp, err := stream.Recv()
+		if err == io.EOF {
+			return observers.Result{}, streamed, errStreamClosed
+		}
+		if err != nil {
+			return observers.Result{}, streamed, err
+		}
+		streamed = true
+
+		switch {
+		case resp.Error != "":
+			reason := resp.Reason
+			if reason == "" {
+				reason = ReasonPluginFailed
+			}
+			return observers.Result{Err: &observers.Failure{Reason: reason, Message: resp.Error}}, true, nil
+		case resp.Object != nil:
+			log.Info("observer fulfilled", "plugin", obs.plugin.Name, "id", obs.request.ID)
+			return observers.Result{Object: &unstructured.Unstructured{Object: resp.Object}}, true, nil
+		case resp.Progress != "":
+			log.Info("observer progress", "plugin", obs.plugin.Name, "id", obs.request.ID, "progress", resp.Progress)
+		}
+	}
+}
+
+// cancel stops the observation in the plugin, the plugins not implementing
+// the cancelling keep it until its result
+func (obs *Observer) cancel() {
+	ctx, cancel := context.WithTimeout(context.Background(), cancelTimeout)
+	defer cancel()
+
+	_, err := obs.plugin.client.Cancel(ctx, &CancelRequest{ID: obs.request.ID})
+	if err != nil && status.Code(err) != codes.Unimplemented {
+		log.Error(err, "unable to cancel the observation", "plugin", obs.plugin.Name, "id", obs.request.ID)
+	}
+}
+
+// retriable returns whether the observation may succeed when started again
+func retriable(err error) bool {
+	if err == errStreamClosed {
+		return true
+	}
+
+	switch status.Code(err) {
+	case codes.Unavailable, codes.NotFound, codes.Aborted, codes.ResourceExhausted, codes.DeadlineExceeded, codes.Internal:
+		return true
+	}
+	return false
+}
+
+// Result implements observers.Observer
+func (obs *Observer) Result() <-chan observers.Result {
+	return obs.result
+}
+
+// Describe implements observers.Observer
+func (obs *Observer) Describe() string {
+	return fmt.Sprintf("Waiting for %s of plugin %s", obs.request.Type, obs.plugin.Name)
+}
@@ -0,0 +1,331 @@
+package plugin
+
+import (
+	"context"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	v1alpha1 "github.com/cermakm/argo-await-operator/api/v1alpha1"
+	"github.com/cermakm/argo-await-operator/observers"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/wait"
+)
+
+// fakePlugin serves the results in the order given, the streams are closed
+// without a result until the results are exhausted
+type fakePlugin struct {
+	mu      sync.Mutex
+	watches []*WatchRequest
+	results []*ResultResponse
+	lost    bool
+	// block keeps the streams open without a result
+	block     bool
+	cancelled []string
+}
+
+func (p *fakePlugin) Register(ctx context.Context, req *RegisterRequest) (*RegisterResponse, error) {
+	return &RegisterResponse{Types: []ObserverType{{Name: "ticket", Description: "Waits for a ticket"}}}, nil
+}
+
+func (p *fakePlugin) Watch(ctx context.Context, req *WatchRequest) (*WatchResponse, error) {
+	if req.Parameters["ticket"] == "" {
+		return nil, status.Error(codes.InvalidArgument, "ticket parameter is required")
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.watches = append(p.watches, req)
+	p.lost = false
+	return &WatchResponse{}, nil
+}
+
+func (p *fakePlugin) Cancel(ctx context.Context, req *CancelRequest) (*CancelResponse, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.cancelled = append(p.cancelled, req.ID)
+	return &CancelResponse{}, nil
+}
+
+func (p *fakePlugin) Result(req *ResultRequest, stream ResultServer) error {
+	if p.block {
+		if err := stream.Send(&ResultResponse{Progress: "waiting"}); err != nil {
+			return err
+		}
+		<-stream.Context().Done()
+		return stream.Context().Err()
+	}
+
+	p.mu.Lock()
+	if p.lost {
+		p.mu.Unlock()
+		return status.Error(codes.NotFound, "unknown id")
+	}
+	if len(p.results) == 0 {
+		// the plugin is restarted and has lost the watch
+		p.lost = true
+		p.mu.Unlock()
+		return nil
+	}
+	result := p.results[0]
+	p.results = p.results[1:]
+	p.mu.Unlock()
+
+	if err := stream.Send(&ResultResponse{Progress: "waiting"}); err != nil {
+		return err
+	}
+	return stream.Send(result)
+}
+
+// serve serves the plugin in-process and returns the option to dial it
+func serve(t *testing.T, srv ObserverPluginServer) (grpc.DialOption, func()) {
+	lis := bufconn.Listen(1 << 20)
+	s := grpc.NewServer()
+	RegisterObserverPluginServer(s, srv)
+	go s.Serve(lis)
+
+	dialer := grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+		return lis.Dial()
+	})
+	return dialer, s.Stop
+}
+
+func newAwait(params map[string]string) *v1alpha1.Await {
+	return &v1alpha1.Await{
+		ObjectMeta: metav1.ObjectMeta{Name: "await", Namespace: "default", UID: "uid"},
+		Spec: v1alpha1.AwaitSpec{
+			Observer: &v1alpha1.ObserverSpec{Type: "ticket", Parameters: params},
+			Filters:  []string{"status == Done"},
+		},
+	}
+}
+
+func TestObserver(t *testing.T) {
+	object := map[string]interface{}{"ticket": "OPS-1", "status": "Done"}
+
+	tests := []struct {
+		name    string
+		params  map[string]string
+		results []*ResultResponse
+		// lost closes the streams and loses the watch before the result
+		lost        bool
+		wantObject  map[string]interface{}
+		wantFailure *observers.Failure
+		wantErr     bool
+	}{
+		{
+			name:       "Object is the result",
+			params:     map[string]string{"ticket": "OPS-1"},
+			results:    []*ResultResponse{{Object: object}},
+			wantObject: object,
+		},
+		{
+			name:        "Plugin failure fails the await",
+			params:      map[string]string{"ticket": "OPS-1"},
+			results:     []*ResultResponse{{Error: "ticket was closed", Reason: "TicketClosed"}},
+			wantFailure: &observers.Failure{Reason: "TicketClosed", Message: "ticket was closed"},
+		},
+		{
+			name:        "Failure reason defaults",
+			params:      map[string]string{"ticket": "OPS-1"},
+			results:     []*ResultResponse{{Error: "ticket was closed"}},
+			wantFailure: &observers.Failure{Reason: ReasonPluginFailed, Message: "ticket was closed"},
+		},
+		{
+			name:       "Lost watch is started again",
+			params:     map[string]string{"ticket": "OPS-1"},
+			lost:       true,
+			wantObject: object,
+		},
+		{
+			name:    "Invalid parameters are not retried",
+			params:  map[string]string{},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := &fakePlugin{results: tt.results}
+			dialer, stop := serve(t, srv)
+			defer stop()
+
+			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+
+			p, err := Dial(ctx, "fake", "bufnet", dialer)
+			if err != nil {
+				t.Fatalf("Dial() error = %v", err)
+			}
+			defer p.Close()
+			p.backoff = wait.Backoff{Duration: 10 * time.Millisecond}
+
+			if tt.lost {
+				// the stream is closed first and the watch lost afterwards,
+				// the object is then returned once watched again
+				go func() {
+					time.Sleep(50 * time.Millisecond)
+					srv.mu.Lock()
+					srv.results = []*ResultResponse{{Object: tt.wantObject}}
+					srv.mu.Unlock()
+				}()
+			}
+
+			obs := p.NewObserver("ticket", newAwait(tt.params))
+			obs.Start(ctx)
+
+			var got observers.Result
+			select {
+			case got = <-obs.Result():
+			case <-ctx.Done():
+				t.Fatal("Observer.Result() timed out")
+			}
+
+			switch {
+			case tt.wantErr:
+				if got.Err == nil {
+					t.Errorf("Observer.Result() expected an error, got %v", got)
+				}
+			case tt.wantFailure != nil:
+				if !reflect.DeepEqual(got.Err, tt.wantFailure) {
+					t.Errorf("Observer.Result() error = %v, want %v", got.Err, tt.wantFailure)
+				}
+			default:
+				if got.Err != nil || !reflect.DeepEqual(got.Object.Object, tt.wantObject) {
+					t.Errorf("Observer.Result() = %v, %v, want %v", got.Object, got.Err, tt.wantObject)
+				}
+			}
+
+			if tt.wantErr {
+				return
+			}
+			srv.mu.Lock()
+			watch := srv.watches[0]
+			srv.mu.Unlock()
+			if watch.ID != "uid" || watch.Type != "ticket" || watch.Await.Name != "await" ||
+				!reflect.DeepEqual(watch.Parameters, tt.params) || !reflect.DeepEqual(watch.Filters, []string{"status == Done"}) {
+				t.Errorf("Watch() request = %v", watch)
+			}
+		})
+	}
+}
+
+func TestObserver_cancel(t *testing.T) {
+	srv := &fakePlugin{block: true}
+	dialer, stop := serve(t, srv)
+	defer stop()
+
+	p, err := Dial(context.Background(), "fake", "bufnet", dialer)
+	if err != nil {
+		t.Fatalf("Dial() error = %v", err)
+	}
+	defer p.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	obs := p.NewObserver("ticket", newAwait(map[string]string{"ticket": "OPS-1"}))
+	obs.Start(ctx)
+
+	// The observation is cancelled once it is being streamed
+	for {
+		srv.mu.Lock()
+		watched := len(srv.watches) > 0
+		srv.mu.Unlock()
+		if watched {
+			break
+		}
+		time.Sleep(time.Millisecond)
+	}
+	cancel()
+
+	select {
+	case got := <-obs.Result():
+		if got.Err != context.Canceled {
+			t.Errorf("Observer.Result() error = %v, want %v", got.Err, context.Canceled)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Observer.Result() timed out")
+	}
+
+	srv.mu.Lock()
+	defer srv.mu.Unlock()
+	if !reflect.DeepEqual(srv.cancelled, []string{"uid"}) {
+		t.Errorf("Cancel() requests = %v, want [uid]", srv.cancelled)
+	}
+}
+
+func TestLoad(t *testing.T) {
+	dialer, stop := serve(t, &fakePlugin{})
+	defer stop()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	registry := observers.NewRegistry()
+	plugins, err := Load(ctx, registry, map[string]string{"jira": "bufnet"}, dialer)
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	defer plugins[0].Close()
+
+	obs, err := registry.New(newAwait(map[string]string{"ticket": "OPS-1"}), observers.Options{})
+	if err != nil {
+		t.Fatalf("Registry.New() error = %v", err)
+	}
+	if got, want := obs.Describe(), "Waiting for ticket of plugin jira"; got != want {
+		t.Errorf("Observer.Describe() = %v, want %v", got, want)
+	}
+
+	// the types can not be registered twice
+	if _, err := Load(ctx, registry, map[string]string{"jira": "bufnet"}, dialer); err == nil {
+		t.Errorf("Load() expected an error for a registered type")
+	}
+}
+
+func TestLoad_unreachable(t *testing.T) {
+	registerTimeout = 100 * time.Millisecond
+	defer func() { registerTimeout = 10 * time.Second }()
+
+	registry := observers.NewRegistry()
+	plugins, err := Load(context.Background(), registry, map[string]string{"down": "unix:///nonexistent/down.sock"})
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if len(plugins) != 0 {
+		t.Errorf("Load() = %v, want the unreachable plugin skipped", plugins)
+	}
+}
+
+func TestDiscoverSockets(t *testing.T) {
+	dir, err := ioutil.TempDir("", "plugins")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	lis, err := net.Listen("unix", filepath.Join(dir, "jira.sock"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer lis.Close()
+	if err := ioutil.WriteFile(filepath.Join(dir, "README"), nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := DiscoverSockets(dir)
+	if err != nil {
+		t.Fatalf("DiscoverSockets() error = %v", err)
+	}
+	want := map[string]string{"jira": "unix://" + filepath.Join(dir, "jira.sock")}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("DiscoverSockets() = %v, want %v", got, want)
+	}
+}
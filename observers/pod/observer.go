@@ -0,0 +1,327 @@
+package pod
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"regexp"
+	"sync"
+
+	v1alpha1 "github.com/cermakm/argo-await-operator/api/v1alpha1"
+	"github.com/cermakm/argo-await-operator/metrics"
+	"github.com/cermakm/argo-await-operator/observers/filter"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/watch"
+	"k8s.io/client-go/kubernetes"
+
+	logf "sigs.k8s.io/controller-runtime/pkg/runtime/log"
+)
+
+var log = logf.Log.WithName("pod-observer")
+
+// LogStreamer opens the log stream of a Pod container
+type LogStreamer func(namespace, name string, opts *corev1.PodLogOptions) (io.ReadCloser, error)
+
+// Observer watches the Pods for a container exit code or a log line
+type Observer struct {
+	client kubernetes.Interface
+
+	match   *v1alpha1.PodMatch
+	pattern *regexp.Regexp
+	filters *filter.Filters
+
+	// streamLogs opens the log streams, the Pod log subresource is used by default
+	streamLogs LogStreamer
+}
+
+// NewObserver creates a new Observer of the Pods using the client,
+// only the Pods passing the filters are matched
+func NewObserver(client kubernetes.Interface, match *v1alpha1.PodMatch, filters []string) (*Observer, error) {
+	if match.ExitCode == nil && match.LogPattern == "" {
+		return nil, fmt.Errorf("either the exit code or the log pattern has to be set")
+	}
+
+	obs := &Observer{
+		client:  client,
+		match:   match,
+		filters: filter.Compile(filters...),
+		streamLogs: func(namespace, name string, opts *corev1.PodLogOptions) (io.ReadCloser, error) {
+			return client.CoreV1().Pods(namespace).GetLogs(name, opts).Stream()
+		},
+	}
+
+	if match.LogPattern != "" {
+		pattern, err := regexp.Compile(match.LogPattern)
+		if err != nil {
+			return nil, fmt.Errorf("invalid log pattern: %v", err)
+		}
+		obs.pattern = pattern
+	}
+
+	return obs, nil
+}
+
+// Await watches the Pods until a container terminates with the exit code
+// or logs a line matching the pattern and calls the callback with the Pod
+func (obs *Observer) Await(ctx context.Context, callback func(ctx context.Context, obj *unstructured.Unstructured) error) error {
+	namespace := obs.match.Namespace
+	log := log.WithValues("namespace", namespace, "name", obs.match.Name, "container", obs.match.Container)
+
+	opts := metav1.ListOptions{}
+	if obs.match.Name != "" {
+		opts.FieldSelector = fields.OneTermEqualSelector("metadata.name", obs.match.Name).String()
+	}
+	if obs.match.Selector != nil {
+		selector, err := metav1.LabelSelectorAsSelector(obs.match.Selector)
+		if err != nil {
+			return err
+		}
+		opts.LabelSelector = selector.String()
+	}
+
+	metrics.ActiveObservers.WithLabelValues(v1alpha1.ObservedKindPod, namespace).Inc()
+	defer metrics.ActiveObservers.WithLabelValues(v1alpha1.ObservedKindPod, namespace).Dec()
+
+	streams := newLogStreams()
+	defer streams.closeAll()
+
+	for {
+		watchInterface, err := obs.client.CoreV1().Pods(namespace).Watch(opts)
+		if err != nil {
+			log.Error(err, "error creating a watch for pods")
+			return err
+		}
+		log.Info("watching for pods")
+
+		pod, err := obs.watch(ctx, watchInterface, streams)
+		watchInterface.Stop()
+		if err != nil {
+			return err
+		}
+		if pod != nil {
+			log.Info("pod matched", "pod", pod.Name)
+
+			obj, err := toUnstructured(pod)
+			if err != nil {
+				return err
+			}
+			return callback(ctx, obj)
+		}
+
+		// The watch has expired, start a new one
+	}
+}
+
+// watch processes the events until a Pod matches, nil is returned if the watch has been closed
+func (obs *Observer) watch(ctx context.Context, watchInterface watch.Interface, streams *logStreams) (*corev1.Pod, error) {
+	namespace := obs.match.Namespace
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case pod := <-streams.matched:
+			return pod, nil
+		case evt, ok := <-watchInterface.ResultChan():
+			if !ok {
+				return nil, nil
+			}
+			pod, isPod := evt.Object.(*corev1.Pod)
+			if !isPod || (evt.Type != watch.Added && evt.Type != watch.Modified) {
+				continue
+			}
+			metrics.EventsProcessed.WithLabelValues(v1alpha1.ObservedKindPod, namespace).Inc()
+
+			passed, err := obs.passFilters(pod)
+			if err != nil {
+				return nil, err
+			}
+			if !passed {
+				continue
+			}
+
+			if obs.pattern == nil {
+				if obs.exited(pod) {
+					return pod, nil
+				}
+				continue
+			}
+
+			if status, ok := obs.logsAvailable(pod); ok {
+				streams.start(pod, status, obs.streamLogs, obs.pattern)
+			}
+		}
+	}
+}
+
+// passFilters returns whether the Pod passes the filters
+func (obs *Observer) passFilters(pod *corev1.Pod) (bool, error) {
+	obj, err := toUnstructured(pod)
+	if err != nil {
+		return false, err
+	}
+
+	metrics.FilterEvaluations.WithLabelValues(v1alpha1.ObservedKindPod, obs.match.Namespace).Inc()
+	ok, err := obs.filters.Pass(obj.Object)
+	if err != nil {
+		metrics.FilterErrors.WithLabelValues(v1alpha1.ObservedKindPod, obs.match.Namespace).Inc()
+		return false, filter.ErrInvalidFilters
+	}
+	return ok, nil
+}
+
+// exited returns whether a container of the Pod has terminated with the exit code
+func (obs *Observer) exited(pod *corev1.Pod) bool {
+	if obs.match.ExitCode == nil {
+		return false
+	}
+
+	statuses := containerStatuses(pod)
+	for _, status := range statuses {
+		if obs.match.Container != "" && status.Name != obs.match.Container {
+			continue
+		}
+
+		// The restarted containers keep the exit code in the last state
+		for _, state := range []corev1.ContainerState{status.State, status.LastTerminationState} {
+			if state.Terminated != nil && state.Terminated.ExitCode == *obs.match.ExitCode {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// logsAvailable returns the status of the container whose logs are matched
+// if it has already been started
+func (obs *Observer) logsAvailable(pod *corev1.Pod) (corev1.ContainerStatus, bool) {
+	container := obs.match.Container
+	if container == "" {
+		if len(pod.Spec.Containers) == 0 {
+			return corev1.ContainerStatus{}, false
+		}
+		container = pod.Spec.Containers[0].Name
+	}
+
+	statuses := containerStatuses(pod)
+	for _, status := range statuses {
+		if status.Name == container && (status.State.Running != nil || status.State.Terminated != nil) {
+			return status, true
+		}
+	}
+
+	return corev1.ContainerStatus{}, false
+}
+
+// containerStatuses returns the statuses of both the init and the regular containers
+func containerStatuses(pod *corev1.Pod) []corev1.ContainerStatus {
+	statuses := make([]corev1.ContainerStatus, 0, len(pod.Status.InitContainerStatuses)+len(pod.Status.ContainerStatuses))
+	statuses = append(statuses, pod.Status.InitContainerStatuses...)
+	return append(statuses, pod.Status.ContainerStatuses...)
+}
+
+// logStreams follows the logs of the Pod containers
+type logStreams struct {
+	mu      sync.Mutex
+	streams map[string]io.ReadCloser
+	// since are the times the ended streams have been read until, keyed as the streams
+	since  map[string]metav1.Time
+	closed bool
+
+	matched chan *corev1.Pod
+}
+
+func newLogStreams() *logStreams {
+	return &logStreams{
+		streams: map[string]io.ReadCloser{},
+		since:   map[string]metav1.Time{},
+		matched: make(chan *corev1.Pod, 1),
+	}
+}
+
+// start follows the logs of the container run unless they are already being followed.
+// The stream which has ended while the container is still running is reopened
+// from the time it has been read until
+func (s *logStreams) start(pod *corev1.Pod, status corev1.ContainerStatus, streamLogs LogStreamer, pattern *regexp.Regexp) {
+	container := status.Name
+	key := fmt.Sprintf("%s/%s/%d", pod.UID, container, status.RestartCount)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.streams[key]; ok || s.closed {
+		return
+	}
+
+	opts := &corev1.PodLogOptions{Container: container, Follow: true}
+	if since, ok := s.since[key]; ok {
+		// The logs of the terminated container have been read to the end
+		if status.State.Running == nil {
+			return
+		}
+		opts.SinceTime = &since
+	}
+
+	stream, err := streamLogs(pod.Namespace, pod.Name, opts)
+	if err != nil {
+		log.Error(err, "unable to stream the logs", "pod", pod.Name, "container", container)
+		return
+	}
+	s.streams[key] = stream
+
+	go func() {
+		since := metav1.Now()
+		scanner := bufio.NewScanner(stream)
+		for scanner.Scan() {
+			if pattern.MatchString(scanner.Text()) {
+				select {
+				case s.matched <- pod:
+				default:
+				}
+				return
+			}
+			since = metav1.Now()
+		}
+
+		// The stream has ended without a match, e.g. the connection has been dropped,
+		// it is reopened by the next event of the Pod
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		if s.closed {
+			return
+		}
+		_ = stream.Close()
+		delete(s.streams, key)
+		s.since[key] = since
+	}()
+}
+
+// closeAll closes all the log streams
+func (s *logStreams) closeAll() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.closed = true
+	for _, stream := range s.streams {
+		_ = stream.Close()
+	}
+}
+
+// toUnstructured converts the Pod, which is missing the type metadata when watched
+func toUnstructured(pod *corev1.Pod) (*unstructured.Unstructured, error) {
+	object, err := runtime.DefaultUnstructuredConverter.ToUnstructured(pod)
+	if err != nil {
+		return nil, err
+	}
+
+	obj := &unstructured.Unstructured{Object: object}
+	obj.SetAPIVersion("v1")
+	obj.SetKind("Pod")
+	return obj, nil
+}
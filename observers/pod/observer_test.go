@@ -0,0 +1,216 @@
+package pod
+
+import (
+	"context"
+	"io"
+	"io/ioutil"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	v1alpha1 "github.com/cermakm/argo-await-operator/api/v1alpha1"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/watch"
+	"k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+)
+
+func newPod(name string, statuses ...corev1.ContainerStatus) *corev1.Pod {
+	return &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: "default",
+			UID:       types.UID("uid-" + name),
+			Labels:    map[string]string{"tier": name},
+		},
+		Spec: corev1.PodSpec{
+			Containers: []corev1.Container{{Name: "main"}, {Name: "sidecar"}},
+		},
+		Status: corev1.PodStatus{ContainerStatuses: statuses},
+	}
+}
+
+func terminated(container string, exitCode int32) corev1.ContainerStatus {
+	return corev1.ContainerStatus{
+		Name:  container,
+		State: corev1.ContainerState{Terminated: &corev1.ContainerStateTerminated{ExitCode: exitCode}},
+	}
+}
+
+func running(container string) corev1.ContainerStatus {
+	return corev1.ContainerStatus{
+		Name:  container,
+		State: corev1.ContainerState{Running: &corev1.ContainerStateRunning{}},
+	}
+}
+
+func TestObserver_Await(t *testing.T) {
+	exitCode := int32(3)
+
+	tests := []struct {
+		name    string
+		match   v1alpha1.PodMatch
+		filters []string
+		events  []*corev1.Pod
+		logs    map[string]string
+		want    string
+	}{
+		{
+			name:  "Container exit code",
+			match: v1alpha1.PodMatch{Name: "etl", Container: "main", ExitCode: &exitCode},
+			events: []*corev1.Pod{
+				newPod("etl", running("main")),
+				newPod("etl", running("main"), terminated("sidecar", exitCode)),
+				newPod("etl", terminated("main", exitCode)),
+			},
+			want: "etl",
+		},
+		{
+			name:  "Log line of the first container",
+			match: v1alpha1.PodMatch{Name: "etl", LogPattern: `^loaded \d+ rows$`},
+			events: []*corev1.Pod{
+				newPod("etl"),
+				newPod("etl", running("main")),
+			},
+			logs: map[string]string{"etl/main": "starting\nloaded 42 rows\n"},
+			want: "etl",
+		},
+		{
+			name: "Log line of any selected pod",
+			match: v1alpha1.PodMatch{
+				Selector:   &metav1.LabelSelector{MatchLabels: map[string]string{"app": "etl"}},
+				Container:  "sidecar",
+				LogPattern: "ready",
+			},
+			events: []*corev1.Pod{
+				newPod("etl-a", running("sidecar")),
+				newPod("etl-b", running("sidecar")),
+			},
+			logs: map[string]string{"etl-a/sidecar": "starting\n", "etl-b/sidecar": "ready\n"},
+			want: "etl-b",
+		},
+		{
+			name: "Exit code of the pod passing the filters",
+			match: v1alpha1.PodMatch{
+				Selector: &metav1.LabelSelector{MatchLabels: map[string]string{"app": "etl"}},
+				ExitCode: &exitCode,
+			},
+			filters: []string{`metadata.labels.tier=="etl-b"`},
+			events: []*corev1.Pod{
+				newPod("etl-a", terminated("main", exitCode)),
+				newPod("etl-b", terminated("main", exitCode)),
+			},
+			want: "etl-b",
+		},
+		{
+			name:    "Log line of the pod passing the filters",
+			match:   v1alpha1.PodMatch{Container: "main", LogPattern: "ready"},
+			filters: []string{`metadata.name=="etl-b"`},
+			events: []*corev1.Pod{
+				newPod("etl-a", running("main")),
+				newPod("etl-b", running("main")),
+			},
+			logs: map[string]string{"etl-a/main": "ready\n", "etl-b/main": "ready\n"},
+			want: "etl-b",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			watcher := watch.NewFake()
+			client := fake.NewSimpleClientset()
+			client.PrependWatchReactor("pods", k8stesting.DefaultWatchReactor(watcher, nil))
+
+			go func() {
+				for _, evt := range tt.events {
+					watcher.Modify(evt)
+				}
+			}()
+
+			tt.match.Namespace = "default"
+			obs, err := NewObserver(client, &tt.match, tt.filters)
+			if err != nil {
+				t.Fatal(err)
+			}
+			obs.streamLogs = func(namespace, name string, opts *corev1.PodLogOptions) (io.ReadCloser, error) {
+				return ioutil.NopCloser(strings.NewReader(tt.logs[name+"/"+opts.Container])), nil
+			}
+
+			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+
+			var got *unstructured.Unstructured
+			err = obs.Await(ctx, func(ctx context.Context, obj *unstructured.Unstructured) error {
+				got = obj
+				return nil
+			})
+			if err != nil {
+				t.Fatalf("Await() error = %v", err)
+			}
+			if got.GetName() != tt.want || got.GetKind() != "Pod" {
+				t.Errorf("Await() pod = %v %v, want Pod %v", got.GetKind(), got.GetName(), tt.want)
+			}
+		})
+	}
+}
+
+func TestNewObserver(t *testing.T) {
+	if _, err := NewObserver(fake.NewSimpleClientset(), &v1alpha1.PodMatch{Name: "etl", LogPattern: "loaded ("}, nil); err == nil {
+		t.Error("NewObserver() expected an error for an invalid log pattern")
+	}
+	if _, err := NewObserver(fake.NewSimpleClientset(), &v1alpha1.PodMatch{Name: "etl"}, nil); err == nil {
+		t.Error("NewObserver() expected an error without the exit code and the log pattern")
+	}
+}
+
+func TestLogStreams_start(t *testing.T) {
+	pod := newPod("etl", running("main"))
+	logs := make(chan string, 2)
+	opened := make(chan *corev1.PodLogOptions, 3)
+	streamLogs := func(namespace, name string, opts *corev1.PodLogOptions) (io.ReadCloser, error) {
+		opened <- opts
+		return ioutil.NopCloser(strings.NewReader(<-logs)), nil
+	}
+
+	streams := newLogStreams()
+	defer streams.closeAll()
+
+	logs <- "starting\n"
+	streams.start(pod, running("main"), streamLogs, regexp.MustCompile("ready"))
+	if opts := <-opened; opts.SinceTime != nil {
+		t.Errorf("start() since = %v, want the whole log", opts.SinceTime)
+	}
+
+	// The ended stream is reopened by the next event of the running container
+	ended := func() bool {
+		streams.mu.Lock()
+		defer streams.mu.Unlock()
+		return len(streams.streams) == 0
+	}
+	for !ended() {
+		time.Sleep(time.Millisecond)
+	}
+	streams.start(pod, terminated("main", 0), streamLogs, regexp.MustCompile("ready"))
+	if len(opened) != 0 {
+		t.Fatal("start() reopened the logs of the terminated container")
+	}
+
+	logs <- "ready\n"
+	streams.start(pod, running("main"), streamLogs, regexp.MustCompile("ready"))
+	if opts := <-opened; opts.SinceTime == nil {
+		t.Error("start() reopened the whole log, want since the stream has ended")
+	}
+
+	select {
+	case got := <-streams.matched:
+		if got.Name != pod.Name {
+			t.Errorf("start() matched = %v, want %v", got.Name, pod.Name)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("start() did not match the reopened stream")
+	}
+}
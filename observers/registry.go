@@ -0,0 +1,88 @@
+package observers
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"sync"
+
+	v1alpha1 "github.com/cermakm/argo-await-operator/api/v1alpha1"
+	"github.com/cermakm/argo-await-operator/observers/cloudevents"
+
+	"k8s.io/client-go/rest"
+)
+
+// Options are the dependencies the Observers are created with
+type Options struct {
+	Config     *rest.Config
+	HTTPClient *http.Client
+	// Events receives the CloudEvents, nil if the receiver is not enabled
+	Events *cloudevents.Receiver
+}
+
+// Factory creates the Observer of the Await
+type Factory func(res *v1alpha1.Await, opts Options) (Observer, error)
+
+// UnknownTypeError is returned when no Factory is registered for the observer type
+type UnknownTypeError struct {
+	Type string
+}
+
+func (e *UnknownTypeError) Error() string {
+	return fmt.Sprintf("unknown observer type %q", e.Type)
+}
+
+// Registry holds the Factories of the Observers keyed by the observer type
+type Registry struct {
+	mu        sync.RWMutex
+	factories map[string]Factory
+}
+
+// NewRegistry creates a new Registry with the built-in observers registered
+func NewRegistry() *Registry {
+	r := &Registry{factories: map[string]Factory{}}
+	registerBuiltins(r)
+
+	return r
+}
+
+// Register registers the Factory of the observer type, the types can not be registered twice
+func (r *Registry) Register(observerType string, factory Factory) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.factories[observerType]; ok {
+		return fmt.Errorf("observer type %q is already registered", observerType)
+	}
+	r.factories[observerType] = factory
+
+	return nil
+}
+
+// Types returns the sorted registered observer types
+func (r *Registry) Types() []string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	types := make([]string, 0, len(r.factories))
+	for t := range r.factories {
+		types = append(types, t)
+	}
+	sort.Strings(types)
+
+	return types
+}
+
+// New creates the Observer of the Await by the Factory of its observer type
+func (r *Registry) New(res *v1alpha1.Await, opts Options) (Observer, error) {
+	observerType := res.Spec.ObserverType()
+
+	r.mu.RLock()
+	factory, ok := r.factories[observerType]
+	r.mu.RUnlock()
+	if !ok {
+		return nil, &UnknownTypeError{Type: observerType}
+	}
+
+	return factory(res, opts)
+}
@@ -0,0 +1,114 @@
+package observers
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	v1alpha1 "github.com/cermakm/argo-await-operator/api/v1alpha1"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+)
+
+// fakeAwaiter matches the object or fails with the error right away
+type fakeAwaiter struct {
+	obj *unstructured.Unstructured
+	err error
+}
+
+func (a *fakeAwaiter) Await(ctx context.Context, callback func(ctx context.Context, obj *unstructured.Unstructured) error) error {
+	if a.err != nil {
+		return a.err
+	}
+	return callback(ctx, a.obj)
+}
+
+func TestFromAwaiter(t *testing.T) {
+	obj := &unstructured.Unstructured{Object: map[string]interface{}{"kind": "ConfigMap"}}
+	errFailed := errors.New("failed")
+
+	tests := []struct {
+		name    string
+		awaiter *fakeAwaiter
+		want    Result
+	}{
+		{
+			name:    "Matched object is the result",
+			awaiter: &fakeAwaiter{obj: obj},
+			want:    Result{Object: obj},
+		},
+		{
+			name:    "Error is the result",
+			awaiter: &fakeAwaiter{err: errFailed},
+			want:    Result{Err: errFailed},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			obs := FromAwaiter(tt.awaiter, "fake")
+			obs.Start(context.TODO())
+
+			select {
+			case got := <-obs.Result():
+				if !reflect.DeepEqual(got, tt.want) {
+					t.Errorf("Observer.Result() = %v, want %v", got, tt.want)
+				}
+			case <-time.After(time.Second):
+				t.Fatal("Observer.Result() timed out")
+			}
+			if got := obs.Describe(); got != "fake" {
+				t.Errorf("Observer.Describe() = %v, want %v", got, "fake")
+			}
+		})
+	}
+}
+
+func TestRegistry(t *testing.T) {
+	registry := NewRegistry()
+
+	custom := func(res *v1alpha1.Await, opts Options) (Observer, error) {
+		return FromAwaiter(&fakeAwaiter{}, "custom "+res.Spec.Observer.Parameters["name"]), nil
+	}
+	if err := registry.Register("custom", custom); err != nil {
+		t.Fatalf("Registry.Register() error = %v", err)
+	}
+	if err := registry.Register("custom", custom); err == nil {
+		t.Errorf("Registry.Register() expected an error for a duplicate type")
+	}
+	if err := registry.Register(v1alpha1.ObserverTypeHTTP, custom); err == nil {
+		t.Errorf("Registry.Register() expected an error for a built-in type")
+	}
+
+	want := []string{"cloudevent", "custom", "http", "pod", "resource", "workflow"}
+	if got := registry.Types(); !reflect.DeepEqual(got, want) {
+		t.Errorf("Registry.Types() = %v, want %v", got, want)
+	}
+
+	res := &v1alpha1.Await{Spec: v1alpha1.AwaitSpec{
+		Observer: &v1alpha1.ObserverSpec{Type: "custom", Parameters: map[string]string{"name": "observer"}},
+	}}
+	obs, err := registry.New(res, Options{})
+	if err != nil {
+		t.Fatalf("Registry.New() error = %v", err)
+	}
+	if got := obs.Describe(); got != "custom observer" {
+		t.Errorf("Registry.New() observer = %v, want %v", got, "custom observer")
+	}
+
+	res.Spec.Observer.Type = "unknown"
+	if _, err := registry.New(res, Options{}); !reflect.DeepEqual(err, &UnknownTypeError{Type: "unknown"}) {
+		t.Errorf("Registry.New() error = %v, want an unknown type", err)
+	}
+
+	res.Spec.Observer = nil
+	res.Spec.HTTP = &v1alpha1.HTTPEndpoint{URL: "https://example.com/status"}
+	obs, err = registry.New(res, Options{})
+	if err != nil {
+		t.Fatalf("Registry.New() error = %v", err)
+	}
+	if got := obs.Describe(); got != "Polling https://example.com/status" {
+		t.Errorf("Registry.New() observer = %v, want the http observer", got)
+	}
+}
@@ -0,0 +1,252 @@
+package resource
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	v1alpha1 "github.com/cermakm/argo-await-operator/api/v1alpha1"
+	"github.com/cermakm/argo-await-operator/metrics"
+	"github.com/cermakm/argo-await-operator/tracing"
+	"go.opentelemetry.io/otel/label"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/clock"
+	"k8s.io/apimachinery/pkg/watch"
+)
+
+// countKey is the key of the Count condition in the stable window
+const countKey = "count"
+
+// matchSet keeps the selected resources by their UIDs and whether they pass the filters
+type matchSet struct {
+	condition *v1alpha1.CountCondition
+	entries   map[types.UID]match
+}
+
+type match struct {
+	object map[string]interface{}
+	passed bool
+}
+
+func newMatchSet(condition *v1alpha1.CountCondition) *matchSet {
+	return &matchSet{
+		condition: condition,
+		entries:   map[types.UID]match{},
+	}
+}
+
+// set records the latest state of the resource
+func (s *matchSet) set(uid types.UID, object map[string]interface{}, passed bool) {
+	s.entries[uid] = match{object: object, passed: passed}
+}
+
+// remove removes the resource, e.g. once it has been deleted
+func (s *matchSet) remove(uid types.UID) {
+	delete(s.entries, uid)
+}
+
+// matched returns the resources passing the filters sorted by their names
+func (s *matchSet) matched() []interface{} {
+	var objects []map[string]interface{}
+	for _, m := range s.entries {
+		if m.passed {
+			objects = append(objects, m.object)
+		}
+	}
+	sort.Slice(objects, func(i, j int) bool {
+		return (&unstructured.Unstructured{Object: objects[i]}).GetName() <
+			(&unstructured.Unstructured{Object: objects[j]}).GetName()
+	})
+
+	items := make([]interface{}, len(objects))
+	for i := range objects {
+		items[i] = objects[i]
+	}
+	return items
+}
+
+// satisfied returns whether the resources fulfill the Count condition
+func (s *matchSet) satisfied() bool {
+	passing := 0
+	for _, m := range s.entries {
+		if m.passed {
+			passing++
+		}
+	}
+
+	switch s.condition.Operator {
+	case v1alpha1.CountAtLeast:
+		value := int(s.condition.Value)
+		if value == 0 {
+			value = 1
+		}
+		return passing >= value
+	case v1alpha1.CountAll:
+		return len(s.entries) > 0 && passing == len(s.entries)
+	case v1alpha1.CountNone:
+		return passing == 0
+	}
+	return false
+}
+
+// list returns the resources passing the filters as a List
+func (s *matchSet) list() map[string]interface{} {
+	return map[string]interface{}{
+		"apiVersion": "v1",
+		"kind":       "List",
+		"items":      s.matched(),
+	}
+}
+
+// awaitCount awaits the Count condition over the selected resources and calls
+// the callback with the List of the resources passing the filters. The resources
+// are listed first so that the condition is evaluated over all of them, and listed
+// again whenever the watch expires so that no change is missed.
+func (obs *Observer) awaitCount(ctx context.Context, callback func(ctx context.Context, obj *unstructured.Unstructured) error) error {
+	opts := metav1.ListOptions{}
+	if obs.count.Selector != nil {
+		selector, err := metav1.LabelSelectorAsSelector(obs.count.Selector)
+		if err != nil {
+			return err
+		}
+		opts.LabelSelector = selector.String()
+	}
+
+	log.WithValues(
+		"group", obs.resource.Group,
+		"version", obs.resource.Version,
+		"kind", obs.resource.Kind,
+		"namespace", obs.namespace,
+		"operator", obs.count.Operator,
+		"value", obs.count.Value,
+	).Info("counting resources")
+
+	metrics.ActiveObservers.WithLabelValues(obs.resource.Kind, obs.namespace).Inc()
+	defer metrics.ActiveObservers.WithLabelValues(obs.resource.Kind, obs.namespace).Dec()
+
+	set := newMatchSet(obs.count)
+	for {
+		opts.ResourceVersion = ""
+		resourceVersion, err := obs.relist(ctx, set, opts)
+		if err != nil {
+			return err
+		}
+
+		opts.ResourceVersion = resourceVersion
+		watchInterface, err := obs.startWatch(ctx, opts)
+		if err != nil {
+			return err
+		}
+
+		object, err := obs.watchCount(ctx, watchInterface, set)
+		watchInterface.Stop()
+		if err != nil {
+			return err
+		}
+		if object != nil {
+			log.Info("count fulfilled", "matched", len(set.matched()))
+
+			// Execute the callback function and return
+			return callback(ctx, &unstructured.Unstructured{Object: object})
+		}
+
+		// The watch has expired, list the resources again and start a new one
+	}
+}
+
+// relist lists the selected resources into the set and returns the resource version
+// of the List, the resources deleted since the previous List are removed
+func (obs *Observer) relist(ctx context.Context, set *matchSet, opts metav1.ListOptions) (string, error) {
+	_, span := tracing.StartSpan(ctx, "List",
+		label.String("resource.kind", obs.resource.Kind),
+		label.String("resource.namespace", obs.namespace),
+	)
+	list, err := obs.List(opts)
+	if err != nil {
+		log.Error(err, "error listing resources", "resource", *obs.resource)
+		tracing.RecordError(ctx, span, err)
+		span.End()
+		return "", err
+	}
+	span.End()
+
+	listed := map[types.UID]bool{}
+	for i := range list.Items {
+		listed[list.Items[i].GetUID()] = true
+		if err := obs.match(ctx, set, list.Items[i].Object); err != nil {
+			return "", err
+		}
+	}
+	for uid := range set.entries {
+		if !listed[uid] {
+			obs.tracker.Forget(string(uid))
+			set.remove(uid)
+		}
+	}
+	obs.window.Observe(countKey, set.list(), set.satisfied())
+
+	return list.GetResourceVersion(), nil
+}
+
+// watchCount processes the events until the Count condition is stable, nil is
+// returned if the watch has been closed or has failed
+func (obs *Observer) watchCount(ctx context.Context, watchInterface watch.Interface, set *matchSet) (map[string]interface{}, error) {
+	for {
+		if object, ok := obs.window.Stable(); ok {
+			return object, nil
+		}
+
+		// The condition is fulfilled once it has held for the stable window
+		var timer clock.Timer
+		var stable <-chan time.Time
+		if remaining, ok := obs.window.Next(); ok {
+			timer = obs.clock.NewTimer(remaining)
+			stable = timer.C()
+		}
+
+		select {
+		case <-ctx.Done():
+			stopTimer(timer)
+			return nil, ctx.Err()
+		case <-stable:
+		case evt, open := <-watchInterface.ResultChan():
+			stopTimer(timer)
+			if !open || watchFailed(evt) {
+				return nil, nil
+			}
+
+			object, ok := obs.eventObject(evt)
+			if !ok {
+				continue
+			}
+
+			if evt.Type == watch.Deleted {
+				uid := (&unstructured.Unstructured{Object: object}).GetUID()
+				obs.tracker.Forget(string(uid))
+				set.remove(uid)
+				obs.window.Observe(countKey, set.list(), set.satisfied())
+				continue
+			}
+			if err := obs.match(ctx, set, object); err != nil {
+				return nil, err
+			}
+		}
+	}
+}
+
+// match evaluates the filters against the resource and records it in the set
+func (obs *Observer) match(ctx context.Context, set *matchSet, object map[string]interface{}) error {
+	metrics.FilterEvaluations.WithLabelValues(obs.resource.Kind, obs.namespace).Inc()
+	ok, err := obs.evaluateFilters(ctx, object)
+	if err != nil {
+		metrics.FilterErrors.WithLabelValues(obs.resource.Kind, obs.namespace).Inc()
+		return ErrInvalidFilters
+	}
+
+	set.set((&unstructured.Unstructured{Object: object}).GetUID(), object, ok)
+	obs.window.Observe(countKey, set.list(), set.satisfied())
+	return nil
+}
@@ -0,0 +1,242 @@
+package resource
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	v1alpha1 "github.com/cermakm/argo-await-operator/api/v1alpha1"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/clock"
+	"k8s.io/apimachinery/pkg/watch"
+	"k8s.io/client-go/dynamic/fake"
+	k8stesting "k8s.io/client-go/testing"
+)
+
+func newPod(name, phase string) *unstructured.Unstructured {
+	return &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": "v1",
+		"kind":       "Pod",
+		"metadata": map[string]interface{}{
+			"name":      name,
+			"namespace": "default",
+			"uid":       "uid-" + name,
+			"labels":    map[string]interface{}{"app": "etl"},
+		},
+		"status": map[string]interface{}{"phase": phase},
+	}}
+}
+
+func Test_matchSet_satisfied(t *testing.T) {
+	type entry struct {
+		uid    types.UID
+		passed bool
+	}
+	tests := []struct {
+		name      string
+		condition v1alpha1.CountCondition
+		entries   []entry
+		want      bool
+	}{
+		{
+			name:      "At least one of none",
+			condition: v1alpha1.CountCondition{Operator: v1alpha1.CountAtLeast},
+			want:      false,
+		},
+		{
+			name:      "At least two",
+			condition: v1alpha1.CountCondition{Operator: v1alpha1.CountAtLeast, Value: 2},
+			entries:   []entry{{"a", true}, {"b", false}, {"c", true}},
+			want:      true,
+		},
+		{
+			name:      "At least three",
+			condition: v1alpha1.CountCondition{Operator: v1alpha1.CountAtLeast, Value: 3},
+			entries:   []entry{{"a", true}, {"b", false}, {"c", true}},
+			want:      false,
+		},
+		{
+			name:      "All passing",
+			condition: v1alpha1.CountCondition{Operator: v1alpha1.CountAll},
+			entries:   []entry{{"a", true}, {"b", true}},
+			want:      true,
+		},
+		{
+			name:      "All but one passing",
+			condition: v1alpha1.CountCondition{Operator: v1alpha1.CountAll},
+			entries:   []entry{{"a", true}, {"b", false}},
+			want:      false,
+		},
+		{
+			name:      "All of none",
+			condition: v1alpha1.CountCondition{Operator: v1alpha1.CountAll},
+			want:      false,
+		},
+		{
+			name:      "None remaining",
+			condition: v1alpha1.CountCondition{Operator: v1alpha1.CountNone},
+			want:      true,
+		},
+		{
+			name:      "None passing",
+			condition: v1alpha1.CountCondition{Operator: v1alpha1.CountNone},
+			entries:   []entry{{"a", false}},
+			want:      true,
+		},
+		{
+			name:      "One remaining",
+			condition: v1alpha1.CountCondition{Operator: v1alpha1.CountNone},
+			entries:   []entry{{"a", false}, {"b", true}},
+			want:      false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			set := newMatchSet(&tt.condition)
+			for _, e := range tt.entries {
+				set.set(e.uid, map[string]interface{}{}, e.passed)
+			}
+			if got := set.satisfied(); got != tt.want {
+				t.Errorf("satisfied() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestObserver_awaitCount(t *testing.T) {
+	tests := []struct {
+		name      string
+		condition v1alpha1.CountCondition
+		existing  []runtime.Object
+		events    []watch.Event
+		wantNames []string
+	}{
+		{
+			name:      "At least two running",
+			condition: v1alpha1.CountCondition{Operator: v1alpha1.CountAtLeast, Value: 2},
+			existing:  []runtime.Object{newPod("etl-a", "Running")},
+			events: []watch.Event{
+				{Type: watch.Added, Object: newPod("etl-b", "Pending")},
+				{Type: watch.Modified, Object: newPod("etl-b", "Running")},
+			},
+			wantNames: []string{"etl-a", "etl-b"},
+		},
+		{
+			name:      "All running",
+			condition: v1alpha1.CountCondition{Operator: v1alpha1.CountAll},
+			existing:  []runtime.Object{newPod("etl-a", "Running"), newPod("etl-b", "Pending")},
+			events: []watch.Event{
+				{Type: watch.Added, Object: newPod("etl-c", "Running")},
+				{Type: watch.Deleted, Object: newPod("etl-b", "Pending")},
+			},
+			wantNames: []string{"etl-a", "etl-c"},
+		},
+		{
+			name:      "None running",
+			condition: v1alpha1.CountCondition{Operator: v1alpha1.CountNone},
+			existing:  []runtime.Object{newPod("etl-a", "Running"), newPod("etl-b", "Running")},
+			events: []watch.Event{
+				{Type: watch.Modified, Object: newPod("etl-a", "Succeeded")},
+				{Type: watch.Deleted, Object: newPod("etl-b", "Running")},
+			},
+			wantNames: []string{},
+		},
+		{
+			name:      "None existing",
+			condition: v1alpha1.CountCondition{Operator: v1alpha1.CountNone},
+			wantNames: []string{},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			watcher := watch.NewFake()
+			client := fake.NewSimpleDynamicClient(runtime.NewScheme(), tt.existing...)
+			client.PrependWatchReactor("pods", k8stesting.DefaultWatchReactor(watcher, nil))
+
+			go func() {
+				for _, evt := range tt.events {
+					watcher.Action(evt.Type, evt.Object)
+				}
+			}()
+
+			tt.condition.Selector = &metav1.LabelSelector{MatchLabels: map[string]string{"app": "etl"}}
+			gvr := schema.GroupVersionResource{Version: "v1", Resource: "pods"}
+			obs := newObserver(client.Resource(gvr), "default", &v1alpha1.Resource{Name: "pods", Version: "v1", Kind: "Pod"},
+				[]string{`status.phase=="Running"`}, "", 0, &tt.condition, clock.RealClock{})
+
+			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+
+			var got *unstructured.Unstructured
+			err := obs.Await(ctx, func(ctx context.Context, obj *unstructured.Unstructured) error {
+				got = obj
+				return nil
+			})
+			if err != nil {
+				t.Fatalf("Await() error = %v", err)
+			}
+
+			list, err := got.ToList()
+			if err != nil {
+				t.Fatalf("Await() object is not a list: %v", err)
+			}
+			names := []string{}
+			for _, item := range list.Items {
+				names = append(names, item.GetName())
+			}
+			if len(names) != len(tt.wantNames) {
+				t.Fatalf("Await() matched = %v, want %v", names, tt.wantNames)
+			}
+			for i := range names {
+				if names[i] != tt.wantNames[i] {
+					t.Errorf("Await() matched = %v, want %v", names, tt.wantNames)
+				}
+			}
+		})
+	}
+}
+
+func TestObserver_awaitCountWatchClosed(t *testing.T) {
+	watchers := make(chan *watch.FakeWatcher, 2)
+	client := fake.NewSimpleDynamicClient(runtime.NewScheme(), newPod("etl-a", "Running"), newPod("etl-b", "Pending"))
+	client.PrependWatchReactor("pods", rewatchReactor(watchers))
+
+	gvr := schema.GroupVersionResource{Version: "v1", Resource: "pods"}
+	condition := &v1alpha1.CountCondition{Operator: v1alpha1.CountAll}
+	obs := newObserver(client.Resource(gvr), "default", &v1alpha1.Resource{Name: "pods", Version: "v1", Kind: "Pod"},
+		[]string{`status.phase=="Running"`}, "", 0, condition, clock.RealClock{})
+
+	go func() {
+		// The Pod deleted while the watch has expired is missing in the next List
+		watcher := <-watchers
+		if err := client.Resource(gvr).Namespace("default").Delete("etl-b", nil); err != nil {
+			t.Error(err)
+		}
+		watcher.Stop()
+	}()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	var got *unstructured.Unstructured
+	err := obs.Await(ctx, func(ctx context.Context, obj *unstructured.Unstructured) error {
+		got = obj
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Await() error = %v", err)
+	}
+
+	list, err := got.ToList()
+	if err != nil {
+		t.Fatalf("Await() object is not a list: %v", err)
+	}
+	if len(list.Items) != 1 || list.Items[0].GetName() != "etl-a" {
+		t.Errorf("Await() matched = %v, want [etl-a]", list.Items)
+	}
+}
@@ -1,15 +1,23 @@
 package resource
 
 import (
-	"fmt"
+	"context"
+	"time"
 
 	v1alpha1 "github.com/cermakm/argo-await-operator/api/v1alpha1"
 	"github.com/cermakm/argo-await-operator/common"
+	"github.com/cermakm/argo-await-operator/metrics"
+	"github.com/cermakm/argo-await-operator/observers/filter"
+	"github.com/cermakm/argo-await-operator/observers/stability"
+	"github.com/cermakm/argo-await-operator/tracing"
+	"go.opentelemetry.io/otel/label"
 
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/clock"
 	"k8s.io/apimachinery/pkg/watch"
 	"k8s.io/client-go/dynamic"
 	"k8s.io/client-go/rest"
@@ -19,6 +27,9 @@ import (
 
 var log = logf.Log.WithName("observer")
 
+// ErrInvalidFilters is returned by Await when the filters can not be evaluated
+var ErrInvalidFilters = filter.ErrInvalidFilters
+
 // Observer watches for specified resources
 type Observer struct {
 	client dynamic.NamespaceableResourceInterface
@@ -27,6 +38,15 @@ type Observer struct {
 	resource  *v1alpha1.Resource
 	filters   []string
 
+	// tracker keeps the previous state of the resources to evaluate the filters against
+	tracker *filter.Tracker
+	// window holds the resources passing the filters until they are stable
+	window *stability.Window
+	clock  clock.Clock
+
+	// count is the aggregate condition over the resources, nil if the first resource fulfills the Await
+	count *v1alpha1.CountCondition
+
 	K8RestConfig *rest.Config
 }
 
@@ -46,11 +66,10 @@ func (obs *Observer) Watch(opts metav1.ListOptions) (watch.Interface, error) {
 }
 
 // Await awaits a resource based on given filters
-func (obs *Observer) Await(callback func() error) error {
-	watchInterface, err := obs.Watch(metav1.ListOptions{})
-	if err != nil {
-		log.Error(err, "error creating a watch for resource", "resource", *obs.resource)
-		panic(err)
+// and calls the callback with the resource which fulfilled them
+func (obs *Observer) Await(ctx context.Context, callback func(ctx context.Context, obj *unstructured.Unstructured) error) error {
+	if obs.count != nil {
+		return obs.awaitCount(ctx, callback)
 	}
 
 	log.WithValues(
@@ -59,50 +78,172 @@ func (obs *Observer) Await(callback func() error) error {
 		"kind", obs.resource.Kind,
 		"namespace", obs.namespace,
 	).Info("watching for resources")
+
+	metrics.ActiveObservers.WithLabelValues(obs.resource.Kind, obs.namespace).Inc()
+	defer metrics.ActiveObservers.WithLabelValues(obs.resource.Kind, obs.namespace).Dec()
+
 	for {
+		watchInterface, err := obs.startWatch(ctx, metav1.ListOptions{})
+		if err != nil {
+			return err
+		}
+
+		// The filter tracker and the stable window are kept across the watches
+		object, err := obs.watch(ctx, watchInterface)
+		watchInterface.Stop()
+		if err != nil {
+			return err
+		}
+		if object != nil {
+			log.Info("resource fulfilled")
+
+			// Execute the callback function and return
+			return callback(ctx, &unstructured.Unstructured{Object: object})
+		}
+
+		// The watch has expired, start a new one
+	}
+}
+
+// startWatch starts a watch of the resources in a traced span
+func (obs *Observer) startWatch(ctx context.Context, opts metav1.ListOptions) (watch.Interface, error) {
+	_, span := tracing.StartSpan(ctx, "Watch",
+		label.String("resource.kind", obs.resource.Kind),
+		label.String("resource.namespace", obs.namespace),
+	)
+	defer span.End()
+
+	watchInterface, err := obs.Watch(opts)
+	if err != nil {
+		log.Error(err, "error creating a watch for resource", "resource", *obs.resource)
+		tracing.RecordError(ctx, span, err)
+		return nil, err
+	}
+	return watchInterface, nil
+}
+
+// watch processes the events until a resource is stable, nil is returned
+// if the watch has been closed or has failed
+func (obs *Observer) watch(ctx context.Context, watchInterface watch.Interface) (map[string]interface{}, error) {
+	for {
+		// The resources passing the filters are fulfilled once stable
+		var timer clock.Timer
+		var stable <-chan time.Time
+		if remaining, ok := obs.window.Next(); ok {
+			timer = obs.clock.NewTimer(remaining)
+			stable = timer.C()
+		}
+
 		select {
-		case evt := <-watchInterface.ResultChan():
-			log := log.WithValues(
-				"type", evt.Type,
-				"resource", evt.Object.GetObjectKind().GroupVersionKind(),
-			)
-			log.Info("new event received")
-			log.V(2).Info("received event", "event", evt)
-
-			gvk := evt.Object.GetObjectKind().GroupVersionKind()
-			if obs.resource.Kind != gvk.Kind {
-				log.Info("resource does not match required kind: ", "kind", obs.resource.Kind)
+		case <-ctx.Done():
+			stopTimer(timer)
+			return nil, ctx.Err()
+		case <-stable:
+		case evt, open := <-watchInterface.ResultChan():
+			stopTimer(timer)
+			if !open || watchFailed(evt) {
+				return nil, nil
+			}
+
+			object, ok := obs.eventObject(evt)
+			if !ok {
 				continue
 			}
+			uid := string((&unstructured.Unstructured{Object: object}).GetUID())
 
-			unstructured, err := runtime.DefaultUnstructuredConverter.ToUnstructured(evt.Object)
+			metrics.FilterEvaluations.WithLabelValues(obs.resource.Kind, obs.namespace).Inc()
+			ok, err := obs.evaluateFilters(ctx, object)
 			if err != nil {
-				log.Error(err, "Unable to convert runtime object to unstructured")
-				continue
+				metrics.FilterErrors.WithLabelValues(obs.resource.Kind, obs.namespace).Inc()
+				return nil, ErrInvalidFilters
+			}
+			if !ok {
+				log.Info("resource dit not pass the filters")
 			}
+			obs.window.Observe(uid, object, ok)
 
-			if ok, err := passFilters(unstructured, obs.filters...); ok == false {
-				if err != nil {
-					return fmt.Errorf("Unable to parse resource filters")
+			if evt.Type == watch.Deleted {
+				if object, ok := obs.window.Stable(); ok {
+					return object, nil
 				}
-
-				log.Info("resource dit not pass the filters")
+				obs.tracker.Forget(uid)
+				obs.window.Forget(uid)
 				continue
 			}
+		}
 
-			log.Info("resource fulfilled")
-
-			// Execute the callback function and return
-			return callback()
+		if object, ok := obs.window.Stable(); ok {
+			return object, nil
 		}
 	}
 }
 
+// watchFailed returns whether the event is the error the watch has failed with,
+// e.g. 410 Gone once the resource version is too old
+func watchFailed(evt watch.Event) bool {
+	if evt.Type != watch.Error {
+		return false
+	}
+
+	log.Info("the watch has failed", "status", apierrors.FromObject(evt.Object))
+	return true
+}
+
+// eventObject returns the object of the event, false if it is not the awaited resource
+func (obs *Observer) eventObject(evt watch.Event) (map[string]interface{}, bool) {
+	log := log.WithValues(
+		"type", evt.Type,
+		"resource", evt.Object.GetObjectKind().GroupVersionKind(),
+	)
+	log.Info("new event received")
+	metrics.EventsProcessed.WithLabelValues(obs.resource.Kind, obs.namespace).Inc()
+	log.V(2).Info("received event", "event", evt)
+
+	gvk := evt.Object.GetObjectKind().GroupVersionKind()
+	if obs.resource.Kind != gvk.Kind {
+		log.Info("resource does not match required kind: ", "kind", obs.resource.Kind)
+		return nil, false
+	}
+
+	object, err := runtime.DefaultUnstructuredConverter.ToUnstructured(evt.Object)
+	if err != nil {
+		log.Error(err, "Unable to convert runtime object to unstructured")
+		return nil, false
+	}
+	return object, true
+}
+
+// stopTimer stops the timer, if any
+func stopTimer(timer clock.Timer) {
+	if timer != nil {
+		timer.Stop()
+	}
+}
+
+// evaluateFilters evaluates the filters against the object in a traced span
+func (obs *Observer) evaluateFilters(ctx context.Context, object map[string]interface{}) (bool, error) {
+	obj := unstructured.Unstructured{Object: object}
+
+	ctx, span := tracing.StartSpan(ctx, "EvaluateFilters",
+		label.String("object.name", obj.GetName()),
+		label.String("object.namespace", obj.GetNamespace()),
+	)
+	defer span.End()
+
+	ok, err := obs.tracker.Pass(string(obj.GetUID()), object)
+	if err != nil {
+		tracing.RecordError(ctx, span, err)
+	}
+	span.SetAttributes(label.Bool("filters.passed", ok))
+
+	return ok, err
+}
+
 // NewObserverForResource create a new ResourceObserver from kubernetes config
-func NewObserverForResource(conf *rest.Config, res *v1alpha1.Resource, filters []string) (*Observer, error) {
+func NewObserverForResource(conf *rest.Config, res *v1alpha1.Resource, filters []string, mode v1alpha1.FilterMode, stableFor time.Duration, count *v1alpha1.CountCondition) (*Observer, error) {
 	ns, err := common.GetWatchNamespace()
 	if err != nil {
-		panic(err)
+		return nil, err
 	}
 
 	gvr := schema.GroupVersionResource{
@@ -110,13 +251,30 @@ func NewObserverForResource(conf *rest.Config, res *v1alpha1.Resource, filters [
 		Version:  res.Version,
 		Resource: res.Name,
 	}
-	resourceClient := dynamic.NewForConfigOrDie(conf).Resource(gvr)
+	client, err := dynamic.NewForConfig(conf)
+	if err != nil {
+		return nil, err
+	}
+	resourceClient := client.Resource(gvr)
 
+	obs := newObserver(resourceClient, ns, res, filters, mode, stableFor, count, clock.RealClock{})
+	obs.K8RestConfig = conf
+
+	return obs, nil
+}
+
+// newObserver creates a new Observer of the resources using the client, the resources
+// are stable once they have passed the filters for the stableFor duration of the clock
+func newObserver(client dynamic.NamespaceableResourceInterface, namespace string, res *v1alpha1.Resource,
+	filters []string, mode v1alpha1.FilterMode, stableFor time.Duration, count *v1alpha1.CountCondition, clk clock.Clock) *Observer {
 	return &Observer{
-		client:       resourceClient,
-		namespace:    ns,
-		resource:     res,
-		filters:      filters,
-		K8RestConfig: conf,
-	}, nil
+		client:    client,
+		namespace: namespace,
+		resource:  res,
+		filters:   filters,
+		tracker:   filter.NewTracker(mode == v1alpha1.FilterModeChanged, filters...),
+		window:    stability.NewWindow(stableFor, clk),
+		clock:     clk,
+		count:     count,
+	}
 }
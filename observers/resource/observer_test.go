@@ -0,0 +1,178 @@
+package resource
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	v1alpha1 "github.com/cermakm/argo-await-operator/api/v1alpha1"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/clock"
+	"k8s.io/apimachinery/pkg/watch"
+	"k8s.io/client-go/dynamic/fake"
+	k8stesting "k8s.io/client-go/testing"
+)
+
+// newWatchedObserver creates an Observer of the Pods watched by the fake watcher
+func newWatchedObserver(watcher *watch.FakeWatcher, filters []string, stableFor time.Duration, clk clock.Clock) *Observer {
+	client := fake.NewSimpleDynamicClient(runtime.NewScheme())
+	client.PrependWatchReactor("pods", k8stesting.DefaultWatchReactor(watcher, nil))
+
+	gvr := schema.GroupVersionResource{Version: "v1", Resource: "pods"}
+	return newObserver(client.Resource(gvr), "default", &v1alpha1.Resource{Name: "pods", Version: "v1", Kind: "Pod"},
+		filters, "", stableFor, nil, clk)
+}
+
+// waitForTimer waits until the Observer waits for the resources to be stable
+func waitForTimer(t *testing.T, clk *clock.FakeClock) {
+	deadline := time.Now().Add(5 * time.Second)
+	for !clk.HasWaiters() {
+		if time.Now().After(deadline) {
+			t.Fatal("the observer is not waiting for the resources to be stable")
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestObserver_AwaitStable(t *testing.T) {
+	const stableFor = time.Minute
+	tests := []struct {
+		name string
+		// events sends the events and steps the clock
+		events    func(t *testing.T, watcher *watch.FakeWatcher, clk *clock.FakeClock)
+		wantName  string
+		wantError error
+	}{
+		{
+			name: "Passing for the stable window",
+			events: func(t *testing.T, watcher *watch.FakeWatcher, clk *clock.FakeClock) {
+				watcher.Add(newPod("etl-a", "Pending"))
+				watcher.Modify(newPod("etl-a", "Running"))
+				waitForTimer(t, clk)
+				clk.Step(stableFor)
+			},
+			wantName: "etl-a",
+		},
+		{
+			name: "Not passing before the end of the stable window",
+			events: func(t *testing.T, watcher *watch.FakeWatcher, clk *clock.FakeClock) {
+				watcher.Add(newPod("etl-a", "Running"))
+				waitForTimer(t, clk)
+				clk.Step(stableFor / 2)
+				watcher.Modify(newPod("etl-a", "Failed"))
+				clk.Step(stableFor)
+			},
+			wantError: context.Canceled,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			watcher := watch.NewFake()
+			clk := clock.NewFakeClock(time.Now())
+			obs := newWatchedObserver(watcher, []string{`status.phase=="Running"`}, stableFor, clk)
+
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+
+			var got *unstructured.Unstructured
+			errs := make(chan error, 1)
+			go func() {
+				errs <- obs.Await(ctx, func(ctx context.Context, obj *unstructured.Unstructured) error {
+					got = obj
+					return nil
+				})
+			}()
+
+			tt.events(t, watcher, clk)
+			if tt.wantError != nil {
+				// The Observer keeps waiting until it is cancelled
+				cancel()
+			}
+
+			select {
+			case err := <-errs:
+				if err != tt.wantError {
+					t.Fatalf("Await() error = %v, want %v", err, tt.wantError)
+				}
+			case <-time.After(5 * time.Second):
+				t.Fatal("Await() has not returned")
+			}
+			if tt.wantName == "" && got != nil {
+				t.Errorf("Await() fulfilled by %v", got.GetName())
+			}
+			if tt.wantName != "" && (got == nil || got.GetName() != tt.wantName) {
+				t.Errorf("Await() fulfilled by %v, want %v", got, tt.wantName)
+			}
+		})
+	}
+}
+
+// rewatchReactor creates a new fake watcher for every watch and sends it to the watchers
+func rewatchReactor(watchers chan<- *watch.FakeWatcher) k8stesting.WatchReactionFunc {
+	return func(k8stesting.Action) (bool, watch.Interface, error) {
+		watcher := watch.NewFake()
+		watchers <- watcher
+		return true, watcher, nil
+	}
+}
+
+func TestObserver_AwaitWatchClosed(t *testing.T) {
+	watchers := make(chan *watch.FakeWatcher, 2)
+	client := fake.NewSimpleDynamicClient(runtime.NewScheme())
+	client.PrependWatchReactor("pods", rewatchReactor(watchers))
+
+	gvr := schema.GroupVersionResource{Version: "v1", Resource: "pods"}
+	obs := newObserver(client.Resource(gvr), "default", &v1alpha1.Resource{Name: "pods", Version: "v1", Kind: "Pod"},
+		[]string{`status.phase=="Running"`}, "", 0, nil, clock.RealClock{})
+
+	go func() {
+		// The first watch expires, the resource is awaited by the next one
+		watcher := <-watchers
+		watcher.Add(newPod("etl-a", "Pending"))
+		watcher.Error(&metav1.Status{Status: metav1.StatusFailure, Code: http.StatusGone, Reason: metav1.StatusReasonExpired})
+		watcher = <-watchers
+		watcher.Stop()
+		watcher = <-watchers
+		watcher.Modify(newPod("etl-a", "Running"))
+	}()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	var got *unstructured.Unstructured
+	err := obs.Await(ctx, func(ctx context.Context, obj *unstructured.Unstructured) error {
+		got = obj
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Await() error = %v", err)
+	}
+	if got == nil || got.GetName() != "etl-a" {
+		t.Errorf("Await() fulfilled by %v, want etl-a", got)
+	}
+}
+
+func TestObserver_AwaitWatchError(t *testing.T) {
+	client := fake.NewSimpleDynamicClient(runtime.NewScheme())
+	client.PrependWatchReactor("pods", func(k8stesting.Action) (bool, watch.Interface, error) {
+		return true, nil, fmt.Errorf("forbidden")
+	})
+
+	gvr := schema.GroupVersionResource{Version: "v1", Resource: "pods"}
+	obs := newObserver(client.Resource(gvr), "default", &v1alpha1.Resource{Name: "pods", Version: "v1", Kind: "Pod"},
+		nil, "", 0, nil, clock.RealClock{})
+
+	err := obs.Await(context.Background(), func(ctx context.Context, obj *unstructured.Unstructured) error {
+		t.Errorf("Await() fulfilled by %v", obj.GetName())
+		return nil
+	})
+	if err == nil {
+		t.Error("Await() error = nil, want the watch error")
+	}
+}
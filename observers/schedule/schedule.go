@@ -0,0 +1,66 @@
+package schedule
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/robfig/cron/v3"
+)
+
+// DefaultTimeZone is the time zone of the cron expressions if not set
+const DefaultTimeZone = "UTC"
+
+// Schedule computes the time the Await is fulfilled at
+type Schedule interface {
+	// Next returns the time the Await started at the given time is fulfilled at
+	Next(from time.Time) time.Time
+}
+
+// at fulfills the Awaits at an absolute time
+type at time.Time
+
+func (s at) Next(from time.Time) time.Time {
+	return time.Time(s)
+}
+
+// At creates a Schedule fulfilled at the absolute time
+func At(t time.Time) Schedule {
+	return at(t)
+}
+
+// Cron creates a Schedule fulfilled at the next time matching the standard cron
+// expression in the time zone, DefaultTimeZone if empty
+func Cron(expr, timeZone string) (Schedule, error) {
+	if strings.HasPrefix(expr, "TZ=") || strings.HasPrefix(expr, "CRON_TZ=") {
+		return nil, fmt.Errorf("the time zone must be set separately from the cron expression")
+	}
+	if timeZone == "" {
+		timeZone = DefaultTimeZone
+	}
+	if _, err := time.LoadLocation(timeZone); err != nil {
+		return nil, fmt.Errorf("unknown time zone %q: %v", timeZone, err)
+	}
+
+	s, err := cron.ParseStandard(fmt.Sprintf("CRON_TZ=%s %s", timeZone, expr))
+	if err != nil {
+		return nil, err
+	}
+	// The expressions of e.g. February 30 parse, but are never due
+	if s.Next(time.Now()).IsZero() {
+		return nil, fmt.Errorf("the cron expression never matches")
+	}
+
+	return s, nil
+}
+
+// Remaining returns the duration until the Await started at the given time
+// is fulfilled, zero if it is already due
+func Remaining(s Schedule, from, now time.Time) time.Duration {
+	remaining := s.Next(from).Sub(now)
+	if remaining < 0 {
+		return 0
+	}
+
+	return remaining
+}
@@ -0,0 +1,82 @@
+package schedule
+
+import (
+	"testing"
+	"time"
+)
+
+func TestCron(t *testing.T) {
+	// Monday 2019-09-02 18:30 UTC
+	from := time.Date(2019, time.September, 2, 18, 30, 0, 0, time.UTC)
+
+	tests := []struct {
+		name     string
+		expr     string
+		timeZone string
+		want     time.Time
+		wantErr  bool
+	}{
+		{
+			name: "Next business hours in UTC",
+			expr: "0 9 * * 1-5",
+			want: time.Date(2019, time.September, 3, 9, 0, 0, 0, time.UTC),
+		},
+		{
+			name:     "Next business hours in a time zone",
+			expr:     "0 9 * * 1-5",
+			timeZone: "America/New_York",
+			want:     time.Date(2019, time.September, 3, 13, 0, 0, 0, time.UTC),
+		},
+		{
+			name: "Descriptor",
+			expr: "@every 1h",
+			want: from.Add(time.Hour),
+		},
+		{
+			name:    "Invalid expression",
+			expr:    "0 9 * *",
+			wantErr: true,
+		},
+		{
+			name:    "Never matching expression",
+			expr:    "0 0 30 2 *",
+			wantErr: true,
+		},
+		{
+			name:     "Unknown time zone",
+			expr:     "0 9 * * 1-5",
+			timeZone: "Mars/Olympus_Mons",
+			wantErr:  true,
+		},
+		{
+			name:    "Time zone in the expression",
+			expr:    "CRON_TZ=UTC 0 9 * * 1-5",
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, err := Cron(tt.expr, tt.timeZone)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Cron() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if got := s.Next(from); !got.Equal(tt.want) {
+				t.Errorf("Next() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRemaining(t *testing.T) {
+	now := time.Date(2019, time.September, 2, 18, 30, 0, 0, time.UTC)
+
+	if got := Remaining(At(now.Add(time.Minute)), now, now); got != time.Minute {
+		t.Errorf("Remaining() = %v, want %v", got, time.Minute)
+	}
+	if got := Remaining(At(now.Add(-time.Minute)), now, now); got != 0 {
+		t.Errorf("Remaining() = %v, want due", got)
+	}
+}
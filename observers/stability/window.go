@@ -0,0 +1,92 @@
+package stability
+
+import (
+	"time"
+
+	"k8s.io/apimachinery/pkg/util/clock"
+)
+
+// Window holds back the objects passing the filters until they have passed
+// them continuously for its duration, the objects are kept by their keys, e.g. UIDs
+type Window struct {
+	clock    clock.Clock
+	duration time.Duration
+
+	// passing are the objects passing the filters and the times they started to pass at
+	passing map[string]passing
+}
+
+type passing struct {
+	object map[string]interface{}
+	since  time.Time
+}
+
+// NewWindow creates a new Window of the duration, the objects
+// passing the filters are stable right away if the duration is zero
+func NewWindow(duration time.Duration, clock clock.Clock) *Window {
+	return &Window{
+		clock:    clock,
+		duration: duration,
+		passing:  map[string]passing{},
+	}
+}
+
+// Observe records whether the object passes the filters. The window of the object
+// is started once it passes and reset whenever it does not.
+func (w *Window) Observe(key string, object map[string]interface{}, passed bool) {
+	if !passed {
+		delete(w.passing, key)
+		return
+	}
+
+	p, ok := w.passing[key]
+	if !ok {
+		p.since = w.clock.Now()
+	}
+	p.object = object
+	w.passing[key] = p
+}
+
+// Forget removes the object, e.g. once it has been deleted
+func (w *Window) Forget(key string) {
+	delete(w.passing, key)
+}
+
+// Stable returns the latest state of the object which has been passing
+// the filters for the longest time, if it has been passing for the duration
+func (w *Window) Stable() (map[string]interface{}, bool) {
+	key, ok := w.earliest()
+	if !ok || w.clock.Since(w.passing[key].since) < w.duration {
+		return nil, false
+	}
+
+	return w.passing[key].object, true
+}
+
+// Next returns the time until an object becomes stable, false if there is no object passing
+func (w *Window) Next() (time.Duration, bool) {
+	key, ok := w.earliest()
+	if !ok {
+		return 0, false
+	}
+
+	remaining := w.duration - w.clock.Since(w.passing[key].since)
+	if remaining < 0 {
+		remaining = 0
+	}
+	return remaining, true
+}
+
+// earliest returns the key of the object passing the filters for the longest time
+func (w *Window) earliest() (string, bool) {
+	var earliest string
+	var found bool
+	for key, p := range w.passing {
+		if !found || p.since.Before(w.passing[earliest].since) ||
+			(p.since.Equal(w.passing[earliest].since) && key < earliest) {
+			earliest, found = key, true
+		}
+	}
+
+	return earliest, found
+}
@@ -0,0 +1,122 @@
+package stability
+
+import (
+	"testing"
+	"time"
+
+	"k8s.io/apimachinery/pkg/util/clock"
+)
+
+func TestWindow(t *testing.T) {
+	ready := map[string]interface{}{"status": "ready"}
+	starting := map[string]interface{}{"status": "starting"}
+
+	// observation of a nil object forgets the key
+	type observation struct {
+		key    string
+		object map[string]interface{}
+		passed bool
+	}
+	tests := []struct {
+		name     string
+		duration time.Duration
+		// steps are the observations made after the clock has been stepped by a second
+		steps      [][]observation
+		wantStable []bool
+	}{
+		{
+			name:       "No duration is stable right away",
+			steps:      [][]observation{{{"a", ready, true}}},
+			wantStable: []bool{true},
+		},
+		{
+			name:     "Passing for the duration",
+			duration: 2 * time.Second,
+			steps: [][]observation{
+				{{"a", ready, true}},
+				{{"a", ready, true}},
+				{},
+			},
+			wantStable: []bool{false, false, true},
+		},
+		{
+			name:     "Failure resets the window",
+			duration: 2 * time.Second,
+			steps: [][]observation{
+				{{"a", ready, true}},
+				{{"a", starting, false}},
+				{{"a", ready, true}},
+				{},
+				{},
+			},
+			wantStable: []bool{false, false, false, false, true},
+		},
+		{
+			name:     "Forgotten object",
+			duration: time.Second,
+			steps: [][]observation{
+				{{"a", ready, true}},
+				{{"a", nil, false}},
+				{},
+			},
+			wantStable: []bool{false, false, false},
+		},
+		{
+			name:     "Other object keeps passing",
+			duration: 2 * time.Second,
+			steps: [][]observation{
+				{{"a", ready, true}},
+				{{"b", ready, true}, {"a", starting, false}},
+				{},
+				{},
+			},
+			wantStable: []bool{false, false, false, true},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fakeClock := clock.NewFakeClock(time.Date(2019, 9, 1, 12, 0, 0, 0, time.UTC))
+			w := NewWindow(tt.duration, fakeClock)
+
+			for i, step := range tt.steps {
+				if i > 0 {
+					fakeClock.Step(time.Second)
+				}
+				for _, o := range step {
+					if o.object == nil {
+						w.Forget(o.key)
+						continue
+					}
+					w.Observe(o.key, o.object, o.passed)
+				}
+
+				_, stable := w.Stable()
+				if stable != tt.wantStable[i] {
+					t.Errorf("Stable() at step %d = %v, want %v", i, stable, tt.wantStable[i])
+				}
+			}
+		})
+	}
+}
+
+func TestWindow_Next(t *testing.T) {
+	fakeClock := clock.NewFakeClock(time.Date(2019, 9, 1, 12, 0, 0, 0, time.UTC))
+	w := NewWindow(time.Minute, fakeClock)
+
+	if _, ok := w.Next(); ok {
+		t.Error("Next() ok = true with no object passing")
+	}
+
+	w.Observe("a", map[string]interface{}{}, true)
+	fakeClock.Step(20 * time.Second)
+	w.Observe("b", map[string]interface{}{}, true)
+
+	if remaining, ok := w.Next(); !ok || remaining != 40*time.Second {
+		t.Errorf("Next() = %v, %v, want %v, true", remaining, ok, 40*time.Second)
+	}
+
+	fakeClock.Step(time.Minute)
+	if remaining, ok := w.Next(); !ok || remaining != 0 {
+		t.Errorf("Next() = %v, %v, want 0, true", remaining, ok)
+	}
+}
@@ -0,0 +1,261 @@
+package workflow
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	workflowv1alpha1 "github.com/argoproj/argo/pkg/apis/workflow/v1alpha1"
+
+	v1alpha1 "github.com/cermakm/argo-await-operator/api/v1alpha1"
+	"github.com/cermakm/argo-await-operator/metrics"
+	"github.com/cermakm/argo-await-operator/observers/filter"
+	"github.com/cermakm/argo-await-operator/tracing"
+	"go.opentelemetry.io/otel/label"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/watch"
+	"k8s.io/client-go/dynamic"
+
+	logf "sigs.k8s.io/controller-runtime/pkg/runtime/log"
+)
+
+var log = logf.Log.WithName("workflow-observer")
+
+// Resource is the resource of the Argo Workflows
+var Resource = schema.GroupVersionResource{Group: "argoproj.io", Version: "v1alpha1", Resource: "workflows"}
+
+// UnexpectedOutcome is returned by Await when the awaited Workflow
+// has completed with an outcome other than the awaited one
+type UnexpectedOutcome struct {
+	Name      string
+	Namespace string
+	Phase     workflowv1alpha1.NodePhase
+}
+
+func (e *UnexpectedOutcome) Error() string {
+	return fmt.Sprintf("Workflow %s/%s has completed with phase %s", e.Namespace, e.Name, e.Phase)
+}
+
+// Observer watches for the completion of the Argo Workflows
+type Observer struct {
+	client dynamic.NamespaceableResourceInterface
+
+	completion *v1alpha1.WorkflowCompletion
+	filters    *filter.Filters
+}
+
+// NewObserver creates a new Observer of the completion using the dynamic client
+func NewObserver(client dynamic.Interface, completion *v1alpha1.WorkflowCompletion, filters []string) *Observer {
+	return &Observer{
+		client:     client.Resource(Resource),
+		completion: completion,
+		filters:    filter.Compile(filters...),
+	}
+}
+
+// Await awaits the completion of a Workflow with the awaited outcome and calls
+// the callback with the Workflow. If the Workflow awaited by name completes
+// with another outcome, *UnexpectedOutcome is returned.
+func (obs *Observer) Await(ctx context.Context, callback func(ctx context.Context, obj *unstructured.Unstructured) error) error {
+	namespace := obs.completion.Namespace
+	log := log.WithValues("namespace", namespace, "name", obs.completion.Name, "outcome", obs.completion.Outcome)
+
+	opts := metav1.ListOptions{}
+	if obs.completion.Name != "" {
+		opts.FieldSelector = fields.OneTermEqualSelector("metadata.name", obs.completion.Name).String()
+	}
+	if obs.completion.Selector != nil {
+		selector, err := metav1.LabelSelectorAsSelector(obs.completion.Selector)
+		if err != nil {
+			return err
+		}
+		opts.LabelSelector = selector.String()
+	}
+
+	metrics.ActiveObservers.WithLabelValues(v1alpha1.ObservedKindWorkflow, namespace).Inc()
+	defer metrics.ActiveObservers.WithLabelValues(v1alpha1.ObservedKindWorkflow, namespace).Dec()
+
+	for {
+		watchInterface, err := obs.client.Namespace(namespace).Watch(opts)
+		if err != nil {
+			log.Error(err, "error creating a watch for workflows")
+			return err
+		}
+		log.Info("watching for workflows")
+
+		obj, err := obs.watch(ctx, watchInterface)
+		watchInterface.Stop()
+		if err != nil || obj != nil {
+			if obj != nil {
+				log.Info("workflow completed", "workflow", obj.GetName())
+				return callback(ctx, obj)
+			}
+			return err
+		}
+
+		// The watch has expired, start a new one
+	}
+}
+
+// watch processes the events until a Workflow completes with the awaited outcome,
+// nil is returned if the watch has been closed
+func (obs *Observer) watch(ctx context.Context, watchInterface watch.Interface) (*unstructured.Unstructured, error) {
+	namespace := obs.completion.Namespace
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case evt, ok := <-watchInterface.ResultChan():
+			if !ok {
+				return nil, nil
+			}
+			if evt.Type != watch.Added && evt.Type != watch.Modified {
+				continue
+			}
+			metrics.EventsProcessed.WithLabelValues(v1alpha1.ObservedKindWorkflow, namespace).Inc()
+
+			object, err := runtime.DefaultUnstructuredConverter.ToUnstructured(evt.Object)
+			if err != nil {
+				log.Error(err, "Unable to convert runtime object to unstructured")
+				continue
+			}
+			wf := &workflowv1alpha1.Workflow{}
+			if err := runtime.DefaultUnstructuredConverter.FromUnstructured(object, wf); err != nil {
+				log.Error(err, "Unable to convert unstructured object to workflow")
+				continue
+			}
+
+			status, ok := obs.completed(wf)
+			if !ok {
+				continue
+			}
+			if !Matches(obs.completion.Outcome, status) {
+				if obs.completion.Name != "" {
+					return nil, &UnexpectedOutcome{Name: wf.Name, Namespace: wf.Namespace, Phase: status.Phase}
+				}
+				continue
+			}
+
+			if ok, err := obs.evaluateFilters(ctx, object); !ok {
+				if err != nil {
+					metrics.FilterErrors.WithLabelValues(v1alpha1.ObservedKindWorkflow, namespace).Inc()
+					return nil, filter.ErrInvalidFilters
+				}
+				continue
+			}
+
+			return &unstructured.Unstructured{Object: object}, nil
+		}
+	}
+}
+
+// completed returns the status of the awaited Workflow or its node, if completed
+func (obs *Observer) completed(wf *workflowv1alpha1.Workflow) (workflowv1alpha1.NodeStatus, bool) {
+	if obs.completion.Name != "" && wf.Name != obs.completion.Name {
+		return workflowv1alpha1.NodeStatus{}, false
+	}
+
+	if obs.completion.Node == "" {
+		status := workflowv1alpha1.NodeStatus{Phase: wf.Status.Phase}
+		return status, wf.Status.Completed()
+	}
+
+	node, ok := Node(wf, obs.completion.Node)
+	if !ok || !node.Completed() {
+		return workflowv1alpha1.NodeStatus{}, false
+	}
+
+	return node, true
+}
+
+// evaluateFilters evaluates the filters against the Workflow in a traced span
+func (obs *Observer) evaluateFilters(ctx context.Context, object map[string]interface{}) (bool, error) {
+	obj := unstructured.Unstructured{Object: object}
+
+	ctx, span := tracing.StartSpan(ctx, "EvaluateFilters",
+		label.String("object.name", obj.GetName()),
+		label.String("object.namespace", obj.GetNamespace()),
+	)
+	defer span.End()
+
+	metrics.FilterEvaluations.WithLabelValues(v1alpha1.ObservedKindWorkflow, obs.completion.Namespace).Inc()
+	ok, err := obs.filters.Pass(object)
+	if err != nil {
+		tracing.RecordError(ctx, span, err)
+	}
+	span.SetAttributes(label.Bool("filters.passed", ok))
+
+	return ok, err
+}
+
+// Matches returns whether the completed Workflow or node has the awaited outcome
+func Matches(outcome v1alpha1.WorkflowOutcome, status workflowv1alpha1.NodeStatus) bool {
+	switch outcome {
+	case v1alpha1.OutcomeAny:
+		return true
+	case v1alpha1.OutcomeFailed:
+		return status.Phase == workflowv1alpha1.NodeFailed || status.Phase == workflowv1alpha1.NodeError
+	default:
+		return status.Successful()
+	}
+}
+
+// Node returns the node of the Workflow with the given ID, name or display name.
+// The exact ID or name is preferred, the nodes sharing the display name, e.g. the steps
+// of the same name in different templates, are told apart by the lowest ID
+func Node(wf *workflowv1alpha1.Workflow, name string) (workflowv1alpha1.NodeStatus, bool) {
+	if node, ok := wf.Status.Nodes[name]; ok {
+		return node, true
+	}
+
+	var ids []string
+	for id, node := range wf.Status.Nodes {
+		if node.Name == name {
+			return node, true
+		}
+		if node.DisplayName == name {
+			ids = append(ids, id)
+		}
+	}
+	if len(ids) == 0 {
+		return workflowv1alpha1.NodeStatus{}, false
+	}
+
+	sort.Strings(ids)
+	return wf.Status.Nodes[ids[0]], true
+}
+
+// Outputs returns the output parameters of the node with the given name,
+// or of the Workflow itself if empty
+func Outputs(obj *unstructured.Unstructured, nodeName string) (map[string]string, error) {
+	wf := &workflowv1alpha1.Workflow{}
+	if err := runtime.DefaultUnstructuredConverter.FromUnstructured(obj.Object, wf); err != nil {
+		return nil, err
+	}
+
+	var node workflowv1alpha1.NodeStatus
+	if nodeName != "" {
+		node, _ = Node(wf, nodeName)
+	} else {
+		// The root node is named after the Workflow
+		node = wf.Status.Nodes[wf.Name]
+	}
+	if node.Outputs == nil || len(node.Outputs.Parameters) == 0 {
+		return nil, nil
+	}
+
+	outputs := make(map[string]string, len(node.Outputs.Parameters))
+	for _, param := range node.Outputs.Parameters {
+		if param.Value != nil {
+			outputs[param.Name] = *param.Value
+		}
+	}
+
+	return outputs, nil
+}
@@ -0,0 +1,195 @@
+package workflow
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	workflowv1alpha1 "github.com/argoproj/argo/pkg/apis/workflow/v1alpha1"
+
+	v1alpha1 "github.com/cermakm/argo-await-operator/api/v1alpha1"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/watch"
+	"k8s.io/client-go/dynamic/fake"
+	k8stesting "k8s.io/client-go/testing"
+)
+
+func newWorkflow(name string, phase workflowv1alpha1.NodePhase, nodes ...workflowv1alpha1.NodeStatus) *unstructured.Unstructured {
+	wf := &workflowv1alpha1.Workflow{
+		TypeMeta:   metav1.TypeMeta{APIVersion: "argoproj.io/v1alpha1", Kind: "Workflow"},
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "default"},
+		Status: workflowv1alpha1.WorkflowStatus{
+			Phase: phase,
+			Nodes: map[string]workflowv1alpha1.NodeStatus{},
+		},
+	}
+	for _, node := range nodes {
+		wf.Status.Nodes[node.ID] = node
+	}
+
+	object, err := runtime.DefaultUnstructuredConverter.ToUnstructured(wf)
+	if err != nil {
+		panic(err)
+	}
+	return &unstructured.Unstructured{Object: object}
+}
+
+func TestObserver_Await(t *testing.T) {
+	version := "1.2.3"
+	build := workflowv1alpha1.NodeStatus{
+		ID:          "upstream-1",
+		Name:        "upstream.build",
+		DisplayName: "build",
+		Phase:       workflowv1alpha1.NodeSucceeded,
+		Outputs: &workflowv1alpha1.Outputs{
+			Parameters: []workflowv1alpha1.Parameter{{Name: "version", Value: &version}},
+		},
+	}
+
+	tests := []struct {
+		name       string
+		completion v1alpha1.WorkflowCompletion
+		events     []*unstructured.Unstructured
+		wantErr    bool
+		want       string
+	}{
+		{
+			name:       "Succeeded workflow",
+			completion: v1alpha1.WorkflowCompletion{Name: "upstream", Outcome: v1alpha1.OutcomeSucceeded},
+			events: []*unstructured.Unstructured{
+				newWorkflow("upstream", workflowv1alpha1.NodeRunning),
+				newWorkflow("upstream", workflowv1alpha1.NodeSucceeded),
+			},
+			want: "upstream",
+		},
+		{
+			name:       "Failed workflow awaited to succeed",
+			completion: v1alpha1.WorkflowCompletion{Name: "upstream", Outcome: v1alpha1.OutcomeSucceeded},
+			events: []*unstructured.Unstructured{
+				newWorkflow("upstream", workflowv1alpha1.NodeError),
+			},
+			wantErr: true,
+		},
+		{
+			name: "Any selected workflow failing",
+			completion: v1alpha1.WorkflowCompletion{
+				Selector: &metav1.LabelSelector{MatchLabels: map[string]string{"app": "upstream"}},
+				Outcome:  v1alpha1.OutcomeFailed,
+			},
+			events: []*unstructured.Unstructured{
+				newWorkflow("upstream-a", workflowv1alpha1.NodeSucceeded),
+				newWorkflow("upstream-b", workflowv1alpha1.NodeFailed),
+			},
+			want: "upstream-b",
+		},
+		{
+			name:       "Completed node",
+			completion: v1alpha1.WorkflowCompletion{Name: "upstream", Node: "build", Outcome: v1alpha1.OutcomeAny},
+			events: []*unstructured.Unstructured{
+				newWorkflow("upstream", workflowv1alpha1.NodeRunning),
+				newWorkflow("upstream", workflowv1alpha1.NodeRunning, build),
+			},
+			want: "upstream",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			watcher := watch.NewFake()
+			client := fake.NewSimpleDynamicClient(runtime.NewScheme())
+			client.PrependWatchReactor("workflows", k8stesting.DefaultWatchReactor(watcher, nil))
+
+			go func() {
+				for _, evt := range tt.events {
+					watcher.Modify(evt)
+				}
+			}()
+
+			tt.completion.Namespace = "default"
+			obs := NewObserver(client, &tt.completion, nil)
+
+			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+
+			var got string
+			err := obs.Await(ctx, func(ctx context.Context, obj *unstructured.Unstructured) error {
+				got = obj.GetName()
+				return nil
+			})
+			if tt.wantErr {
+				if _, ok := err.(*UnexpectedOutcome); !ok {
+					t.Errorf("Await() error = %v, want unexpected outcome", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Await() error = %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("Await() workflow = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestOutputs(t *testing.T) {
+	version := "1.2.3"
+	root := workflowv1alpha1.NodeStatus{
+		ID:    "upstream",
+		Name:  "upstream",
+		Phase: workflowv1alpha1.NodeSucceeded,
+		Outputs: &workflowv1alpha1.Outputs{
+			Parameters: []workflowv1alpha1.Parameter{{Name: "version", Value: &version}},
+		},
+	}
+	obj := newWorkflow("upstream", workflowv1alpha1.NodeSucceeded, root)
+
+	outputs, err := Outputs(obj, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if outputs["version"] != version {
+		t.Errorf("Outputs() = %v, want version %v", outputs, version)
+	}
+
+	if outputs, _ := Outputs(obj, "missing"); outputs != nil {
+		t.Errorf("Outputs() = %v, want none for a missing node", outputs)
+	}
+}
+
+func TestNode(t *testing.T) {
+	nodes := []workflowv1alpha1.NodeStatus{
+		{ID: "upstream-2", Name: "upstream.release.build", DisplayName: "build", Phase: workflowv1alpha1.NodeRunning},
+		{ID: "upstream-1", Name: "upstream.test.build", DisplayName: "build", Phase: workflowv1alpha1.NodeSucceeded},
+		{ID: "upstream-3", Name: "upstream.deploy", DisplayName: "upstream.release.build", Phase: workflowv1alpha1.NodeFailed},
+	}
+	wf := &workflowv1alpha1.Workflow{}
+	if err := runtime.DefaultUnstructuredConverter.FromUnstructured(newWorkflow("upstream", workflowv1alpha1.NodeRunning, nodes...).Object, wf); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name   string
+		node   string
+		wantID string
+		wantOk bool
+	}{
+		{name: "Node ID", node: "upstream-3", wantID: "upstream-3", wantOk: true},
+		{name: "Node name preferred to display name", node: "upstream.release.build", wantID: "upstream-2", wantOk: true},
+		{name: "Duplicate display name", node: "build", wantID: "upstream-1", wantOk: true},
+		{name: "Missing node", node: "deploy"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// The map order is random, the same node is expected every time
+			for i := 0; i < 10; i++ {
+				got, ok := Node(wf, tt.node)
+				if ok != tt.wantOk || got.ID != tt.wantID {
+					t.Fatalf("Node() = %v, %v, want %v, %v", got.ID, ok, tt.wantID, tt.wantOk)
+				}
+			}
+		})
+	}
+}
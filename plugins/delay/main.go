@@ -0,0 +1,63 @@
+/*
+Copyright 2019 Marek Cermak <macermak@redhat.com>.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// The delay plugin is the reference implementation of an observer plugin,
+// its "delay" observer is fulfilled once the duration given by the "duration"
+// parameter has passed since the observation started.
+package main
+
+import (
+	"flag"
+	"net"
+	"os"
+
+	"github.com/cermakm/argo-await-operator/observers/plugin"
+
+	"google.golang.org/grpc"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/log/zap"
+)
+
+var setupLog = ctrl.Log.WithName("setup")
+
+func main() {
+	var socket string
+	flag.StringVar(&socket, "socket", "/var/run/await-plugins/delay"+plugin.SocketSuffix,
+		"The path of the socket the plugin listens on.")
+	flag.Parse()
+
+	ctrl.SetLogger(zap.Logger(true))
+
+	// The socket is left behind by the previous run of the plugin
+	if err := os.Remove(socket); err != nil && !os.IsNotExist(err) {
+		setupLog.Error(err, "unable to remove the socket")
+		os.Exit(1)
+	}
+	lis, err := net.Listen("unix", socket)
+	if err != nil {
+		setupLog.Error(err, "unable to listen", "socket", socket)
+		os.Exit(1)
+	}
+
+	s := grpc.NewServer()
+	plugin.RegisterObserverPluginServer(s, newServer())
+
+	setupLog.Info("serving the plugin", "socket", socket)
+	if err := s.Serve(lis); err != nil {
+		setupLog.Error(err, "problem serving the plugin")
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,122 @@
+/*
+Copyright 2019 Marek Cermak <macermak@redhat.com>.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/cermakm/argo-await-operator/observers/plugin"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// observerType is the observer type provided by the plugin
+const observerType = "delay"
+
+// server observes the delays, the observations are kept in memory
+// and are lost when the plugin is restarted
+type server struct {
+	mu sync.Mutex
+	// deadlines are the times the observations are fulfilled at, keyed by their IDs
+	deadlines map[string]time.Time
+
+	now func() time.Time
+}
+
+func newServer() *server {
+	return &server{deadlines: map[string]time.Time{}, now: time.Now}
+}
+
+// Register implements plugin.ObserverPluginServer
+func (s *server) Register(ctx context.Context, req *plugin.RegisterRequest) (*plugin.RegisterResponse, error) {
+	if req.ProtocolVersion != plugin.ProtocolVersion {
+		return nil, status.Errorf(codes.FailedPrecondition, "unsupported protocol version %q", req.ProtocolVersion)
+	}
+
+	return &plugin.RegisterResponse{Types: []plugin.ObserverType{
+		{Name: observerType, Description: "Waits for the duration given by the duration parameter"},
+	}}, nil
+}
+
+// Watch implements plugin.ObserverPluginServer
+func (s *server) Watch(ctx context.Context, req *plugin.WatchRequest) (*plugin.WatchResponse, error) {
+	if req.Type != observerType {
+		return nil, status.Errorf(codes.InvalidArgument, "unknown observer type %q", req.Type)
+	}
+	duration, err := time.ParseDuration(req.Parameters["duration"])
+	if err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "invalid duration parameter: %v", err)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.deadlines[req.ID]; !ok {
+		s.deadlines[req.ID] = s.now().Add(duration)
+	}
+
+	return &plugin.WatchResponse{}, nil
+}
+
+// Cancel implements plugin.ObserverPluginServer
+func (s *server) Cancel(ctx context.Context, req *plugin.CancelRequest) (*plugin.CancelResponse, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.deadlines, req.ID)
+
+	return &plugin.CancelResponse{}, nil
+}
+
+// Result implements plugin.ObserverPluginServer
+func (s *server) Result(req *plugin.ResultRequest, stream plugin.ResultServer) error {
+	s.mu.Lock()
+	deadline, ok := s.deadlines[req.ID]
+	s.mu.Unlock()
+	if !ok {
+		return status.Errorf(codes.NotFound, "%s is not being observed", req.ID)
+	}
+
+	if remaining := deadline.Sub(s.now()); remaining > 0 {
+		if err := stream.Send(&plugin.ResultResponse{Progress: fmt.Sprintf("%s remaining", remaining.Round(time.Second))}); err != nil {
+			return err
+		}
+
+		timer := time.NewTimer(remaining)
+		defer timer.Stop()
+		select {
+		case <-stream.Context().Done():
+			return stream.Context().Err()
+		case <-timer.C:
+		}
+	}
+
+	err := stream.Send(&plugin.ResultResponse{Object: map[string]interface{}{
+		"delayedUntil": deadline.UTC().Format(time.RFC3339),
+	}})
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	delete(s.deadlines, req.ID)
+	s.mu.Unlock()
+
+	return nil
+}
@@ -0,0 +1,102 @@
+/*
+Copyright 2019 Marek Cermak <macermak@redhat.com>.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	v1alpha1 "github.com/cermakm/argo-await-operator/api/v1alpha1"
+	"github.com/cermakm/argo-await-operator/observers"
+	"github.com/cermakm/argo-await-operator/observers/plugin"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/test/bufconn"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestServer(t *testing.T) {
+	lis := bufconn.Listen(1 << 20)
+	s := grpc.NewServer()
+	plugin.RegisterObserverPluginServer(s, newServer())
+	go s.Serve(lis)
+	defer s.Stop()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	registry := observers.NewRegistry()
+	plugins, err := plugin.Load(ctx, registry, map[string]string{"delay": "bufnet"},
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return lis.Dial()
+		}),
+	)
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	defer plugins[0].Close()
+
+	tests := []struct {
+		name     string
+		duration string
+		wantErr  bool
+	}{
+		{
+			name:     "Delay has passed",
+			duration: "100ms",
+		},
+		{
+			name:     "Invalid duration",
+			duration: "soon",
+			wantErr:  true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			res := &v1alpha1.Await{
+				ObjectMeta: metav1.ObjectMeta{Name: "await", Namespace: "default", UID: "uid"},
+				Spec: v1alpha1.AwaitSpec{Observer: &v1alpha1.ObserverSpec{
+					Type:       observerType,
+					Parameters: map[string]string{"duration": tt.duration},
+				}},
+			}
+			obs, err := registry.New(res, observers.Options{})
+			if err != nil {
+				t.Fatalf("Registry.New() error = %v", err)
+			}
+
+			started := time.Now()
+			obs.Start(ctx)
+			result := <-obs.Result()
+
+			if (result.Err != nil) != tt.wantErr {
+				t.Fatalf("Observer.Result() error = %v, wantErr %v", result.Err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if elapsed := time.Since(started); elapsed < 100*time.Millisecond {
+				t.Errorf("Observer.Result() after %v, want after the delay", elapsed)
+			}
+			if _, ok := result.Object.Object["delayedUntil"]; !ok {
+				t.Errorf("Observer.Result() object = %v, want delayedUntil", result.Object.Object)
+			}
+		})
+	}
+}
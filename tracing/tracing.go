@@ -0,0 +1,135 @@
+/*
+Copyright 2019 Marek Cermak <macermak@redhat.com>.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package tracing sets up optional OpenTelemetry tracing of the operator.
+//
+// All the spans of an Await share a single trace whose ID is derived
+// from the Await UID, so that the reconciles, the observer and the
+// Workflow resume can be correlated even though they run at different times.
+package tracing
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"go.opentelemetry.io/otel/api/global"
+	"go.opentelemetry.io/otel/api/trace"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp"
+	"go.opentelemetry.io/otel/exporters/stdout"
+	"go.opentelemetry.io/otel/label"
+	export "go.opentelemetry.io/otel/sdk/export/trace"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/semconv"
+
+	"k8s.io/apimachinery/pkg/types"
+)
+
+const (
+	// ExporterNone disables tracing
+	ExporterNone = ""
+	// ExporterOTLP exports the spans to an OTLP collector
+	ExporterOTLP = "otlp"
+	// ExporterStdout prints the spans to the standard output
+	ExporterStdout = "stdout"
+)
+
+const (
+	serviceName     = "argo-await-operator"
+	instrumentation = "github.com/cermakm/argo-await-operator"
+)
+
+// AwaitUIDKey is the span attribute holding the Await UID
+var AwaitUIDKey = label.Key("await.uid")
+
+// Setup installs the global tracer provider exporting the spans with the given exporter,
+// the endpoint is the address of the OTLP collector and defaults to the OTLP default.
+// The returned function flushes the remaining spans and stops the exporter.
+func Setup(exporter, endpoint string) (func(), error) {
+	var exp export.SpanExporter
+	switch exporter {
+	case ExporterNone:
+		return func() {}, nil
+	case ExporterOTLP:
+		opts := []otlp.ExporterOption{otlp.WithInsecure()}
+		if endpoint != "" {
+			opts = append(opts, otlp.WithAddress(endpoint))
+		}
+
+		otlpExporter, err := otlp.NewExporter(opts...)
+		if err != nil {
+			return nil, err
+		}
+		exp = otlpExporter
+	case ExporterStdout:
+		stdoutExporter, err := stdout.NewExporter(stdout.WithPrettyPrint())
+		if err != nil {
+			return nil, err
+		}
+		exp = stdoutExporter
+	default:
+		return nil, fmt.Errorf("unknown tracing exporter: %q", exporter)
+	}
+
+	bsp := sdktrace.NewBatchSpanProcessor(exp)
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithSpanProcessor(bsp),
+		sdktrace.WithResource(resource.New(semconv.ServiceNameKey.String(serviceName))),
+	)
+	global.SetTracerProvider(provider)
+
+	shutdown := func() {
+		// unregistering the processor flushes and shuts it down
+		provider.UnregisterSpanProcessor(bsp)
+		_ = exp.Shutdown(context.Background())
+	}
+
+	return shutdown, nil
+}
+
+// StartSpan starts a new span with the global tracer
+func StartSpan(ctx context.Context, name string, attrs ...label.KeyValue) (context.Context, trace.Span) {
+	return global.Tracer(instrumentation).Start(ctx, name, trace.WithAttributes(attrs...))
+}
+
+// RecordError records the error on the span and marks the span as failed
+func RecordError(ctx context.Context, span trace.Span, err error) {
+	span.RecordError(ctx, err)
+	span.SetStatus(codes.Error, err.Error())
+}
+
+// ContextForAwait returns a context whose spans belong to the trace of the Await
+// with the given UID. The trace and the parent span IDs are derived from the UID.
+func ContextForAwait(ctx context.Context, uid types.UID) context.Context {
+	hex := strings.Replace(string(uid), "-", "", -1)
+
+	traceID, err := trace.IDFromHex(hex)
+	if err != nil {
+		return ctx
+	}
+	spanID, err := trace.SpanIDFromHex(hex[:16])
+	if err != nil {
+		return ctx
+	}
+
+	return trace.ContextWithRemoteSpanContext(ctx, trace.SpanContext{
+		TraceID:    traceID,
+		SpanID:     spanID,
+		TraceFlags: trace.FlagsSampled,
+	})
+}
@@ -0,0 +1,80 @@
+/*
+Copyright 2019 Marek Cermak <macermak@redhat.com>.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package tracing
+
+import (
+	"context"
+	"testing"
+
+	"go.opentelemetry.io/otel/api/trace"
+
+	"k8s.io/apimachinery/pkg/types"
+)
+
+func TestContextForAwait(t *testing.T) {
+	tests := []struct {
+		name        string
+		uid         string
+		wantTraceID string
+		wantSpanID  string
+	}{
+		{
+			name:        "trace ID from the UID",
+			uid:         "6f1c2d4e-8a1b-4c3d-9e2f-0a1b2c3d4e5f",
+			wantTraceID: "6f1c2d4e8a1b4c3d9e2f0a1b2c3d4e5f",
+			wantSpanID:  "6f1c2d4e8a1b4c3d",
+		},
+		{
+			name: "invalid UID",
+			uid:  "not-a-uuid",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := ContextForAwait(context.Background(), types.UID(tt.uid))
+			sc := trace.RemoteSpanContextFromContext(ctx)
+
+			if tt.wantTraceID == "" {
+				if sc.IsValid() {
+					t.Errorf("ContextForAwait() span context = %v, want invalid", sc)
+				}
+				return
+			}
+			if got := sc.TraceID.String(); got != tt.wantTraceID {
+				t.Errorf("ContextForAwait() trace ID = %v, want %v", got, tt.wantTraceID)
+			}
+			if got := sc.SpanID.String(); got != tt.wantSpanID {
+				t.Errorf("ContextForAwait() span ID = %v, want %v", got, tt.wantSpanID)
+			}
+			if !sc.IsSampled() {
+				t.Errorf("ContextForAwait() span context is not sampled")
+			}
+		})
+	}
+}
+
+func TestSetup(t *testing.T) {
+	if _, err := Setup("unknown", ""); err == nil {
+		t.Errorf("Setup() expected an error for an unknown exporter")
+	}
+
+	shutdown, err := Setup(ExporterNone, "")
+	if err != nil {
+		t.Fatalf("Setup() error = %v", err)
+	}
+	shutdown()
+}